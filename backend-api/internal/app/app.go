@@ -1,6 +1,13 @@
 package app
 
-import "os"
+import (
+	"os"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/service"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/util"
+	"go.uber.org/zap"
+)
 
 func Run() {
 	InitLogger()
@@ -8,6 +15,17 @@ func Run() {
 	if err != nil {
 		os.Exit(1)
 	}
+	// 密钥文件与数据库放在同一目录，随数据一起备份
+	if err := util.LoadSecretKey("db/secret.key"); err != nil {
+		logger.Error("LoadSecretKey failed", zap.Error(err))
+		os.Exit(1)
+	}
+	if err := service.SeedDefaultDicts(db); err != nil {
+		os.Exit(1)
+	}
+	service.StartOpenListHealthChecker(db)
+	service.StartStrmTaskQueue(db)
+	service.StartStrmScheduler(db)
 	r := RegisterRouter(db)
 	r.Run(":8890")
 } 
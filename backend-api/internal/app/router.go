@@ -26,9 +26,15 @@ func RegisterRouter(db *gorm.DB) *gin.Engine {
 	strmGroup := r.Group("/strm")
 	controller.RegisterStrmConfigRoutes(strmGroup, db)
 
+	strmTaskGroup := r.Group("/strm/task")
+	controller.RegisterStrmTaskRoutes(strmTaskGroup, db)
+
 	dictGroup := r.Group("/dict")
 	controller.RegisterDictRoutes(dictGroup, db)
 
+	playGroup := r.Group("/play")
+	controller.RegisterPlayRoutes(playGroup, db)
+
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	return r
 } 
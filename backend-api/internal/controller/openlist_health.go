@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/middleware"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/service"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/util"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ListOpenListHealth godoc
+// @Summary      OpenList服务健康状态
+// @Description  查询当前用户所有OpenList服务最近一次健康检查结果
+// @Tags         OpenListService
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Success      200 {object} middleware.Response[[]model.OpenListHealthResponse]
+// @Router       /openlist/health [get]
+func ListOpenListHealth(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger.Info("[API] /openlist/health [GET] called - 请求入口")
+		claims, ok := c.Get("claims")
+		if !ok {
+			middleware.Unauthorized(c, "未登录或token缺失")
+			return
+		}
+		userID := util.ExtractUserIDFromClaims(claims)
+		if userID == 0 {
+			middleware.Unauthorized(c, "用户信息无效")
+			return
+		}
+		services, err := service.GetAllOpenListServicesByUserID(db, userID)
+		if err != nil {
+			logger.Error("[API] /openlist/health [GET] 查询失败", zap.Error(err))
+			middleware.InternalServerError(c, "查询失败")
+			return
+		}
+		checker := service.GetOpenListHealthChecker()
+		list := make([]model.OpenListHealthResponse, len(services))
+		for i, svc := range services {
+			item := model.OpenListHealthResponse{
+				ServiceID: svc.ID,
+				Name: svc.Name,
+				Enabled: model.Enabled(strconv.Itoa(util.Bool2Int(svc.Enabled))),
+				Status: string(model.HealthStatusUnknown),
+				Url: svc.ServiceUrl,
+			}
+			if checker != nil {
+				if record := checker.Status(svc.ID); record != nil {
+					item.Status = string(record.Status)
+					item.LatencyMs = record.LatencyMs
+					item.Url = record.Url
+					item.Error = record.Error
+					item.CheckedAt = record.CheckedAt.Format("2006-01-02T15:04:05Z07:00")
+				}
+			}
+			list[i] = item
+		}
+		middleware.Success(c, list)
+	}
+}
+
+// ListOpenListHealthHistory godoc
+// @Summary      OpenList服务健康检查历史
+// @Description  分页查询指定服务的状态/延迟采样记录
+// @Tags         OpenListService
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Param        id path int true "服务ID"
+// @Param        page query int false "页码(默认1)"
+// @Param        pageSize query int false "每页条数(默认10)"
+// @Success      200 {object} middleware.Response[model.PageResult[model.OpenListHealthRecordResponse]]
+// @Router       /openlist/health/{id}/history [get]
+func ListOpenListHealthHistory(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger.Info("[API] /openlist/health/:id/history [GET] called - 请求入口")
+		idStr := c.Param("id")
+		id, err := strconv.Atoi(idStr)
+		if err != nil || id <= 0 {
+			middleware.ValidationError(c, "参数错误")
+			return
+		}
+		claims, ok := c.Get("claims")
+		if !ok {
+			middleware.Unauthorized(c, "未登录或token缺失")
+			return
+		}
+		userID := util.ExtractUserIDFromClaims(claims)
+		serviceObj, err := service.GetOpenListServiceByID(db, id)
+		if err != nil || serviceObj == nil || serviceObj.UserID != userID {
+			middleware.NotFound(c, "服务不存在")
+			return
+		}
+		page, pageSize := util.GetPageParams(c)
+		records, total, err := service.GetOpenListHealthRecords(db, id, page, pageSize)
+		if err != nil {
+			logger.Error("[API] /openlist/health/:id/history [GET] 查询失败", zap.Error(err))
+			middleware.InternalServerError(c, "查询失败")
+			return
+		}
+		list := make([]model.OpenListHealthRecordResponse, len(records))
+		for i, r := range records {
+			list[i] = model.OpenListHealthRecordResponse{
+				ID: r.ID,
+				Status: string(r.Status),
+				LatencyMs: r.LatencyMs,
+				Url: r.Url,
+				Error: r.Error,
+				CheckedAt: r.CheckedAt.Format("2006-01-02T15:04:05Z07:00"),
+			}
+		}
+		middleware.Success(c, model.PageResult[model.OpenListHealthRecordResponse]{
+			List: list,
+			Total: int(total),
+			Page: page,
+			PageSize: pageSize,
+		})
+	}
+}
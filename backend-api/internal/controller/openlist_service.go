@@ -1,7 +1,10 @@
 package controller
 
 import (
+	"context"
+	"errors"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
@@ -16,12 +19,38 @@ import (
 type OpenListServiceReq struct {
 	Name        string `json:"name" binding:"required"`
 	Account     string `json:"account" binding:"required"`
-	Token       string `json:"token" binding:"required"`
+	Token       string `json:"token"`    // token 与 password 至少填写一项
+	Password    string `json:"password"` // 可选，填写后自动登录获取/刷新 token，加密存储
+	SignSecret  string `json:"signSecret"` // 可选，OpenList 站点 token，开启签名且列表不返回 sign 时用于本地计算
+	SignExpireHours *int `json:"signExpireHours"` // 签名有效期（小时），0 表示永不过期
+	WalkConcurrency *int `json:"walkConcurrency"` // 遍历并发数，0 表示默认（1），最大 32
+	RequestRateLimit *float64 `json:"requestRateLimit"` // 每秒请求数上限，0 表示不限速
+	TaskConcurrency *int `json:"taskConcurrency"` // 同时执行的任务数，0 表示默认（1），最大 32
 	ServiceUrl  string `json:"serviceUrl" binding:"required"`
 	BackupUrl   string `json:"backupUrl"`
 	Enabled     model.Enabled `json:"enabled"` // 支持字符串或数字
 }
 
+// maxWalkConcurrency 单个服务遍历目录的最大并发数
+const maxWalkConcurrency = 32
+
+// validateThrottleReq 校验并发数和限速参数，失败时已写入响应
+func validateThrottleReq(c *gin.Context, req *OpenListServiceReq) bool {
+	if req.WalkConcurrency != nil && (*req.WalkConcurrency < 0 || *req.WalkConcurrency > maxWalkConcurrency) {
+		middleware.ValidationError(c, "walkConcurrency 取值范围为 0-32")
+		return false
+	}
+	if req.RequestRateLimit != nil && *req.RequestRateLimit < 0 {
+		middleware.ValidationError(c, "requestRateLimit 不能为负数")
+		return false
+	}
+	if req.TaskConcurrency != nil && (*req.TaskConcurrency < 0 || *req.TaskConcurrency > maxWalkConcurrency) {
+		middleware.ValidationError(c, "taskConcurrency 取值范围为 0-32")
+		return false
+	}
+	return true
+}
+
 // convertToResponse 将 OpenListService 转换为 OpenListServiceResponse
 func convertToResponse(service *model.OpenListService) *model.OpenListServiceResponse {
 	return &model.OpenListServiceResponse{
@@ -31,6 +60,12 @@ func convertToResponse(service *model.OpenListService) *model.OpenListServiceRes
 		Token:       service.Token,
 		ServiceUrl:  service.ServiceUrl,
 		BackupUrl:   service.BackupUrl,
+		HasPassword: service.Password != "",
+		HasSignSecret: service.SignSecret != "",
+		SignExpireHours: service.SignExpireHours,
+		WalkConcurrency: service.WalkConcurrency,
+		RequestRateLimit: service.RequestRateLimit,
+		TaskConcurrency: service.TaskConcurrency,
 		Enabled:     model.Enabled(strconv.Itoa(util.Bool2Int(service.Enabled))),
 		UpdatedAt:   service.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 	}
@@ -102,7 +137,7 @@ func ListOpenListService(db *gorm.DB) gin.HandlerFunc {
 
 // CreateOpenListService godoc
 // @Summary      新增OpenList服务
-// @Description  新增OpenList服务，字段：name、account、token、serviceUrl、backupUrl、enabled
+// @Description  新增OpenList服务，字段：name、account、token、password、serviceUrl、backupUrl、enabled；token与password至少填写一项
 // @Tags         OpenListService
 // @Accept       json
 // @Produce      json
@@ -119,7 +154,12 @@ func CreateOpenListService(db *gorm.DB) gin.HandlerFunc {
 			middleware.ValidationError(c, "参数错误")
 			return
 		}
-		logger.Info("[API] /openlist/service [POST] 参数绑定成功", zap.Any("req", req))
+		if req.Token == "" && req.Password == "" {
+			logger.Error("[API] /openlist/service [POST] token和密码均为空")
+			middleware.ValidationError(c, "token和密码至少填写一项")
+			return
+		}
+		logger.Info("[API] /openlist/service [POST] 参数绑定成功", zap.String("name", req.Name), zap.String("serviceUrl", req.ServiceUrl))
 		claims, ok := c.Get("claims")
 		if !ok {
 			logger.Error("[API] /openlist/service [POST] 未获取到claims")
@@ -132,17 +172,56 @@ func CreateOpenListService(db *gorm.DB) gin.HandlerFunc {
 			middleware.Unauthorized(c, "用户信息无效")
 			return
 		}
+		encryptedPassword, err := util.EncryptString(req.Password)
+		if err != nil {
+			logger.Error("[API] /openlist/service [POST] 密码加密失败", zap.Error(err))
+			middleware.InternalServerError(c, "创建服务失败")
+			return
+		}
+		encryptedSignSecret, err := util.EncryptString(req.SignSecret)
+		if err != nil {
+			logger.Error("[API] /openlist/service [POST] 签名密钥加密失败", zap.Error(err))
+			middleware.InternalServerError(c, "创建服务失败")
+			return
+		}
+		signExpireHours := 0
+		if req.SignExpireHours != nil {
+			signExpireHours = *req.SignExpireHours
+		}
+		if signExpireHours < 0 {
+			middleware.ValidationError(c, "signExpireHours 不能为负数")
+			return
+		}
+		if !validateThrottleReq(c, &req) {
+			return
+		}
+		walkConcurrency, requestRateLimit, taskConcurrency := 0, 0.0, 0
+		if req.WalkConcurrency != nil {
+			walkConcurrency = *req.WalkConcurrency
+		}
+		if req.RequestRateLimit != nil {
+			requestRateLimit = *req.RequestRateLimit
+		}
+		if req.TaskConcurrency != nil {
+			taskConcurrency = *req.TaskConcurrency
+		}
 		enabledBool := util.ParseEnabled(req.Enabled)
 		serviceObj := &model.OpenListService{
 			Name: req.Name,
 			Account: req.Account,
 			Token: req.Token,
+			Password: encryptedPassword,
+			SignSecret: encryptedSignSecret,
+			SignExpireHours: signExpireHours,
+			WalkConcurrency: walkConcurrency,
+			RequestRateLimit: requestRateLimit,
+			TaskConcurrency: taskConcurrency,
 			ServiceUrl: req.ServiceUrl,
 			BackupUrl: req.BackupUrl,
 			Enabled: enabledBool,
 			UserID: userID,
 		}
-		err := service.CreateOpenListService(db, serviceObj)
+		err = service.CreateOpenListService(db, serviceObj)
 		if err != nil {
 			logger.Error("[API] /openlist/service [POST] 创建失败", zap.Error(err))
 			middleware.InternalServerError(c, "创建服务失败")
@@ -223,7 +302,7 @@ func UpdateOpenListService(db *gorm.DB) gin.HandlerFunc {
 			middleware.ValidationError(c, "参数错误")
 			return
 		}
-		logger.Info("[API] /openlist/service/:id [PUT] 参数绑定成功", zap.Any("req", req))
+		logger.Info("[API] /openlist/service/:id [PUT] 参数绑定成功", zap.String("name", req.Name), zap.String("serviceUrl", req.ServiceUrl))
 		claims, ok := c.Get("claims")
 		if !ok {
 			logger.Error("[API] /openlist/service/:id [PUT] 未获取到claims")
@@ -242,14 +321,64 @@ func UpdateOpenListService(db *gorm.DB) gin.HandlerFunc {
 			middleware.NotFound(c, "服务不存在")
 			return
 		}
+		if req.SignExpireHours != nil && *req.SignExpireHours < 0 {
+			middleware.ValidationError(c, "signExpireHours 不能为负数")
+			return
+		}
+		if !validateThrottleReq(c, &req) {
+			return
+		}
 		enabledBool := util.ParseEnabled(req.Enabled)
 		serviceObj.Enabled = enabledBool
+		if req.Password != "" {
+			encryptedPassword, err := util.EncryptString(req.Password)
+			if err != nil {
+				logger.Error("[API] /openlist/service/:id [PUT] 密码加密失败", zap.Error(err))
+				middleware.InternalServerError(c, "更新失败")
+				return
+			}
+			serviceObj.Password = encryptedPassword
+		}
 		err = service.UpdateOpenListService(db, serviceObj)
 		if err != nil {
 			logger.Error("[API] /openlist/service/:id [PUT] 更新失败", zap.Error(err))
 			middleware.InternalServerError(c, "更新失败")
 			return
 		}
+		if req.SignSecret != "" || req.SignExpireHours != nil {
+			signExpireHours := serviceObj.SignExpireHours
+			if req.SignExpireHours != nil {
+				signExpireHours = *req.SignExpireHours
+			}
+			encryptedSignSecret, err := util.EncryptString(req.SignSecret)
+			if err != nil {
+				logger.Error("[API] /openlist/service/:id [PUT] 签名密钥加密失败", zap.Error(err))
+				middleware.InternalServerError(c, "更新失败")
+				return
+			}
+			if err := service.UpdateOpenListServiceSign(db, id, encryptedSignSecret, signExpireHours); err != nil {
+				logger.Error("[API] /openlist/service/:id [PUT] 更新签名设置失败", zap.Error(err))
+				middleware.InternalServerError(c, "更新失败")
+				return
+			}
+		}
+		if req.WalkConcurrency != nil || req.RequestRateLimit != nil || req.TaskConcurrency != nil {
+			walkConcurrency, requestRateLimit, taskConcurrency := serviceObj.WalkConcurrency, serviceObj.RequestRateLimit, serviceObj.TaskConcurrency
+			if req.WalkConcurrency != nil {
+				walkConcurrency = *req.WalkConcurrency
+			}
+			if req.RequestRateLimit != nil {
+				requestRateLimit = *req.RequestRateLimit
+			}
+			if req.TaskConcurrency != nil {
+				taskConcurrency = *req.TaskConcurrency
+			}
+			if err := service.UpdateOpenListServiceThrottle(db, id, walkConcurrency, requestRateLimit, taskConcurrency); err != nil {
+				logger.Error("[API] /openlist/service/:id [PUT] 更新并发及限速设置失败", zap.Error(err))
+				middleware.InternalServerError(c, "更新失败")
+				return
+			}
+		}
 		logger.Info("[API] /openlist/service/:id [PUT] 更新成功", zap.Int("id", id), zap.Int("user_id", userID))
 		middleware.SuccessWithMessage(c, "更新成功", nil)
 	}
@@ -304,6 +433,156 @@ func DeleteOpenListService(db *gorm.DB) gin.HandlerFunc {
 	}
 }
 
+// TestOpenListService godoc
+// @Summary      测试OpenList服务连接（未保存）
+// @Description  使用请求中的地址和token调用 /api/me，返回延迟、版本、用户基础路径及权限，并检测备用地址是否可用
+// @Tags         OpenListService
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Param        body body OpenListServiceReq true "服务信息"
+// @Success      200 {object} middleware.Response[model.OpenListTestResult]
+// @Router       /openlist/test [post]
+func TestOpenListService(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger.Info("[API] /openlist/test [POST] called - 请求入口")
+		var req OpenListServiceReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			logger.Error("[API] /openlist/test [POST] 参数绑定失败", zap.Error(err))
+			middleware.ValidationError(c, "参数错误")
+			return
+		}
+		if req.Token == "" && req.Password == "" {
+			middleware.ValidationError(c, "token和密码至少填写一项")
+			return
+		}
+		serviceObj := &model.OpenListService{
+			Name: req.Name,
+			Account: req.Account,
+			Token: req.Token,
+			ServiceUrl: req.ServiceUrl,
+			BackupUrl: req.BackupUrl,
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+		defer cancel()
+		result := service.TestOpenListService(ctx, serviceObj, req.Password)
+		logger.Info("[API] /openlist/test [POST] 测试完成", zap.String("service_url", req.ServiceUrl), zap.Bool("success", result.Success))
+		middleware.Success(c, result)
+	}
+}
+
+// TestOpenListServiceByID godoc
+// @Summary      测试OpenList服务连接（已保存）
+// @Description  测试指定ID的OpenList服务，返回结构化诊断结果
+// @Tags         OpenListService
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Param        id path int true "服务ID"
+// @Success      200 {object} middleware.Response[model.OpenListTestResult]
+// @Router       /openlist/test/{id} [post]
+func TestOpenListServiceByID(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger.Info("[API] /openlist/test/:id [POST] called - 请求入口")
+		idStr := c.Param("id")
+		id, err := strconv.Atoi(idStr)
+		if err != nil || id <= 0 {
+			logger.Error("[API] /openlist/test/:id [POST] 参数错误", zap.String("id", idStr))
+			middleware.ValidationError(c, "参数错误")
+			return
+		}
+		claims, ok := c.Get("claims")
+		if !ok {
+			logger.Error("[API] /openlist/test/:id [POST] 未获取到claims")
+			middleware.Unauthorized(c, "未登录或token缺失")
+			return
+		}
+		userID := util.ExtractUserIDFromClaims(claims)
+		if userID == 0 {
+			logger.Error("[API] /openlist/test/:id [POST] 用户信息无效")
+			middleware.Unauthorized(c, "用户信息无效")
+			return
+		}
+		serviceObj, err := service.GetOpenListServiceByID(db, id)
+		if err != nil || serviceObj == nil || serviceObj.UserID != userID {
+			logger.Error("[API] /openlist/test/:id [POST] 服务不存在", zap.Int("id", id))
+			middleware.NotFound(c, "服务不存在")
+			return
+		}
+		password, err := util.DecryptString(serviceObj.Password)
+		if err != nil {
+			logger.Error("[API] /openlist/test/:id [POST] 密码解密失败", zap.Error(err))
+			middleware.InternalServerError(c, "密码解密失败")
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+		defer cancel()
+		result := service.TestOpenListService(ctx, serviceObj, password)
+		logger.Info("[API] /openlist/test/:id [POST] 测试完成", zap.Int("id", id), zap.Bool("success", result.Success))
+		middleware.Success(c, result)
+	}
+}
+
+// BrowseOpenListService godoc
+// @Summary      浏览OpenList远程目录
+// @Description  代理 OpenList 目录列表，用于前端选择 alistBasePath；type=dir 仅返回目录(默认)，type=all 返回目录和文件
+// @Tags         OpenListService
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Param        id path int true "服务ID"
+// @Param        path query string false "远程路径(默认/)"
+// @Param        type query string false "dir/all"
+// @Param        page query int false "页码(默认1)"
+// @Param        pageSize query int false "每页条数(默认10)"
+// @Success      200 {object} middleware.Response[model.PageResult[model.OpenListBrowseItem]]
+// @Router       /openlist/{id}/browse [get]
+func BrowseOpenListService(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger.Info("[API] /openlist/:id/browse [GET] called - 请求入口")
+		idStr := c.Param("id")
+		id, err := strconv.Atoi(idStr)
+		if err != nil || id <= 0 {
+			logger.Error("[API] /openlist/:id/browse [GET] 参数错误", zap.String("id", idStr))
+			middleware.ValidationError(c, "参数错误")
+			return
+		}
+		claims, ok := c.Get("claims")
+		if !ok {
+			middleware.Unauthorized(c, "未登录或token缺失")
+			return
+		}
+		userID := util.ExtractUserIDFromClaims(claims)
+		serviceObj, err := service.GetOpenListServiceByID(db, id)
+		if err != nil || serviceObj == nil || serviceObj.UserID != userID {
+			logger.Error("[API] /openlist/:id/browse [GET] 服务不存在", zap.Int("id", id))
+			middleware.NotFound(c, "服务不存在")
+			return
+		}
+		dir := c.DefaultQuery("path", "/")
+		dirsOnly := c.DefaultQuery("type", "dir") != "all"
+		page, pageSize := util.GetPageParams(c)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+		defer cancel()
+		items, total, err := service.BrowseOpenListDir(ctx, db, serviceObj, dir, dirsOnly, page, pageSize)
+		if err != nil {
+			logger.Error("[API] /openlist/:id/browse [GET] 浏览失败", zap.Int("id", id), zap.String("path", dir), zap.Error(err))
+			if errors.Is(err, service.ErrRemotePathNotFound) {
+				middleware.NotFound(c, "路径不存在")
+			} else {
+				middleware.InternalServerError(c, "获取目录失败")
+			}
+			return
+		}
+		middleware.Success(c, model.PageResult[model.OpenListBrowseItem]{
+			List: items,
+			Total: int(total),
+			Page: page,
+			PageSize: pageSize,
+		})
+	}
+}
+
 // RegisterOpenListServiceRoutes 统一注册/openlist/service相关接口
 func RegisterOpenListServiceRoutes(rg *gin.RouterGroup, db *gorm.DB) {
 	rg.GET("/list", ListOpenListService(db))
@@ -311,4 +590,9 @@ func RegisterOpenListServiceRoutes(rg *gin.RouterGroup, db *gorm.DB) {
 	rg.GET("/detail/:id", GetOpenListService(db))
 	rg.PUT("/update/:id", UpdateOpenListService(db))
 	rg.DELETE("/delete/:id", DeleteOpenListService(db))
+	rg.POST("/test", TestOpenListService(db))
+	rg.POST("/test/:id", TestOpenListServiceByID(db))
+	rg.GET("/health", ListOpenListHealth(db))
+	rg.GET("/health/:id/history", ListOpenListHealthHistory(db))
+	rg.GET("/:id/browse", BrowseOpenListService(db))
 } 
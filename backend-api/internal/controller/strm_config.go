@@ -1,13 +1,17 @@
 package controller
 
 import (
+	"context"
+	"errors"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
 	"github.com/tnnevol/openlist-strm/backend-api/internal/middleware"
 	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
 	"github.com/tnnevol/openlist-strm/backend-api/internal/service"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/strm"
 	"github.com/tnnevol/openlist-strm/backend-api/internal/util"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -26,6 +30,190 @@ type StrmConfigReq struct {
 	UpdateMode       model.UpdateMode  `json:"updateMode" binding:"required"`
 	ServiceID        int         `json:"serviceId" binding:"required"`
 	IsUseBackupUrl   model.IsUseBackupUrl `json:"isUseBackupUrl" binding:"required"`
+	OrphanPolicy     model.OrphanPolicy `json:"orphanPolicy"`    // delete/quarantine/report，默认 delete
+	OrphanThreshold  int         `json:"orphanThreshold"`          // 孤立文件占比超过该百分比时中止清理，0 使用默认值
+	QuarantinePath   string      `json:"quarantinePath"`           // 隔离目录，默认 strmOutputPath/.quarantine
+	UrlTemplate      string      `json:"urlTemplate"`              // .strm 链接模板，为空时使用 /d/ 直链
+	PlayMode         model.PlayMode `json:"playMode"`              // redirect/proxy，默认 redirect
+	ProxyHeaders     string      `json:"proxyHeaders"`             // 代理模式附加请求头，JSON 对象
+	ProxyMaxStreams  int         `json:"proxyMaxStreams"`          // 代理模式每用户的最大并发流数，多个配置取最小的非零值，0 不限
+	ProxyRateLimit   int         `json:"proxyRateLimit"`           // 代理模式每用户的带宽（KB/s），多个配置取最小的非零值，0 不限
+	IncludeExts      string      `json:"includeExts"`              // 视频扩展名，逗号分隔，为空时使用字典 video_ext
+	ExcludeExts      string      `json:"excludeExts"`              // 排除的扩展名，逗号分隔
+	MinFileSize      int64       `json:"minFileSize"`              // 视频最小大小（字节），0 不限
+	MaxFileSize      int64       `json:"maxFileSize"`              // 视频最大大小（字节），0 不限
+	ExcludePatterns  string      `json:"excludePatterns"`          // 排除规则，每行一条，glob 或 re: 开头的正则
+	RewriteRules     string      `json:"rewriteRules"`             // 路径改写规则，JSON 数组：regex/flatten/strip/season
+	FileMode         string      `json:"fileMode"`                 // 输出文件权限（八进制），默认 0644
+	DirMode          string      `json:"dirMode"`                  // 新建目录权限（八进制），默认 0755
+	FileOwner        string      `json:"fileOwner"`                // 输出文件属主 uid:gid，为空时不修改
+}
+
+// convertToStrmConfigResponse 将 StrmConfig 转换为 StrmConfigResponse
+func convertToStrmConfigResponse(cfg *model.StrmConfig) model.StrmConfigResponse {
+	return model.StrmConfigResponse{
+		ID: cfg.ID,
+		Name: cfg.Name,
+		AlistBasePath: cfg.AlistBasePath,
+		StrmOutputPath: cfg.StrmOutputPath,
+		DownloadEnabled: model.DownloadEnabled(strconv.Itoa(util.Bool2Int(cfg.DownloadEnabled))),
+		DownloadInterval: cfg.DownloadInterval,
+		UpdateMode: string(cfg.UpdateMode),
+		ServiceID: cfg.ServiceID,
+		IsUseBackupUrl: model.IsUseBackupUrl(strconv.Itoa(util.Bool2Int(cfg.IsUseBackupUrl))),
+		OrphanPolicy: string(cfg.OrphanPolicy),
+		OrphanThreshold: cfg.OrphanThreshold,
+		QuarantinePath: cfg.QuarantinePath,
+		UrlTemplate: cfg.UrlTemplate,
+		PlayMode: string(cfg.PlayMode),
+		ProxyHeaders: cfg.ProxyHeaders,
+		ProxyMaxStreams: cfg.ProxyMaxStreams,
+		ProxyRateLimit: cfg.ProxyRateLimit,
+		IncludeExts: cfg.IncludeExts,
+		ExcludeExts: cfg.ExcludeExts,
+		MinFileSize: cfg.MinFileSize,
+		MaxFileSize: cfg.MaxFileSize,
+		ExcludePatterns: cfg.ExcludePatterns,
+		RewriteRules: cfg.RewriteRules,
+		FileMode: cfg.FileMode,
+		DirMode: cfg.DirMode,
+		FileOwner: cfg.FileOwner,
+		CreatedAt: cfg.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt: cfg.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// applyStrmConfigReq 将请求字段写入配置
+func applyStrmConfigReq(cfg *model.StrmConfig, req *StrmConfigReq) {
+	cfg.Name = req.Name
+	cfg.AlistBasePath = req.AlistBasePath
+	cfg.StrmOutputPath = req.StrmOutputPath
+	cfg.DownloadEnabled = util.ParseEnabled(req.DownloadEnabled)
+	cfg.DownloadInterval = req.DownloadInterval
+	cfg.UpdateMode = model.UpdateMode(req.UpdateMode)
+	cfg.ServiceID = req.ServiceID
+	cfg.IsUseBackupUrl = util.ParseEnabled(req.IsUseBackupUrl)
+	cfg.OrphanPolicy = req.OrphanPolicy
+	cfg.OrphanThreshold = req.OrphanThreshold
+	cfg.QuarantinePath = req.QuarantinePath
+	cfg.UrlTemplate = req.UrlTemplate
+	cfg.PlayMode = req.PlayMode
+	cfg.ProxyHeaders = req.ProxyHeaders
+	cfg.ProxyMaxStreams = req.ProxyMaxStreams
+	cfg.ProxyRateLimit = req.ProxyRateLimit
+	cfg.IncludeExts = req.IncludeExts
+	cfg.ExcludeExts = req.ExcludeExts
+	cfg.MinFileSize = req.MinFileSize
+	cfg.MaxFileSize = req.MaxFileSize
+	cfg.ExcludePatterns = req.ExcludePatterns
+	cfg.RewriteRules = req.RewriteRules
+	cfg.FileMode = req.FileMode
+	cfg.DirMode = req.DirMode
+	cfg.FileOwner = req.FileOwner
+}
+
+// validateStrmConfigReq 校验服务归属、输出目录及 alistBasePath 在远程服务上存在，configID 为编辑的配置（新增时为 0），失败时已写入响应
+func validateStrmConfigReq(c *gin.Context, db *gorm.DB, userID, configID int, req *StrmConfigReq) bool {
+	switch req.OrphanPolicy {
+	case "", model.OrphanPolicyDelete, model.OrphanPolicyQuarantine, model.OrphanPolicyReport:
+	default:
+		middleware.ValidationError(c, "orphanPolicy 仅支持 delete/quarantine/report")
+		return false
+	}
+	if req.OrphanThreshold < 0 || req.OrphanThreshold > 100 {
+		middleware.ValidationError(c, "orphanThreshold 取值范围为 0-100")
+		return false
+	}
+	if req.DownloadInterval < 0 {
+		middleware.ValidationError(c, "downloadInterval 不能为负数")
+		return false
+	}
+	if err := strm.ValidateURLTemplate(req.UrlTemplate); err != nil {
+		middleware.ValidationError(c, "urlTemplate "+err.Error())
+		return false
+	}
+	if strm.NeedsPlayURL(req.UrlTemplate) && service.PlayBaseURL() == "" {
+		middleware.ValidationError(c, "urlTemplate 使用 {play_url} 需先配置环境变量 PLAY_BASE_URL")
+		return false
+	}
+	switch req.PlayMode {
+	case "", model.PlayModeRedirect, model.PlayModeProxy:
+	default:
+		middleware.ValidationError(c, "playMode 仅支持 redirect/proxy")
+		return false
+	}
+	if _, err := service.ParseProxyHeaders(req.ProxyHeaders); err != nil {
+		middleware.ValidationError(c, "proxyHeaders 须为 JSON 对象")
+		return false
+	}
+	if req.ProxyMaxStreams < 0 || req.ProxyRateLimit < 0 {
+		middleware.ValidationError(c, "proxyMaxStreams/proxyRateLimit 不能为负数")
+		return false
+	}
+	if req.MinFileSize < 0 || req.MaxFileSize < 0 || (req.MaxFileSize > 0 && req.MinFileSize > req.MaxFileSize) {
+		middleware.ValidationError(c, "minFileSize/maxFileSize 取值错误")
+		return false
+	}
+	if err := strm.ValidateFilter(&model.StrmConfig{ExcludePatterns: req.ExcludePatterns}); err != nil {
+		middleware.ValidationError(c, "excludePatterns "+err.Error())
+		return false
+	}
+	if _, err := strm.ParseRewriteRules(req.RewriteRules); err != nil {
+		middleware.ValidationError(c, "rewriteRules "+err.Error())
+		return false
+	}
+	perm, err := strm.ParsePermissions(&model.StrmConfig{FileMode: req.FileMode, DirMode: req.DirMode, FileOwner: req.FileOwner})
+	if err != nil {
+		middleware.ValidationError(c, err.Error())
+		return false
+	}
+	if !validateStrmOutputPath(c, db, userID, configID, req.StrmOutputPath, perm) {
+		return false
+	}
+	svc, err := service.GetOpenListServiceByID(db, req.ServiceID)
+	if err != nil || svc == nil || svc.UserID != userID {
+		middleware.ValidationError(c, "服务不存在")
+		return false
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+	if err := service.ValidateRemoteDir(ctx, db, svc, req.AlistBasePath); err != nil {
+		logger.Error("[API] /strm/config alistBasePath 校验失败", zap.String("path", req.AlistBasePath), zap.Error(err))
+		switch {
+		case errors.Is(err, service.ErrRemotePathNotFound):
+			middleware.ValidationError(c, "alistBasePath 在远程服务上不存在")
+		case errors.Is(err, service.ErrRemotePathNotDir):
+			middleware.ValidationError(c, "alistBasePath 不是目录")
+		default:
+			middleware.BadRequest(c, "无法连接OpenList服务，路径校验失败")
+		}
+		return false
+	}
+	return true
+}
+
+// validateStrmOutputPath 校验输出目录可写、可按配置设置属主且不与其他配置的输出目录重叠，失败时已写入响应
+func validateStrmOutputPath(c *gin.Context, db *gorm.DB, userID, configID int, outputPath string, perm *strm.Permissions) bool {
+	if err := strm.CheckWritable(outputPath, perm); err != nil {
+		logger.Error("[API] /strm/config strmOutputPath 不可写", zap.String("path", outputPath), zap.Error(err))
+		middleware.ValidationError(c, "strmOutputPath "+err.Error())
+		return false
+	}
+	conflict, err := service.FindOutputPathConflict(db, configID, outputPath)
+	if err != nil {
+		middleware.InternalServerError(c, "校验输出目录失败")
+		return false
+	}
+	if conflict != nil {
+		// 不向其他用户暴露其目录
+		if conflict.UserID != userID {
+			middleware.ValidationError(c, "strmOutputPath 与其他用户配置的输出目录重叠")
+			return false
+		}
+		middleware.ValidationError(c, "strmOutputPath 与配置「"+conflict.Name+"」的输出目录重叠："+conflict.StrmOutputPath)
+		return false
+	}
+	return true
 }
 
 type StrmConfigCopyReq struct {
@@ -63,19 +251,7 @@ func ListStrmConfig(db *gorm.DB) gin.HandlerFunc {
 		list := make([]model.StrmConfigResponse, len(configs))
 		for i, v := range configs {
 			if v != nil {
-				list[i] = model.StrmConfigResponse{
-					ID: v.ID,
-					Name: v.Name,
-					AlistBasePath: v.AlistBasePath,
-					StrmOutputPath: v.StrmOutputPath,
-					DownloadEnabled: model.DownloadEnabled(strconv.Itoa(util.Bool2Int(v.DownloadEnabled))),
-					DownloadInterval: v.DownloadInterval,
-					UpdateMode: string(v.UpdateMode),
-					ServiceID: v.ServiceID,
-					IsUseBackupUrl: model.IsUseBackupUrl(strconv.Itoa(util.Bool2Int(v.IsUseBackupUrl))),
-					CreatedAt: v.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-					UpdatedAt: v.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
-				}
+				list[i] = convertToStrmConfigResponse(v)
 			}
 		}
 		result := model.PageResult[model.StrmConfigResponse]{
@@ -141,19 +317,11 @@ func CreateStrmConfig(db *gorm.DB) gin.HandlerFunc {
 		}
 		userID := util.ExtractUserIDFromClaims(claims)
 		logger.Info("[API] /strm/config 新增 claims和userID", zap.Any("claims", claims), zap.Int("userID", userID))
-		downloadEnabledBool := util.ParseEnabled(req.DownloadEnabled)
-		isUseBackupUrlBool := util.ParseEnabled(req.IsUseBackupUrl)
-		cfg := &model.StrmConfig{
-			UserID: userID,
-			Name: req.Name,
-			AlistBasePath: req.AlistBasePath,
-			StrmOutputPath: req.StrmOutputPath,
-			DownloadEnabled: downloadEnabledBool,
-			DownloadInterval: req.DownloadInterval,
-			UpdateMode: model.UpdateMode(req.UpdateMode),
-			ServiceID: req.ServiceID,
-			IsUseBackupUrl: isUseBackupUrlBool,
+		if !validateStrmConfigReq(c, db, userID, 0, &req) {
+			return
 		}
+		cfg := &model.StrmConfig{UserID: userID}
+		applyStrmConfigReq(cfg, &req)
 		err := service.CreateStrmConfig(db, cfg)
 		if err != nil {
 			logger.Error("[API] /strm/config 新增 service.CreateStrmConfig失败", zap.Error(err))
@@ -195,16 +363,10 @@ func UpdateStrmConfig(db *gorm.DB) gin.HandlerFunc {
 			middleware.NotFound(c, "配置不存在")
 			return
 		}
-		downloadEnabledBool := util.ParseEnabled(req.DownloadEnabled)
-		isUseBackupUrlBool := util.ParseEnabled(req.IsUseBackupUrl)
-		cfg.Name = req.Name
-		cfg.AlistBasePath = req.AlistBasePath
-		cfg.StrmOutputPath = req.StrmOutputPath
-		cfg.DownloadEnabled = downloadEnabledBool
-		cfg.DownloadInterval = req.DownloadInterval
-		cfg.UpdateMode = model.UpdateMode(req.UpdateMode)
-		cfg.ServiceID = req.ServiceID
-		cfg.IsUseBackupUrl = isUseBackupUrlBool
+		if !validateStrmConfigReq(c, db, userID, id, &req) {
+			return
+		}
+		applyStrmConfigReq(cfg, &req)
 		err = service.UpdateStrmConfig(db, cfg)
 		if err != nil {
 			middleware.InternalServerError(c, "编辑失败")
@@ -271,20 +433,321 @@ func GetStrmConfig(db *gorm.DB) gin.HandlerFunc {
 			middleware.NotFound(c, "配置不存在")
 			return
 		}
-		resp := model.StrmConfigResponse{
-			ID: cfg.ID,
-			Name: cfg.Name,
-			AlistBasePath: cfg.AlistBasePath,
-			StrmOutputPath: cfg.StrmOutputPath,
-			DownloadEnabled: model.DownloadEnabled(strconv.Itoa(util.Bool2Int(cfg.DownloadEnabled))),
-			DownloadInterval: cfg.DownloadInterval,
-			UpdateMode: string(cfg.UpdateMode),
-			ServiceID: cfg.ServiceID,
-			IsUseBackupUrl: model.IsUseBackupUrl(strconv.Itoa(util.Bool2Int(cfg.IsUseBackupUrl))),
-			CreatedAt: cfg.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-			UpdatedAt: cfg.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		}
-		middleware.Success(c, resp)
+		middleware.Success(c, convertToStrmConfigResponse(cfg))
+	}
+}
+
+// RotateStrmPlaySecret godoc
+// @Summary      轮换播放链接密钥
+// @Description  重新生成配置的播放链接密钥，已生成的 /play 链接立即失效，下次执行生成时重写 .strm 文件
+// @Tags         StrmConfig
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Param        id path int true "配置ID"
+// @Success      200 {object} middleware.Response[string]
+// @Router       /strm/config/rotate-play-secret/{id} [post]
+func RotateStrmPlaySecret(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, _ := strconv.Atoi(c.Param("id"))
+		claims, ok := c.Get("claims")
+		if !ok {
+			middleware.Unauthorized(c, "未登录或token缺失")
+			return
+		}
+		userID := util.ExtractUserIDFromClaims(claims)
+		cfg, err := service.GetStrmConfigByID(db, id)
+		if err != nil || cfg == nil || cfg.UserID != userID {
+			middleware.NotFound(c, "配置不存在")
+			return
+		}
+		logger.Info("[API] /strm/config/rotate-play-secret", zap.Int("id", id), zap.Int("userID", userID))
+		if err := service.RotateStrmPlaySecret(db, cfg); err != nil {
+			middleware.InternalServerError(c, "轮换失败")
+			return
+		}
+		middleware.SuccessWithMessage(c, "轮换成功，旧播放链接已失效，请重新生成", nil)
+	}
+}
+
+// GenerateStrmConfig godoc
+// @Summary      执行Strm生成
+// @Description  以手动优先级将配置的生成加入任务队列，立即返回队列中的执行，该配置已有生成在排队时不重复入队；执行结果（created/updated/skipped/failed 统计）记录到运行日志
+// @Tags         StrmConfig
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Param        id path int true "配置ID"
+// @Success      200 {object} middleware.Response[model.StrmQueueJobResponse]
+// @Router       /strm/config/generate/{id} [post]
+func GenerateStrmConfig(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger.Info("[API] /strm/config/generate [POST] called - 请求入口")
+		idStr := c.Param("id")
+		id, err := strconv.Atoi(idStr)
+		if err != nil || id <= 0 {
+			logger.Error("[API] /strm/config/generate [POST] 参数错误", zap.String("id", idStr))
+			middleware.ValidationError(c, "参数错误")
+			return
+		}
+		claims, ok := c.Get("claims")
+		if !ok {
+			middleware.Unauthorized(c, "未登录或token缺失")
+			return
+		}
+		userID := util.ExtractUserIDFromClaims(claims)
+		cfg, err := service.GetStrmConfigByID(db, id)
+		if err != nil || cfg == nil || cfg.UserID != userID {
+			middleware.NotFound(c, "配置不存在")
+			return
+		}
+		if service.IsOpenListServiceDown(cfg.ServiceID) {
+			middleware.BadRequest(c, "OpenList服务不可用，请稍后重试")
+			return
+		}
+		job, duplicated, err := service.StartGenerateStrm(db, cfg, userID)
+		if err != nil {
+			logger.Error("[API] /strm/config/generate [POST] 入队失败", zap.Int("id", id), zap.Error(err))
+			middleware.InternalServerError(c, err.Error())
+			return
+		}
+		logger.Info("[API] /strm/config/generate [POST] 已加入队列", zap.Int("id", id), zap.Int64("job_id", job.ID), zap.Bool("duplicated", duplicated))
+		message := "生成已加入队列，结果见运行日志"
+		if duplicated {
+			message = "该配置已在队列中"
+		}
+		middleware.SuccessWithMessage(c, message, convertToStrmQueueJobResponse(job, 0))
+	}
+}
+
+// CheckStrmConfig godoc
+// @Summary      检查已生成的Strm
+// @Description  扫描输出目录中的 .strm，检查远程文件是否仍存在、签名是否过期、大小是否变化；repair=true 时自动修复，结果记录到运行日志
+// @Tags         StrmConfig
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Param        id path int true "配置ID"
+// @Param        repair query bool false "是否自动修复"
+// @Success      200 {object} middleware.Response[strm.CheckReport]
+// @Router       /strm/config/check/{id} [post]
+func CheckStrmConfig(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, _ := strconv.Atoi(c.Param("id"))
+		claims, ok := c.Get("claims")
+		if !ok {
+			middleware.Unauthorized(c, "未登录或token缺失")
+			return
+		}
+		userID := util.ExtractUserIDFromClaims(claims)
+		cfg, err := service.GetStrmConfigByID(db, id)
+		if err != nil || cfg == nil || cfg.UserID != userID {
+			middleware.NotFound(c, "配置不存在")
+			return
+		}
+		repair, _ := strconv.ParseBool(c.DefaultQuery("repair", "false"))
+		var report *strm.CheckReport
+		err = service.WithStrmConfigLock(cfg.ID, func() error {
+			report, err = service.CheckStrm(c.Request.Context(), db, cfg, 0, repair)
+			return err
+		})
+		if errors.Is(err, service.ErrConfigBusy) {
+			middleware.BadRequest(c, err.Error())
+			return
+		}
+		if err != nil {
+			logger.Error("[API] /strm/config/check 检查失败", zap.Int("id", id), zap.Error(err))
+			middleware.InternalServerError(c, "检查失败："+err.Error())
+			return
+		}
+		middleware.Success(c, report)
+	}
+}
+
+// StrmUrlPreviewReq 链接模板预览请求
+type StrmUrlPreviewReq struct {
+	ServiceID       int    `json:"serviceId" binding:"required"`
+	AlistBasePath   string `json:"alistBasePath" binding:"required"`
+	UrlTemplate     string `json:"urlTemplate"` // 为空时使用 /d/ 直链
+	SamplePath      string `json:"samplePath"`  // 示例文件远程完整路径，为空时按过滤条件自动选取
+	ConfigID        int    `json:"configId"`    // 编辑已保存的配置时传入，播放链接使用该配置签名，否则仅作示意
+	IncludeExts     string `json:"includeExts"`
+	ExcludeExts     string `json:"excludeExts"`
+	MinFileSize     int64  `json:"minFileSize"`
+	MaxFileSize     int64  `json:"maxFileSize"`
+	ExcludePatterns string `json:"excludePatterns"`
+}
+
+// PreviewStrmUrl godoc
+// @Summary      预览链接模板
+// @Description  用示例文件渲染 .strm 链接模板，samplePath 为空时按过滤条件取 alistBasePath 下第一个视频文件；
+// @Description  未传 configId 时 {play_url} 使用配置ID 0 渲染，仅作示意（illustrative 为 true）
+// @Tags         StrmConfig
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Param        body body StrmUrlPreviewReq true "模板及示例文件"
+// @Success      200 {object} middleware.Response[model.StrmUrlPreviewResponse]
+// @Router       /strm/config/preview-url [post]
+func PreviewStrmUrl(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req StrmUrlPreviewReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			middleware.ValidationError(c, "参数错误")
+			return
+		}
+		claims, ok := c.Get("claims")
+		if !ok {
+			middleware.Unauthorized(c, "未登录或token缺失")
+			return
+		}
+		userID := util.ExtractUserIDFromClaims(claims)
+		if err := strm.ValidateURLTemplate(req.UrlTemplate); err != nil {
+			middleware.ValidationError(c, "urlTemplate "+err.Error())
+			return
+		}
+		cfg := &model.StrmConfig{
+			AlistBasePath:   req.AlistBasePath,
+			UrlTemplate:     req.UrlTemplate,
+			IncludeExts:     req.IncludeExts,
+			ExcludeExts:     req.ExcludeExts,
+			MinFileSize:     req.MinFileSize,
+			MaxFileSize:     req.MaxFileSize,
+			ExcludePatterns: req.ExcludePatterns,
+		}
+		if err := strm.ValidateFilter(cfg); err != nil {
+			middleware.ValidationError(c, "excludePatterns "+err.Error())
+			return
+		}
+		svc, err := service.GetOpenListServiceByID(db, req.ServiceID)
+		if err != nil || svc == nil || svc.UserID != userID {
+			middleware.NotFound(c, "服务不存在")
+			return
+		}
+		if req.ConfigID > 0 {
+			saved, err := service.GetStrmConfigByID(db, req.ConfigID)
+			if err != nil || saved == nil || saved.UserID != userID {
+				middleware.NotFound(c, "配置不存在")
+				return
+			}
+			cfg.ID = saved.ID
+			cfg.PlaySecret = saved.PlaySecret
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+		defer cancel()
+		result, err := service.PreviewStrmUrl(ctx, db, svc, cfg, req.SamplePath)
+		if err != nil {
+			logger.Error("[API] /strm/config/preview-url 预览失败", zap.Int("service_id", req.ServiceID), zap.Error(err))
+			if errors.Is(err, service.ErrRemotePathNotFound) {
+				middleware.NotFound(c, "示例文件或目录不存在")
+				return
+			}
+			middleware.BadRequest(c, "预览失败："+err.Error())
+			return
+		}
+		middleware.Success(c, result)
+	}
+}
+
+// GetStrmUrlTemplateOptions godoc
+// @Summary      链接模板预设
+// @Description  返回常用播放器的链接模板预设及可用占位符
+// @Tags         StrmConfig
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Success      200 {object} middleware.Response[model.StrmUrlTemplateOptions]
+// @Router       /strm/config/url-presets [get]
+func GetStrmUrlTemplateOptions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		middleware.Success(c, model.StrmUrlTemplateOptions{
+			Presets:      strm.URLTemplatePresets,
+			Placeholders: strm.URLPlaceholders,
+		})
+	}
+}
+
+const (
+	defaultRewritePreviewLimit = 20
+	maxRewritePreviewLimit     = 200
+)
+
+// StrmRewritePreviewReq 路径改写预览请求，过滤字段与配置一致
+type StrmRewritePreviewReq struct {
+	ServiceID       int    `json:"serviceId" binding:"required"`
+	AlistBasePath   string `json:"alistBasePath" binding:"required"`
+	RewriteRules    string `json:"rewriteRules"`
+	IncludeExts     string `json:"includeExts"`
+	ExcludeExts     string `json:"excludeExts"`
+	MinFileSize     int64  `json:"minFileSize"`
+	MaxFileSize     int64  `json:"maxFileSize"`
+	ExcludePatterns string `json:"excludePatterns"`
+	Limit           int    `json:"limit"` // 预览前 N 个文件，默认 20，最大 200
+}
+
+// PreviewStrmRewrite godoc
+// @Summary      预览路径改写
+// @Description  遍历远程目录，返回前 N 个视频文件的 远程路径 → 本地 .strm 路径，不写入任何文件
+// @Tags         StrmConfig
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Param        body body StrmRewritePreviewReq true "改写规则及过滤条件"
+// @Success      200 {object} middleware.Response[[]model.StrmRewritePreviewItem]
+// @Router       /strm/config/preview-rewrite [post]
+func PreviewStrmRewrite(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req StrmRewritePreviewReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			middleware.ValidationError(c, "参数错误")
+			return
+		}
+		claims, ok := c.Get("claims")
+		if !ok {
+			middleware.Unauthorized(c, "未登录或token缺失")
+			return
+		}
+		userID := util.ExtractUserIDFromClaims(claims)
+		if req.Limit <= 0 {
+			req.Limit = defaultRewritePreviewLimit
+		}
+		if req.Limit > maxRewritePreviewLimit {
+			req.Limit = maxRewritePreviewLimit
+		}
+		cfg := &model.StrmConfig{
+			AlistBasePath:   req.AlistBasePath,
+			RewriteRules:    req.RewriteRules,
+			IncludeExts:     req.IncludeExts,
+			ExcludeExts:     req.ExcludeExts,
+			MinFileSize:     req.MinFileSize,
+			MaxFileSize:     req.MaxFileSize,
+			ExcludePatterns: req.ExcludePatterns,
+		}
+		if _, err := strm.ParseRewriteRules(cfg.RewriteRules); err != nil {
+			middleware.ValidationError(c, "rewriteRules "+err.Error())
+			return
+		}
+		if err := strm.ValidateFilter(cfg); err != nil {
+			middleware.ValidationError(c, "excludePatterns "+err.Error())
+			return
+		}
+		svc, err := service.GetOpenListServiceByID(db, req.ServiceID)
+		if err != nil || svc == nil || svc.UserID != userID {
+			middleware.NotFound(c, "服务不存在")
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+		defer cancel()
+		items, err := service.PreviewRewrite(ctx, db, svc, cfg, req.Limit)
+		if err != nil {
+			logger.Error("[API] /strm/config/preview-rewrite 预览失败", zap.Int("service_id", req.ServiceID), zap.Error(err))
+			if errors.Is(err, service.ErrRemotePathNotFound) {
+				middleware.NotFound(c, "alistBasePath 在远程服务上不存在")
+				return
+			}
+			middleware.BadRequest(c, "预览失败："+err.Error())
+			return
+		}
+		middleware.Success(c, items)
 	}
 }
 
@@ -296,4 +759,14 @@ func RegisterStrmConfigRoutes(rg *gin.RouterGroup, db *gorm.DB) {
 	rg.PUT("/config/update/:id", UpdateStrmConfig(db))
 	rg.DELETE("/config/delete/:id", DeleteStrmConfig(db))
 	rg.POST("/config/copy", CopyStrmConfig(db))
+	rg.POST("/config/generate/:id", GenerateStrmConfig(db))
+	rg.POST("/config/rotate-play-secret/:id", RotateStrmPlaySecret(db))
+	rg.POST("/config/check/:id", CheckStrmConfig(db))
+	rg.POST("/config/preview-url", PreviewStrmUrl(db))
+	rg.GET("/config/url-presets", GetStrmUrlTemplateOptions())
+	rg.POST("/config/preview-rewrite", PreviewStrmRewrite(db))
+	rg.POST("/config/dry-run/:id", DryRunStrmConfig(db))
+	rg.GET("/config/dry-run/report/:reportId", GetStrmDryRunReport())
+	rg.GET("/config/dry-run/report/:reportId/status", GetStrmDryRunStatus())
+	rg.GET("/config/dry-run/report/:reportId/export", ExportStrmDryRunReport())
 }
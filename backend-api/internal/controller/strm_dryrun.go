@@ -0,0 +1,210 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/middleware"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/service"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/strm"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/util"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// DryRunStrmConfig godoc
+// @Summary      试运行Strm生成
+// @Description  以手动优先级加入任务队列，完整遍历远程目录并与本地比对，但不写入、下载或删除任何文件；
+// @Description  立即返回报告ID，该配置已有试运行在排队时返回已有的报告；通过报告状态接口查询进度和各操作计数，完成后明细通过报告接口分页获取（报告保留 1 小时）
+// @Tags         StrmConfig
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Param        id path int true "配置ID"
+// @Success      200 {object} middleware.Response[service.DryRunReport]
+// @Router       /strm/config/dry-run/{id} [post]
+func DryRunStrmConfig(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, _ := strconv.Atoi(c.Param("id"))
+		claims, ok := c.Get("claims")
+		if !ok {
+			middleware.Unauthorized(c, "未登录或token缺失")
+			return
+		}
+		userID := util.ExtractUserIDFromClaims(claims)
+		cfg, err := service.GetStrmConfigByID(db, id)
+		if err != nil || cfg == nil || cfg.UserID != userID {
+			middleware.NotFound(c, "配置不存在")
+			return
+		}
+		if service.IsOpenListServiceDown(cfg.ServiceID) {
+			middleware.BadRequest(c, "OpenList服务不可用，请稍后重试")
+			return
+		}
+		report, duplicated, err := service.StartDryRunStrm(db, cfg, userID)
+		if err != nil {
+			logger.Error("[API] /strm/config/dry-run 入队失败", zap.Int("id", id), zap.Error(err))
+			middleware.InternalServerError(c, err.Error())
+			return
+		}
+		message := "试运行已加入队列"
+		if duplicated {
+			message = "该配置已在队列中"
+		}
+		middleware.SuccessWithMessage(c, message, report)
+	}
+}
+
+// GetStrmDryRunStatus godoc
+// @Summary      试运行报告状态
+// @Description  返回试运行的执行状态，完成后包含统计和各操作计数
+// @Tags         StrmConfig
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Param        reportId path string true "报告ID"
+// @Success      200 {object} middleware.Response[service.DryRunReport]
+// @Router       /strm/config/dry-run/report/{reportId}/status [get]
+func GetStrmDryRunStatus() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report, ok := loadDryRunReport(c)
+		if !ok {
+			return
+		}
+		middleware.Success(c, report)
+	}
+}
+
+// GetStrmDryRunReport godoc
+// @Summary      试运行报告明细
+// @Description  分页获取试运行报告中的文件操作，action 可选 create/update/delete/quarantine/download
+// @Tags         StrmConfig
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Param        reportId path string true "报告ID"
+// @Param        action query string false "操作类型"
+// @Param        page query int false "页码"
+// @Param        pageSize query int false "每页条数"
+// @Success      200 {object} middleware.Response[model.PageResult[strm.DiffItem]]
+// @Router       /strm/config/dry-run/report/{reportId} [get]
+func GetStrmDryRunReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report, ok := loadCompletedDryRunReport(c)
+		if !ok {
+			return
+		}
+		page, pageSize := util.GetPageParams(c)
+		items := report.FilterItems(strm.DiffAction(c.Query("action")))
+		start := (page - 1) * pageSize
+		if start > len(items) {
+			start = len(items)
+		}
+		end := start + pageSize
+		if end > len(items) {
+			end = len(items)
+		}
+		middleware.Success(c, model.PageResult[*strm.DiffItem]{
+			List:     items[start:end],
+			Total:    len(items),
+			Page:     page,
+			PageSize: pageSize,
+		})
+	}
+}
+
+// ExportStrmDryRunReport godoc
+// @Summary      导出试运行报告
+// @Description  以附件形式下载试运行报告，format 可选 json（默认）或 csv，action 可选筛选操作类型
+// @Tags         StrmConfig
+// @Produce      json
+// @Produce      text/csv
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Param        reportId path string true "报告ID"
+// @Param        format query string false "json 或 csv"
+// @Param        action query string false "操作类型"
+// @Success      200 {file} file
+// @Router       /strm/config/dry-run/report/{reportId}/export [get]
+func ExportStrmDryRunReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.DefaultQuery("format", "json")
+		if format != "json" && format != "csv" {
+			middleware.ValidationError(c, "format 只能是 json 或 csv")
+			return
+		}
+		report, ok := loadCompletedDryRunReport(c)
+		if !ok {
+			return
+		}
+		items := report.FilterItems(strm.DiffAction(c.Query("action")))
+		filename := fmt.Sprintf("dry-run-%d-%s.%s", report.ConfigID, report.CreatedAt.Format("20060102150405"), format)
+		var buf bytes.Buffer
+		contentType := "application/json; charset=utf-8"
+		if format == "csv" {
+			contentType = "text/csv; charset=utf-8"
+			// 写入 BOM，便于 Excel 正确识别 UTF-8 中文路径
+			buf.WriteString("\xEF\xBB\xBF")
+			w := csv.NewWriter(&buf)
+			w.Write([]string{"action", "remotePath", "localPath", "content"})
+			for _, item := range items {
+				w.Write([]string{string(item.Action), item.RemotePath, item.LocalPath, item.Content})
+			}
+			w.Flush()
+		} else {
+			enc := json.NewEncoder(&buf)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("", "  ")
+			enc.Encode(struct {
+				*service.DryRunReport
+				Items []*strm.DiffItem `json:"items"`
+			}{report, items})
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.Data(http.StatusOK, contentType, buf.Bytes())
+	}
+}
+
+// loadDryRunReport 读取当前用户的试运行报告，失败时已写入响应
+func loadDryRunReport(c *gin.Context) (*service.DryRunReport, bool) {
+	claims, ok := c.Get("claims")
+	if !ok {
+		middleware.Unauthorized(c, "未登录或token缺失")
+		return nil, false
+	}
+	userID := util.ExtractUserIDFromClaims(claims)
+	report, err := service.GetDryRunReport(c.Param("reportId"), userID)
+	if err != nil {
+		if errors.Is(err, service.ErrDryRunReportNotFound) {
+			middleware.NotFound(c, err.Error())
+			return nil, false
+		}
+		middleware.InternalServerError(c, err.Error())
+		return nil, false
+	}
+	return report, true
+}
+
+// loadCompletedDryRunReport 读取已完成的试运行报告，尚未完成或执行失败时已写入响应
+func loadCompletedDryRunReport(c *gin.Context) (*service.DryRunReport, bool) {
+	report, ok := loadDryRunReport(c)
+	if !ok {
+		return nil, false
+	}
+	switch report.Status {
+	case model.TaskStatusCompleted:
+		return report, true
+	case model.TaskStatusError:
+		middleware.BadRequest(c, "试运行失败："+report.Error)
+	default:
+		middleware.BadRequest(c, "试运行尚未完成")
+	}
+	return nil, false
+}
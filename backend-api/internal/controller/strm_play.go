@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/service"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/util"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// PlayStrm godoc
+// @Summary      播放跳转
+// @Description  .strm 中的 /play 链接，校验签名后实时解析直链；redirect 模式 302 跳转，proxy 模式由本服务中转（支持 Range）。
+// @Description  由播放器直接访问，不需要登录，出错时返回 HTTP 状态码
+// @Tags         StrmPlay
+// @Param        configId path int true "配置ID"
+// @Param        path path string true "相对 alistBasePath 的文件路径"
+// @Param        t query string true "链接签名"
+// @Success      200
+// @Success      206
+// @Success      302
+// @Failure      403 {string} string "签名无效"
+// @Failure      404 {string} string "文件不存在"
+// @Failure      429 {string} string "同时播放的流数已达上限"
+// @Router       /play/{configId}/{path} [get]
+func PlayStrm(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		configID, err := strconv.Atoi(c.Param("configId"))
+		if err != nil || configID <= 0 {
+			c.String(http.StatusBadRequest, "参数错误")
+			return
+		}
+		relPath := strings.TrimPrefix(c.Param("path"), "/")
+		// 配置不存在时同样返回签名无效，不暴露配置是否存在
+		cfg, err := service.GetPlayConfig(db, configID)
+		if err != nil || !util.VerifyPlayToken(configID, cfg.PlaySecret, relPath, c.Query("t")) {
+			logger.Info("[API] /play 签名无效", zap.Int("config_id", configID), zap.String("path", relPath), zap.String("ip", c.ClientIP()))
+			c.String(http.StatusForbidden, "签名无效")
+			return
+		}
+		if cfg.PlayMode == model.PlayModeProxy {
+			err := service.ProxyPlay(db, cfg, relPath, c.Writer, c.Request)
+			if err != nil {
+				logger.Error("[API] /play 代理失败", zap.Int("config_id", configID), zap.String("path", relPath), zap.Error(err))
+				writePlayError(c, err)
+			}
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+		defer cancel()
+		url, err := service.ResolvePlayURL(ctx, db, cfg, relPath)
+		if err != nil {
+			logger.Error("[API] /play 解析直链失败", zap.Int("config_id", configID), zap.String("path", relPath), zap.Error(err))
+			writePlayError(c, err)
+			return
+		}
+		c.Redirect(http.StatusFound, url)
+	}
+}
+
+// writePlayError 播放器无法解析 JSON，直接返回 HTTP 状态码
+func writePlayError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrPlayPathInvalid):
+		c.String(http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrPlayConfigNotFound), errors.Is(err, service.ErrRemotePathNotFound):
+		c.String(http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrTooManyStreams):
+		c.String(http.StatusTooManyRequests, err.Error())
+	default:
+		c.String(http.StatusBadGateway, "获取直链失败")
+	}
+}
+
+// RegisterPlayRoutes 统一注册/play相关接口
+func RegisterPlayRoutes(rg *gin.RouterGroup, db *gorm.DB) {
+	rg.GET("/:configId/*path", PlayStrm(db))
+	rg.HEAD("/:configId/*path", PlayStrm(db))
+}
@@ -1,10 +1,43 @@
 package controller
 
 import (
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/middleware"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/service"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/util"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
+type StrmTaskReq struct {
+	Name          string              `json:"name" binding:"required"`
+	ScheduledTime string              `json:"scheduledTime"`               // once 的执行时间或 interval 的起点，RFC3339 格式，如 2024-01-01T03:00:00+08:00
+	TaskMode      model.TaskMode      `json:"taskMode" binding:"required"` // create/check
+	Enabled       model.Enabled       `json:"enabled"`                     // 支持字符串或数字
+	ServiceID     int                 `json:"serviceId" binding:"required"`
+	ConfigID      int                 `json:"configId" binding:"required"`
+	AutoRepair    bool                `json:"autoRepair"`    // check 模式发现问题时自动修复
+	ScheduleType  model.ScheduleType  `json:"scheduleType"`  // cron/interval/once，默认 once
+	CronExpr      string              `json:"cronExpr"`      // 5 段 cron 表达式，如 0 3 * * *
+	IntervalHours int                 `json:"intervalHours"` // interval 模式的间隔小时数，以 scheduledTime 为起点
+	CatchUp       model.CatchUpPolicy `json:"catchUp"`       // 停机期间错过执行的处理策略 skip/once，为空使用全局默认
+}
+
+type StrmTaskToggleReq struct {
+	Enabled model.Enabled `json:"enabled" binding:"required"`
+}
+
+type StrmTaskCopyReq struct {
+	IDs []int `json:"ids" binding:"required"`
+}
+
 func RegisterStrmTaskRoutes(rg *gin.RouterGroup, db *gorm.DB) {
 	rg.GET("/list", ListStrmTasks(db))
 	rg.POST("/add", CreateStrmTask(db))
@@ -12,46 +45,655 @@ func RegisterStrmTaskRoutes(rg *gin.RouterGroup, db *gorm.DB) {
 	rg.DELETE("/delete/:id", DeleteStrmTask(db))
 	rg.GET("/detail/:id", GetStrmTask(db))
 	rg.POST("/copy", CopyStrmTask(db))
+	rg.POST("/execute/:id", ExecuteStrmTask(db))
+	rg.PUT("/toggle/:id", ToggleStrmTask(db))
+	rg.GET("/queue", ListStrmTaskQueue())
+	rg.POST("/:id/cancel", CancelStrmTask(db))
+	rg.POST("/:id/pause", PauseStrmTask(db))
+	rg.POST("/:id/resume", ResumeStrmTask(db))
+	rg.GET("/:id/progress", StreamStrmTaskProgress(db))
+	rg.GET("/:id/progress/snapshot", GetStrmTaskProgress(db))
+	rg.POST("/:id/progress/ticket", IssueStrmTaskProgressTicket(db))
+}
+
+// convertToStrmTaskResponse 将 StrmTask 转换为 StrmTaskResponse，configName 为所属配置名称
+func convertToStrmTaskResponse(task *model.StrmTask, configName string) model.StrmTaskResponse {
+	scheduledTime := ""
+	if !task.ScheduledTime.IsZero() {
+		scheduledTime = task.ScheduledTime.Format("2006-01-02T15:04:05Z07:00")
+	}
+	return model.StrmTaskResponse{
+		ID:            task.ID,
+		Name:          task.Name,
+		ScheduledTime: scheduledTime,
+		TaskMode:      string(task.TaskMode),
+		Enabled:       model.Enabled(strconv.Itoa(util.Bool2Int(task.Enabled))),
+		ServiceID:     task.ServiceID,
+		ConfigID:      task.ConfigID,
+		ConfigName:    configName,
+		AutoRepair:    task.AutoRepair,
+		ScheduleType:  string(task.ScheduleType),
+		CronExpr:      task.CronExpr,
+		IntervalHours: task.IntervalHours,
+		CatchUp:       string(task.CatchUp),
+		NextRunAt:     formatOptionalTime(task.NextRunAt),
+		LastRunAt:     formatOptionalTime(task.LastRunAt),
+		CreatedAt:     task.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:     task.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02T15:04:05Z07:00")
 }
 
+// applyStrmTaskReq 将请求字段写入任务，scheduledTime 已在校验时解析
+func applyStrmTaskReq(task *model.StrmTask, req *StrmTaskReq, scheduledTime time.Time) {
+	task.Name = req.Name
+	task.ScheduledTime = scheduledTime
+	task.TaskMode = req.TaskMode
+	task.Enabled = util.ParseEnabled(string(req.Enabled))
+	task.ServiceID = req.ServiceID
+	task.ConfigID = req.ConfigID
+	task.AutoRepair = req.AutoRepair
+	task.ScheduleType = req.ScheduleType
+	if task.ScheduleType == "" {
+		task.ScheduleType = model.ScheduleTypeOnce
+	}
+	task.CronExpr = req.CronExpr
+	task.IntervalHours = req.IntervalHours
+	task.CatchUp = req.CatchUp
+}
+
+// validateStrmTaskReq 校验任务模式、计划时间及服务/配置归属，失败时已写入响应
+func validateStrmTaskReq(c *gin.Context, db *gorm.DB, userID int, req *StrmTaskReq) (time.Time, bool) {
+	var scheduledTime time.Time
+	switch req.TaskMode {
+	case model.TaskModeCreate, model.TaskModeCheck:
+	default:
+		middleware.ValidationError(c, "taskMode 仅支持 create/check")
+		return scheduledTime, false
+	}
+	if req.ScheduledTime != "" {
+		t, err := time.Parse(time.RFC3339, req.ScheduledTime)
+		if err != nil {
+			middleware.ValidationError(c, "scheduledTime 格式错误，应为 RFC3339")
+			return scheduledTime, false
+		}
+		scheduledTime = t
+	}
+	if err := service.ValidateStrmTaskSchedule(&model.StrmTask{
+		ScheduleType:  req.ScheduleType,
+		CronExpr:      req.CronExpr,
+		IntervalHours: req.IntervalHours,
+		ScheduledTime: scheduledTime,
+		CatchUp:       req.CatchUp,
+	}); err != nil {
+		middleware.ValidationError(c, err.Error())
+		return scheduledTime, false
+	}
+	svc, err := service.GetOpenListServiceByID(db, req.ServiceID)
+	if err != nil || svc == nil || svc.UserID != userID {
+		middleware.ValidationError(c, "服务不存在")
+		return scheduledTime, false
+	}
+	cfg, err := service.GetStrmConfigByID(db, req.ConfigID)
+	if err != nil || cfg == nil || cfg.UserID != userID {
+		middleware.ValidationError(c, "配置不存在")
+		return scheduledTime, false
+	}
+	if cfg.ServiceID != req.ServiceID {
+		middleware.ValidationError(c, "配置不属于该服务")
+		return scheduledTime, false
+	}
+	return scheduledTime, true
+}
+
+// getOwnedStrmTask 获取当前用户的任务，不存在或不属于当前用户时已写入响应
+func getOwnedStrmTask(c *gin.Context, db *gorm.DB) (*model.StrmTask, bool) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	claims, ok := c.Get("claims")
+	if !ok {
+		middleware.Unauthorized(c, "未登录或token缺失")
+		return nil, false
+	}
+	userID := util.ExtractUserIDFromClaims(claims)
+	task, err := service.GetStrmTaskByID(db, id)
+	if err != nil || task == nil || task.UserID != userID {
+		middleware.NotFound(c, "任务不存在")
+		return nil, false
+	}
+	return task, true
+}
+
+// strmConfigName 查询配置名称，配置已删除时返回空
+func strmConfigName(db *gorm.DB, configID int, cache map[int]string) string {
+	if name, ok := cache[configID]; ok {
+		return name
+	}
+	name := ""
+	if cfg, err := service.GetStrmConfigByID(db, configID); err == nil && cfg != nil {
+		name = cfg.Name
+	}
+	cache[configID] = name
+	return name
+}
+
+// ListStrmTasks godoc
+// @Summary      分页查询Strm任务
+// @Description  分页查询当前用户的任务，支持 serviceId/configId/taskMode/enabled/name 筛选，返回list/total/page/pageSize
+// @Tags         StrmTask
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Param        serviceId query int false "服务ID"
+// @Param        configId query int false "配置ID"
+// @Param        taskMode query string false "任务模式 create/check"
+// @Param        enabled query string false "是否启用 1/0"
+// @Param        name query string false "名称关键字"
+// @Param        page query int false "页码(默认1)"
+// @Param        pageSize query int false "每页条数(默认10)"
+// @Success      200 {object} middleware.Response[model.PageResult[model.StrmTaskResponse]]
+// @Router       /strm/task/list [get]
 func ListStrmTasks(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// TODO: 实现
+		claims, ok := c.Get("claims")
+		if !ok {
+			middleware.Unauthorized(c, "未登录或token缺失")
+			return
+		}
+		userID := util.ExtractUserIDFromClaims(claims)
+		query := &model.StrmTaskQuery{
+			UserID:   userID,
+			TaskMode: model.TaskMode(c.Query("taskMode")),
+			Name:     c.Query("name"),
+		}
+		query.ServiceID, _ = strconv.Atoi(c.Query("serviceId"))
+		query.ConfigID, _ = strconv.Atoi(c.Query("configId"))
+		if enabled := c.Query("enabled"); enabled != "" {
+			v := util.ParseEnabled(enabled)
+			query.Enabled = &v
+		}
+		page, pageSize := util.GetPageParams(c)
+		tasks, total, err := service.ListStrmTasks(db, query, page, pageSize)
+		if err != nil {
+			logger.Error("[API] /strm/task/list 查询失败", zap.Error(err))
+			middleware.InternalServerError(c, "查询失败")
+			return
+		}
+		names := make(map[int]string)
+		list := make([]model.StrmTaskResponse, len(tasks))
+		for i, v := range tasks {
+			list[i] = convertToStrmTaskResponse(v, strmConfigName(db, v.ConfigID, names))
+		}
+		middleware.Success(c, model.PageResult[model.StrmTaskResponse]{
+			List:     list,
+			Total:    int(total),
+			Page:     page,
+			PageSize: pageSize,
+		})
 	}
 }
 
+// CreateStrmTask godoc
+// @Summary      新增Strm任务
+// @Description  新增任务，serviceId/configId 须属于当前用户且配置属于该服务
+// @Tags         StrmTask
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Param        body body StrmTaskReq true "任务内容"
+// @Success      200 {object} middleware.Response[model.StrmTaskResponse]
+// @Router       /strm/task/add [post]
 func CreateStrmTask(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// TODO: 实现
+		var req StrmTaskReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			logger.Error("[API] /strm/task 新增 参数绑定失败", zap.Error(err))
+			middleware.ValidationError(c, "参数错误")
+			return
+		}
+		claims, ok := c.Get("claims")
+		if !ok {
+			middleware.Unauthorized(c, "未登录或token缺失")
+			return
+		}
+		userID := util.ExtractUserIDFromClaims(claims)
+		scheduledTime, ok := validateStrmTaskReq(c, db, userID, &req)
+		if !ok {
+			return
+		}
+		task := &model.StrmTask{UserID: userID}
+		applyStrmTaskReq(task, &req, scheduledTime)
+		if err := service.CreateStrmTask(db, task); err != nil {
+			logger.Error("[API] /strm/task 新增失败", zap.Error(err))
+			middleware.InternalServerError(c, "新增失败")
+			return
+		}
+		middleware.SuccessWithMessage(c, "新增成功", convertToStrmTaskResponse(task, strmConfigName(db, task.ConfigID, map[int]string{})))
 	}
 }
 
+// UpdateStrmTask godoc
+// @Summary      编辑Strm任务
+// @Description  编辑指定ID的任务
+// @Tags         StrmTask
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Param        id path int true "任务ID"
+// @Param        body body StrmTaskReq true "任务内容"
+// @Success      200 {object} middleware.Response[model.StrmTaskResponse]
+// @Router       /strm/task/update/{id} [put]
 func UpdateStrmTask(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// TODO: 实现
+		var req StrmTaskReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			middleware.ValidationError(c, "参数错误")
+			return
+		}
+		task, ok := getOwnedStrmTask(c, db)
+		if !ok {
+			return
+		}
+		scheduledTime, ok := validateStrmTaskReq(c, db, task.UserID, &req)
+		if !ok {
+			return
+		}
+		applyStrmTaskReq(task, &req, scheduledTime)
+		if err := service.UpdateStrmTask(db, task); err != nil {
+			logger.Error("[API] /strm/task 编辑失败", zap.Int("id", task.ID), zap.Error(err))
+			middleware.InternalServerError(c, "编辑失败")
+			return
+		}
+		middleware.SuccessWithMessage(c, "编辑成功", convertToStrmTaskResponse(task, strmConfigName(db, task.ConfigID, map[int]string{})))
 	}
 }
 
+// DeleteStrmTask godoc
+// @Summary      删除Strm任务
+// @Description  删除指定ID的任务及其运行日志
+// @Tags         StrmTask
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Param        id path int true "任务ID"
+// @Success      200 {object} middleware.Response[string]
+// @Router       /strm/task/delete/{id} [delete]
 func DeleteStrmTask(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// TODO: 实现
+		task, ok := getOwnedStrmTask(c, db)
+		if !ok {
+			return
+		}
+		if err := service.DeleteStrmTask(db, task.ID); err != nil {
+			logger.Error("[API] /strm/task 删除失败", zap.Int("id", task.ID), zap.Error(err))
+			middleware.InternalServerError(c, "删除失败")
+			return
+		}
+		middleware.SuccessWithMessage(c, "删除成功", nil)
 	}
 }
 
+// GetStrmTask godoc
+// @Summary      Strm任务详情
+// @Description  获取指定ID的任务详情
+// @Tags         StrmTask
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Param        id path int true "任务ID"
+// @Success      200 {object} middleware.Response[model.StrmTaskResponse]
+// @Router       /strm/task/detail/{id} [get]
 func GetStrmTask(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// TODO: 实现
+		task, ok := getOwnedStrmTask(c, db)
+		if !ok {
+			return
+		}
+		middleware.Success(c, convertToStrmTaskResponse(task, strmConfigName(db, task.ConfigID, map[int]string{})))
 	}
 }
 
+// CopyStrmTask godoc
+// @Summary      批量复制Strm任务
+// @Description  通过ids批量复制任务，名称后追加“-复制”，复制出的任务默认停用
+// @Tags         StrmTask
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Param        body body StrmTaskCopyReq true "要复制的id列表"
+// @Success      200 {object} middleware.Response[string]
+// @Router       /strm/task/copy [post]
 func CopyStrmTask(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// TODO: 实现
+		var req StrmTaskCopyReq
+		if err := c.ShouldBindJSON(&req); err != nil || len(req.IDs) == 0 {
+			middleware.ValidationError(c, "参数错误")
+			return
+		}
+		claims, ok := c.Get("claims")
+		if !ok {
+			middleware.Unauthorized(c, "未登录或token缺失")
+			return
+		}
+		userID := util.ExtractUserIDFromClaims(claims)
+		tasks := make([]*model.StrmTask, 0, len(req.IDs))
+		for _, id := range req.IDs {
+			task, err := service.GetStrmTaskByID(db, id)
+			if err != nil || task == nil || task.UserID != userID {
+				middleware.NotFound(c, "任务不存在："+strconv.Itoa(id))
+				return
+			}
+			tasks = append(tasks, task)
+		}
+		if err := service.CopyStrmTasks(db, tasks); err != nil {
+			logger.Error("[API] /strm/task/copy 复制失败", zap.Error(err))
+			middleware.InternalServerError(c, "复制失败")
+			return
+		}
+		middleware.SuccessWithMessage(c, "复制成功", nil)
 	}
 }
 
+// ExecuteStrmTask godoc
+// @Summary      立即执行Strm任务
+// @Description  以手动优先级加入任务队列，按任务模式执行生成或检查，执行结果记录到运行日志；同一配置已有相同模式的执行在排队时不重复入队
+// @Tags         StrmTask
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Param        id path int true "任务ID"
+// @Success      200 {object} middleware.Response[model.StrmQueueJobResponse]
+// @Router       /strm/task/execute/{id} [post]
 func ExecuteStrmTask(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// TODO: 实现
+		task, ok := getOwnedStrmTask(c, db)
+		if !ok {
+			return
+		}
+		if service.IsOpenListServiceDown(task.ServiceID) {
+			middleware.BadRequest(c, "OpenList服务不可用，请稍后重试")
+			return
+		}
+		job, duplicated, err := service.EnqueueStrmTask(task, service.JobPriorityManual)
+		if err != nil {
+			logger.Error("[API] /strm/task/execute 入队失败", zap.Int("id", task.ID), zap.Error(err))
+			middleware.InternalServerError(c, err.Error())
+			return
+		}
+		message := "任务已加入队列"
+		if duplicated {
+			message = "该配置已在队列中"
+		}
+		middleware.SuccessWithMessage(c, message, convertToStrmQueueJobResponse(job, 0))
+	}
+}
+
+// convertToStrmQueueJobResponse 将队列中的执行转换为响应结构，position 为排队位置
+func convertToStrmQueueJobResponse(job *service.StrmQueueJob, position int) model.StrmQueueJobResponse {
+	startedAt := ""
+	if !job.StartedAt.IsZero() {
+		startedAt = job.StartedAt.Format("2006-01-02T15:04:05Z07:00")
+	}
+	return model.StrmQueueJobResponse{
+		JobID:      job.ID,
+		TaskID:     job.Task.ID,
+		TaskName:   job.Task.Name,
+		TaskMode:   string(job.Task.TaskMode),
+		ConfigID:   job.Task.ConfigID,
+		ServiceID:  job.Task.ServiceID,
+		Priority:   job.Priority.String(),
+		State:      string(job.State),
+		Position:   position,
+		EnqueuedAt: job.EnqueuedAt.Format("2006-01-02T15:04:05Z07:00"),
+		StartedAt:  startedAt,
+	}
+}
+
+// ListStrmTaskQueue godoc
+// @Summary      查看任务队列
+// @Description  返回当前用户执行中和排队中的任务，执行中的在前，排队的按执行顺序排列
+// @Tags         StrmTask
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Success      200 {object} middleware.Response[[]model.StrmQueueJobResponse]
+// @Router       /strm/task/queue [get]
+func ListStrmTaskQueue() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := c.Get("claims")
+		if !ok {
+			middleware.Unauthorized(c, "未登录或token缺失")
+			return
+		}
+		userID := util.ExtractUserIDFromClaims(claims)
+		list := []model.StrmQueueJobResponse{}
+		if q := service.GetStrmTaskQueue(); q != nil {
+			// 排队位置按全局队列计算，其他用户的任务不返回
+			position := 0
+			for _, job := range q.Jobs() {
+				job := job
+				p := 0
+				if job.State == service.JobStateQueued {
+					position++
+					p = position
+				}
+				if job.Task.UserID == userID {
+					list = append(list, convertToStrmQueueJobResponse(&job, p))
+				}
+			}
+		}
+		middleware.Success(c, list)
+	}
+}
+
+// ToggleStrmTask godoc
+// @Summary      启用/停用Strm任务
+// @Description  启用后立即按调度方式计算下一次执行时间，停用后不再调度
+// @Tags         StrmTask
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Param        id path int true "任务ID"
+// @Param        body body StrmTaskToggleReq true "是否启用 1/0"
+// @Success      200 {object} middleware.Response[model.StrmTaskResponse]
+// @Router       /strm/task/toggle/{id} [put]
+func ToggleStrmTask(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req StrmTaskToggleReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			middleware.ValidationError(c, "参数错误")
+			return
+		}
+		task, ok := getOwnedStrmTask(c, db)
+		if !ok {
+			return
+		}
+		if err := service.ToggleStrmTask(db, task, util.ParseEnabled(string(req.Enabled))); err != nil {
+			logger.Error("[API] /strm/task/toggle 失败", zap.Int("id", task.ID), zap.Error(err))
+			middleware.InternalServerError(c, "操作失败")
+			return
+		}
+		middleware.SuccessWithMessage(c, "操作成功", convertToStrmTaskResponse(task, strmConfigName(db, task.ConfigID, map[int]string{})))
+	}
+}
+
+// CancelStrmTask godoc
+// @Summary      取消Strm任务
+// @Description  排队中的任务移出队列；执行中的任务停止，已写入的 .strm 保持完整，不清理孤立文件也不更新清单；已暂停的任务清除保存的进度。运行日志标记为 cancelled
+// @Tags         StrmTask
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Param        id path int true "任务ID"
+// @Success      200 {object} middleware.Response[string]
+// @Router       /strm/task/{id}/cancel [post]
+func CancelStrmTask(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		task, ok := getOwnedStrmTask(c, db)
+		if !ok {
+			return
+		}
+		if err := service.CancelStrmTask(db, task); err != nil {
+			if errors.Is(err, service.ErrTaskNotRunning) {
+				middleware.BadRequest(c, err.Error())
+				return
+			}
+			logger.Error("[API] /strm/task/:id/cancel 失败", zap.Int("id", task.ID), zap.Error(err))
+			middleware.InternalServerError(c, "取消失败")
+			return
+		}
+		middleware.SuccessWithMessage(c, "任务已取消", nil)
+	}
+}
+
+// PauseStrmTask godoc
+// @Summary      暂停Strm任务
+// @Description  停止执行中的生成任务并保存进度，运行日志标记为 paused，恢复后从未遍历完的目录继续；检查任务不支持暂停
+// @Tags         StrmTask
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Param        id path int true "任务ID"
+// @Success      200 {object} middleware.Response[string]
+// @Router       /strm/task/{id}/pause [post]
+func PauseStrmTask(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		task, ok := getOwnedStrmTask(c, db)
+		if !ok {
+			return
+		}
+		if err := service.PauseStrmTask(task); err != nil {
+			middleware.BadRequest(c, err.Error())
+			return
+		}
+		middleware.SuccessWithMessage(c, "任务正在暂停", nil)
+	}
+}
+
+// ResumeStrmTask godoc
+// @Summary      恢复Strm任务
+// @Description  将已暂停的任务以手动优先级加入队列，从暂停时保存的进度继续执行
+// @Tags         StrmTask
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Param        id path int true "任务ID"
+// @Success      200 {object} middleware.Response[model.StrmQueueJobResponse]
+// @Router       /strm/task/{id}/resume [post]
+func ResumeStrmTask(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		task, ok := getOwnedStrmTask(c, db)
+		if !ok {
+			return
+		}
+		job, _, err := service.ResumeStrmTask(db, task)
+		if err != nil {
+			if errors.Is(err, service.ErrTaskNotPaused) {
+				middleware.BadRequest(c, err.Error())
+				return
+			}
+			logger.Error("[API] /strm/task/:id/resume 失败", zap.Int("id", task.ID), zap.Error(err))
+			middleware.InternalServerError(c, err.Error())
+			return
+		}
+		middleware.SuccessWithMessage(c, "任务已加入队列", convertToStrmQueueJobResponse(job, 0))
+	}
+}
+
+// progressHeartbeat SSE 连接的心跳间隔，避免代理因长时间无数据断开连接
+const progressHeartbeat = 15 * time.Second
+
+// IssueStrmTaskProgressTicket godoc
+// @Summary      获取Strm任务进度订阅凭证
+// @Description  EventSource 无法设置请求头，先通过本接口获取凭证，再以查询参数 ticket 连接进度订阅接口；凭证绑定任务和用户，30 秒内可重复使用，便于断线重连
+// @Tags         StrmTask
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Param        id path int true "任务ID"
+// @Success      200 {object} middleware.Response[model.StrmProgressTicketResponse]
+// @Router       /strm/task/{id}/progress/ticket [post]
+func IssueStrmTaskProgressTicket(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		task, ok := getOwnedStrmTask(c, db)
+		if !ok {
+			return
+		}
+		ticket, expiresAt := service.IssueStrmProgressTicket(task.UserID, task.ID)
+		middleware.Success(c, model.StrmProgressTicketResponse{
+			Ticket:    ticket,
+			ExpiresAt: expiresAt.Format("2006-01-02T15:04:05Z07:00"),
+		})
+	}
+}
+
+// StreamStrmTaskProgress godoc
+// @Summary      订阅Strm任务进度
+// @Description  以 Server-Sent Events 推送任务进度，连接后先推送当前状态，执行中至多每 500ms 推送一次，事件名为 progress；
+// @Description  EventSource 无法设置请求头，可通过查询参数 ticket 传递 /strm/task/{id}/progress/ticket 获取的凭证。
+// @Description  每 15 秒发送一次注释行作为心跳；推送 completed/error/cancelled/skipped 状态后发送 end 事件并关闭连接
+// @Tags         StrmTask
+// @Produce      text/event-stream
+// @Param        Authorization header string false "Bearer {accessToken}"
+// @Param        ticket query string false "订阅凭证，未设置 Authorization 时使用"
+// @Param        id path int true "任务ID"
+// @Success      200 {object} service.StrmTaskProgress
+// @Router       /strm/task/{id}/progress [get]
+func StreamStrmTaskProgress(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		task, ok := getOwnedStrmTask(c, db)
+		if !ok {
+			return
+		}
+		updates, unsubscribe := service.SubscribeStrmTaskProgress(task.ID)
+		defer unsubscribe()
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		// 关闭 nginx 缓冲，保证事件即时送达
+		c.Header("X-Accel-Buffering", "no")
+		heartbeat := time.NewTicker(progressHeartbeat)
+		defer heartbeat.Stop()
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case p := <-updates:
+				c.SSEvent("progress", p)
+				// 执行已结束，通知客户端关闭，避免 EventSource 自动重连
+				if p.Finished() {
+					c.SSEvent("end", p.Status)
+					return false
+				}
+			case <-heartbeat.C:
+				io.WriteString(w, ": ping\n\n")
+			case <-c.Request.Context().Done():
+				return false
+			}
+			return true
+		})
+	}
+}
+
+// GetStrmTaskProgress godoc
+// @Summary      获取Strm任务进度
+// @Description  获取任务的最新进度，供无法使用 SSE 的客户端轮询；服务启动后尚未执行过的任务状态为 idle
+// @Tags         StrmTask
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {accessToken}"
+// @Param        id path int true "任务ID"
+// @Success      200 {object} middleware.Response[service.StrmTaskProgress]
+// @Router       /strm/task/{id}/progress/snapshot [get]
+func GetStrmTaskProgress(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		task, ok := getOwnedStrmTask(c, db)
+		if !ok {
+			return
+		}
+		middleware.Success(c, service.GetStrmTaskProgress(task.ID))
 	}
 }
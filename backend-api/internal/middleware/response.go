@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -63,8 +64,28 @@ func AuthMiddleware(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		// 播放跳转由播放器直接访问，使用链接签名自行校验（前缀匹配）
+		if strings.HasPrefix(path, "/play/") {
+			c.Next()
+			return
+		}
+
 		// 解析token
 		tokenStr := c.GetHeader("Authorization")
+		// EventSource 无法设置请求头，任务进度的 SSE 连接使用短期凭证 ticket，避免 accessToken 出现在 URL 和访问日志中
+		if tokenStr == "" && c.Query("ticket") != "" && strings.HasPrefix(path, "/strm/task/") && strings.HasSuffix(path, "/progress") {
+			taskID, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(path, "/strm/task/"), "/progress"))
+			userID, ok := service.RedeemStrmProgressTicket(c.Query("ticket"), taskID)
+			if !ok {
+				logger.Info("[AuthMiddleware] 进度订阅凭证无效，拒绝", zap.String("path", path))
+				Unauthorized(c, "ticket无效或已过期")
+				c.Abort()
+				return
+			}
+			c.Set("claims", jwt.MapClaims{"user_id": float64(userID)})
+			c.Next()
+			return
+		}
 		if tokenStr == "" {
 			logger.Info("[AuthMiddleware] 未获取到token，拒绝", zap.String("path", path))
 			Unauthorized(c, "未登录或token缺失")
@@ -8,6 +8,13 @@ import (
 	"gorm.io/gorm"
 )
 
+// 扩展名字典类型，每条记录的 Value 为一个扩展名（如 .mkv）
+const (
+	DictTypeVideoExt    = "video_ext"
+	DictTypeSubtitleExt = "subtitle_ext"
+	DictTypeImageExt    = "image_ext"
+)
+
 type Dict struct {
 	ID          int       `json:"id" gorm:"primaryKey;autoIncrement"`
 	Type        string    `json:"type" gorm:"type:varchar(64);index"`
@@ -58,6 +65,15 @@ func GetDictByID(db *gorm.DB, id int) (*Dict, error) {
 	return &d, nil
 }
 
+// CountDictsByType 统计指定类型的字典数量
+func CountDictsByType(db *gorm.DB, dictType string) (int64, error) {
+	var count int64
+	if err := db.Model(&Dict{}).Where("type = ?", dictType).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 func ListDicts(db *gorm.DB, dictType string) ([]*Dict, error) {
 	var dicts []*Dict
 	var err error
@@ -20,6 +20,9 @@ const (
 	TaskStatusRunning   TaskStatus = "running"
 	TaskStatusError     TaskStatus = "error"
 	TaskStatusCompleted TaskStatus = "completed"
+	TaskStatusSkipped   TaskStatus = "skipped" // 依赖的OpenList服务不可用，跳过执行
+	TaskStatusCancelled TaskStatus = "cancelled"
+	TaskStatusPaused    TaskStatus = "paused" // 已保存进度，可恢复执行
 )
 
 type LogRecord struct {
@@ -30,6 +33,9 @@ type LogRecord struct {
 	CreatedAt  time.Time  `json:"createdAt"`
 	TaskStatus TaskStatus `json:"taskStatus" gorm:"type:varchar(32)"`
 	TaskID     int        `json:"taskId"`
+	UsedUrl    string     `json:"usedUrl" gorm:"type:varchar(255)"` // 本次运行实际使用的 OpenList 地址（主/备）
+	ConfigID   int        `json:"configId" gorm:"index"`
+	Result     string     `json:"result" gorm:"type:text"` // 运行结果 JSON（生成统计或检查报告）
 }
 
 func CreateLogRecord(db *gorm.DB, record *LogRecord) error {
@@ -83,6 +89,16 @@ func UpdateLogRecordStatus(db *gorm.DB, id int, status TaskStatus) error {
 	return nil
 }
 
+// FinishLogRecord 运行结束时写入状态、实际使用的地址和结果
+func FinishLogRecord(db *gorm.DB, id int, status TaskStatus, usedUrl, result string) error {
+	logger.Info("[DB] FinishLogRecord", zap.Int("id", id), zap.String("status", string(status)))
+	if err := db.Model(&LogRecord{}).Where("id = ?", id).Updates(map[string]interface{}{"task_status": status, "used_url": usedUrl, "result": result}).Error; err != nil {
+		logger.Error("[DB] FinishLogRecord error", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
 func DeleteLogRecord(db *gorm.DB, id int) error {
 	logger.Info("[DB] DeleteLogRecord", zap.Int("id", id))
 	if err := db.Delete(&LogRecord{}, id).Error; err != nil {
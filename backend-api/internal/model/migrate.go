@@ -3,10 +3,12 @@ package model
 import (
 	"strings"
 
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
-// 统一管理所有表的迁移（仅在表不存在时创建表，不做结构变更）
+// 统一管理所有表的迁移（表不存在时创建表；已存在的表仅补充缺失的列，不修改/删除已有列）
 func MigrateIfNotExists(db *gorm.DB) error {
 	models := []interface{}{
 		&User{},
@@ -15,6 +17,9 @@ func MigrateIfNotExists(db *gorm.DB) error {
 		&StrmTask{},
 		&LogRecord{},
 		&Dict{},
+		&OpenListHealthRecord{},
+		&StrmManifestEntry{},
+		&StrmTaskCheckpoint{},
 	}
 	for _, m := range models {
 		if !db.Migrator().HasTable(m) {
@@ -25,7 +30,30 @@ func MigrateIfNotExists(db *gorm.DB) error {
 			} else if err != nil {
 					return err
 			}
+			continue
 	}
+		if err := addMissingColumns(db, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addMissingColumns 为已存在的表补充模型中新增的列
+func addMissingColumns(db *gorm.DB, m interface{}) error {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(m); err != nil {
+		return err
+	}
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName == "" || db.Migrator().HasColumn(m, field.DBName) {
+			continue
+		}
+		logger.Info("[DB] 新增列", zap.String("table", stmt.Schema.Table), zap.String("column", field.DBName))
+		if err := db.Migrator().AddColumn(m, field.Name); err != nil {
+			logger.Error("[DB] 新增列失败", zap.String("table", stmt.Schema.Table), zap.String("column", field.DBName), zap.Error(err))
+			return err
+		}
 	}
 	return nil
 } 
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type HealthStatus string
+
+const (
+	HealthStatusUnknown HealthStatus = "unknown"
+	HealthStatusUp      HealthStatus = "up"
+	HealthStatusDown    HealthStatus = "down"
+)
+
+// OpenListHealthRecord OpenList 服务健康检查采样记录
+type OpenListHealthRecord struct {
+	ID        int          `json:"id" gorm:"primaryKey;autoIncrement"`
+	ServiceID int          `json:"serviceId" gorm:"index"`
+	Status    HealthStatus `json:"status" gorm:"type:varchar(16)"`
+	LatencyMs int64        `json:"latencyMs"`
+	Url       string       `json:"url" gorm:"type:varchar(255)"`
+	Error     string       `json:"error" gorm:"type:varchar(512)"`
+	CheckedAt time.Time    `json:"checkedAt" gorm:"index"`
+}
+
+func CreateOpenListHealthRecord(db *gorm.DB, record *OpenListHealthRecord) error {
+	if record.CheckedAt.IsZero() {
+		record.CheckedAt = time.Now()
+	}
+	if err := db.Create(record).Error; err != nil {
+		logger.Error("[DB] CreateOpenListHealthRecord error", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetLatestOpenListHealthRecord 获取服务最近一次检查记录
+func GetLatestOpenListHealthRecord(db *gorm.DB, serviceID int) (*OpenListHealthRecord, error) {
+	var record OpenListHealthRecord
+	if err := db.Where("service_id = ?", serviceID).Order("checked_at DESC").First(&record).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// GetOpenListHealthRecords 分页获取服务健康检查历史，返回数据和总数
+func GetOpenListHealthRecords(db *gorm.DB, serviceID, page, pageSize int) ([]*OpenListHealthRecord, int64, error) {
+	var records []*OpenListHealthRecord
+	var total int64
+	db = db.Model(&OpenListHealthRecord{}).Where("service_id = ?", serviceID)
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	}
+	offset := (page - 1) * pageSize
+	if err := db.Order("checked_at DESC").Limit(pageSize).Offset(offset).Find(&records).Error; err != nil {
+		return nil, 0, err
+	}
+	return records, total, nil
+}
+
+// DeleteOpenListHealthRecordsBefore 清理指定时间之前的检查记录
+func DeleteOpenListHealthRecordsBefore(db *gorm.DB, before time.Time) error {
+	logger.Info("[DB] DeleteOpenListHealthRecordsBefore", zap.Time("before", before))
+	if err := db.Where("checked_at < ?", before).Delete(&OpenListHealthRecord{}).Error; err != nil {
+		logger.Error("[DB] DeleteOpenListHealthRecordsBefore error", zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -13,6 +13,12 @@ type OpenListService struct {
 	Name       string    `json:"name" gorm:"type:varchar(128);index"`
 	Account    string    `json:"account" gorm:"type:varchar(128)"`
 	Token      string    `json:"token" gorm:"type:varchar(255)"`
+	Password   string    `json:"-" gorm:"type:varchar(512)"` // AES 加密后的登录密码，用于自动获取/刷新 token
+	SignSecret string    `json:"-" gorm:"type:varchar(512)"` // AES 加密后的签名密钥（OpenList 站点 token），用于本地计算 sign
+	SignExpireHours int  `json:"signExpireHours"`            // 签名有效期（小时），与 OpenList 链接过期设置一致，0 表示永不过期
+	WalkConcurrency int  `json:"walkConcurrency"`            // 遍历目录的并发数，0 表示默认（1）
+	RequestRateLimit float64 `json:"requestRateLimit"`       // 每秒请求数上限，同一服务的所有任务共享，0 表示不限速
+	TaskConcurrency int  `json:"taskConcurrency"`            // 同时执行的任务数上限，0 表示默认（1）
 	ServiceUrl string    `json:"serviceUrl" gorm:"type:varchar(255)"`
 	BackupUrl  string    `json:"backupUrl" gorm:"type:varchar(255)"`
 	Enabled    bool      `json:"enabled"`
@@ -62,6 +68,24 @@ func GetOpenListServicesByUserID(db *gorm.DB, userID, page, pageSize int) ([]*Op
 	return services, total, nil
 }
 
+// GetAllOpenListServicesByUserID 获取用户全部OpenList服务
+func GetAllOpenListServicesByUserID(db *gorm.DB, userID int) ([]*OpenListService, error) {
+	var services []*OpenListService
+	if err := db.Where("user_id = ?", userID).Order("created_at DESC").Find(&services).Error; err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+// GetEnabledOpenListServices 获取所有已启用的OpenList服务
+func GetEnabledOpenListServices(db *gorm.DB) ([]*OpenListService, error) {
+	var services []*OpenListService
+	if err := db.Where("enabled = ?", true).Find(&services).Error; err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
 // UpdateOpenListService 更新OpenList服务
 func UpdateOpenListService(db *gorm.DB, service *OpenListService) error {
 	logger.Info("[DB] UpdateOpenListService", zap.Int("id", service.ID))
@@ -73,6 +97,41 @@ func UpdateOpenListService(db *gorm.DB, service *OpenListService) error {
 	return nil
 }
 
+// UpdateOpenListServiceSign 更新签名设置，secret 为空时保留原密钥
+func UpdateOpenListServiceSign(db *gorm.DB, id int, secret string, expireHours int) error {
+	logger.Info("[DB] UpdateOpenListServiceSign", zap.Int("id", id), zap.Int("expire_hours", expireHours))
+	fields := map[string]interface{}{"sign_expire_hours": expireHours}
+	if secret != "" {
+		fields["sign_secret"] = secret
+	}
+	if err := db.Model(&OpenListService{}).Where("id = ?", id).Updates(fields).Error; err != nil {
+		logger.Error("[DB] UpdateOpenListServiceSign error", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// UpdateOpenListServiceThrottle 更新遍历并发数、请求限速和任务并发数
+func UpdateOpenListServiceThrottle(db *gorm.DB, id int, concurrency int, rateLimit float64, taskConcurrency int) error {
+	logger.Info("[DB] UpdateOpenListServiceThrottle", zap.Int("id", id), zap.Int("concurrency", concurrency), zap.Float64("rate_limit", rateLimit), zap.Int("task_concurrency", taskConcurrency))
+	fields := map[string]interface{}{"walk_concurrency": concurrency, "request_rate_limit": rateLimit, "task_concurrency": taskConcurrency}
+	if err := db.Model(&OpenListService{}).Where("id = ?", id).Updates(fields).Error; err != nil {
+		logger.Error("[DB] UpdateOpenListServiceThrottle error", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// UpdateOpenListServiceToken 保存登录获取/刷新后的token
+func UpdateOpenListServiceToken(db *gorm.DB, id int, token string) error {
+	logger.Info("[DB] UpdateOpenListServiceToken", zap.Int("id", id))
+	if err := db.Model(&OpenListService{}).Where("id = ?", id).Updates(map[string]interface{}{"token": token, "updated_at": time.Now()}).Error; err != nil {
+		logger.Error("[DB] UpdateOpenListServiceToken error", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
 // DeleteOpenListService 删除OpenList服务
 func DeleteOpenListService(db *gorm.DB, id int) error {
 	logger.Info("[DB] DeleteOpenListService", zap.Int("id", id))
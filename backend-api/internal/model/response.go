@@ -58,11 +58,73 @@ type StrmConfigResponse struct {
 	UpdateMode       string    `json:"updateMode"`
 	ServiceID        int       `json:"serviceId"`
 	IsUseBackupUrl   IsUseBackupUrl      `json:"isUseBackupUrl"`
+	OrphanPolicy     string    `json:"orphanPolicy"`
+	OrphanThreshold  int       `json:"orphanThreshold"`
+	QuarantinePath   string    `json:"quarantinePath"`
+	UrlTemplate      string    `json:"urlTemplate"`
+	PlayMode         string    `json:"playMode"`
+	ProxyHeaders     string    `json:"proxyHeaders"`
+	ProxyMaxStreams  int       `json:"proxyMaxStreams"`
+	ProxyRateLimit   int       `json:"proxyRateLimit"`
+	IncludeExts      string    `json:"includeExts"`
+	ExcludeExts      string    `json:"excludeExts"`
+	MinFileSize      int64     `json:"minFileSize"`
+	MaxFileSize      int64     `json:"maxFileSize"`
+	ExcludePatterns  string    `json:"excludePatterns"`
+	RewriteRules     string    `json:"rewriteRules"`
+	FileMode         string    `json:"fileMode"`
+	DirMode          string    `json:"dirMode"`
+	FileOwner        string    `json:"fileOwner"`
 	CreatedAt        string    `json:"createdAt"`
 	UpdatedAt        string    `json:"updatedAt"`
 } 
 
 
+// StrmTaskResponse 任务接口返回结构，小驼峰格式，与 StrmConfigResponse 一致
+// swagger:model
+type StrmTaskResponse struct {
+	ID            int     `json:"id"`
+	Name          string  `json:"name"`
+	ScheduledTime string  `json:"scheduledTime"`
+	TaskMode      string  `json:"taskMode"`
+	Enabled       Enabled `json:"enabled"`
+	ServiceID     int     `json:"serviceId"`
+	ConfigID      int     `json:"configId"`
+	ConfigName    string  `json:"configName"`
+	AutoRepair    bool    `json:"autoRepair"`
+	ScheduleType  string  `json:"scheduleType"`
+	CronExpr      string  `json:"cronExpr"`
+	IntervalHours int     `json:"intervalHours"`
+	CatchUp       string  `json:"catchUp"`
+	NextRunAt     string  `json:"nextRunAt"` // 下一次执行时间，为空表示不再调度
+	LastRunAt     string  `json:"lastRunAt"`
+	CreatedAt     string  `json:"createdAt"`
+	UpdatedAt     string  `json:"updatedAt"`
+}
+
+// StrmQueueJobResponse 任务队列中的一次执行
+// swagger:model
+type StrmQueueJobResponse struct {
+	JobID      int64  `json:"jobId"`
+	TaskID     int    `json:"taskId"`
+	TaskName   string `json:"taskName"`
+	TaskMode   string `json:"taskMode"`
+	ConfigID   int    `json:"configId"`
+	ServiceID  int    `json:"serviceId"`
+	Priority   string `json:"priority"` // manual/scheduled
+	State      string `json:"state"`    // running/queued
+	Position   int    `json:"position"` // 排队位置，从 1 开始，执行中为 0
+	EnqueuedAt string `json:"enqueuedAt"`
+	StartedAt  string `json:"startedAt"`
+}
+
+// StrmProgressTicketResponse 任务进度订阅凭证
+// swagger:model
+type StrmProgressTicketResponse struct {
+	Ticket    string `json:"ticket"`
+	ExpiresAt string `json:"expiresAt"`
+}
+
 type Enabled string
 
 const (
@@ -80,6 +142,101 @@ type OpenListServiceResponse struct {
 	Token     string `json:"token"`
 	ServiceUrl string `json:"serviceUrl"`
 	BackupUrl  string `json:"backupUrl"`
+	HasPassword bool  `json:"hasPassword"` // 是否已保存登录密码（不返回密码本身）
+	HasSignSecret bool `json:"hasSignSecret"` // 是否已保存签名密钥
+	SignExpireHours int `json:"signExpireHours"`
+	WalkConcurrency int `json:"walkConcurrency"`
+	RequestRateLimit float64 `json:"requestRateLimit"`
+	TaskConcurrency int `json:"taskConcurrency"`
 	Enabled    Enabled   `json:"enabled"`
 	UpdatedAt  string `json:"updatedAt"`
 }
+
+// OpenListEndpointResult 单个地址的连通性诊断结果
+// swagger:model
+type OpenListEndpointResult struct {
+	URL           string `json:"url"`
+	Reachable     bool   `json:"reachable"`     // 地址是否可访问
+	Authenticated bool   `json:"authenticated"` // token 是否有效
+	Login         bool   `json:"login"`         // 是否通过账号密码登录获取token
+	LatencyMs     int64  `json:"latencyMs"`     // /api/me 请求耗时
+	Version       string `json:"version"`       // 服务端版本
+	Error         string `json:"error,omitempty"`
+}
+
+// OpenListUserResult OpenList 当前 token 对应的用户信息
+// swagger:model
+type OpenListUserResult struct {
+	Username    string   `json:"username"`
+	BasePath    string   `json:"basePath"`
+	Role        string   `json:"role"`
+	Disabled    bool     `json:"disabled"`
+	Permissions []string `json:"permissions"`
+}
+
+// OpenListTestResult OpenList 服务连接测试诊断结果
+// swagger:model
+type OpenListTestResult struct {
+	Success bool                    `json:"success"` // 主地址或备用地址至少一个可用且认证通过
+	Primary OpenListEndpointResult  `json:"primary"`
+	Backup  *OpenListEndpointResult `json:"backup,omitempty"` // 未配置备用地址时为空
+	User    *OpenListUserResult     `json:"user,omitempty"`
+}
+
+// OpenListHealthResponse OpenList 服务当前健康状态
+// swagger:model
+type OpenListHealthResponse struct {
+	ServiceID int    `json:"serviceId"`
+	Name      string `json:"name"`
+	Enabled   Enabled `json:"enabled"`
+	Status    string `json:"status"` // up/down/unknown
+	LatencyMs int64  `json:"latencyMs"`
+	Url       string `json:"url"` // 检查时实际使用的地址
+	Error     string `json:"error,omitempty"`
+	CheckedAt string `json:"checkedAt"`
+}
+
+// OpenListHealthRecordResponse 健康检查历史记录
+// swagger:model
+type OpenListHealthRecordResponse struct {
+	ID        int    `json:"id"`
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latencyMs"`
+	Url       string `json:"url"`
+	Error     string `json:"error,omitempty"`
+	CheckedAt string `json:"checkedAt"`
+}
+
+// OpenListBrowseItem 远程目录浏览项
+// swagger:model
+type OpenListBrowseItem struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	IsDir    bool   `json:"isDir"`
+	Size     int64  `json:"size"`
+	Modified string `json:"modified"`
+}
+
+// StrmUrlPreviewResponse 链接模板预览结果
+// swagger:model
+type StrmUrlPreviewResponse struct {
+	Template     string `json:"template"`
+	SamplePath   string `json:"samplePath"`
+	Url          string `json:"url"`
+	Illustrative bool   `json:"illustrative"` // 链接中的播放地址未绑定已保存的配置，仅作示意
+}
+
+// StrmUrlTemplateOptions 链接模板预设及可用占位符
+// swagger:model
+type StrmUrlTemplateOptions struct {
+	Presets      map[string]string `json:"presets"`
+	Placeholders map[string]string `json:"placeholders"`
+}
+
+// StrmRewritePreviewItem 路径改写预览项
+// swagger:model
+type StrmRewritePreviewItem struct {
+	RemotePath string `json:"remotePath"`
+	LocalPath  string `json:"localPath"` // 相对 strmOutputPath 的 .strm 路径
+	Error      string `json:"error,omitempty"`
+}
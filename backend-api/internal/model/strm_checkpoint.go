@@ -0,0 +1,51 @@
+package model
+
+import (
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// StrmTaskCheckpoint 暂停任务保存的生成进度，每个任务最多一条
+type StrmTaskCheckpoint struct {
+	TaskID          int       `json:"taskId" gorm:"primaryKey;autoIncrement:false"`
+	LogID           int       `json:"logId"`                 // 被暂停的运行日志，恢复后继续使用
+	ConfigID        int       `json:"configId"`              // 暂停时执行的配置
+	ConfigUpdatedAt time.Time `json:"configUpdatedAt"`       // 暂停时配置的修改时间，配置修改后进度作废
+	Data            string    `json:"data" gorm:"type:text"` // 进度 JSON
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+// SaveStrmTaskCheckpoint 保存任务进度，已有进度时覆盖
+func SaveStrmTaskCheckpoint(db *gorm.DB, cp *StrmTaskCheckpoint) error {
+	logger.Info("[DB] SaveStrmTaskCheckpoint", zap.Int("task_id", cp.TaskID), zap.Int("log_id", cp.LogID))
+	cp.CreatedAt = time.Now()
+	if err := db.Save(cp).Error; err != nil {
+		logger.Error("[DB] SaveStrmTaskCheckpoint error", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetStrmTaskCheckpoint 获取任务进度，没有时返回 nil
+func GetStrmTaskCheckpoint(db *gorm.DB, taskID int) (*StrmTaskCheckpoint, error) {
+	var cps []*StrmTaskCheckpoint
+	if err := db.Where("task_id = ?", taskID).Limit(1).Find(&cps).Error; err != nil {
+		return nil, err
+	}
+	if len(cps) == 0 {
+		return nil, nil
+	}
+	return cps[0], nil
+}
+
+func DeleteStrmTaskCheckpoint(db *gorm.DB, taskID int) error {
+	logger.Info("[DB] DeleteStrmTaskCheckpoint", zap.Int("task_id", taskID))
+	if err := db.Where("task_id = ?", taskID).Delete(&StrmTaskCheckpoint{}).Error; err != nil {
+		logger.Error("[DB] DeleteStrmTaskCheckpoint error", zap.Error(err))
+		return err
+	}
+	return nil
+}
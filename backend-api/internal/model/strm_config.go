@@ -15,6 +15,26 @@ const (
 	UpdateModeFull        UpdateMode = "full"
 )
 
+// OrphanPolicy 本地孤立文件（远程已不存在）的处理策略
+type OrphanPolicy string
+
+const (
+	OrphanPolicyDelete     OrphanPolicy = "delete"     // 直接删除
+	OrphanPolicyQuarantine OrphanPolicy = "quarantine" // 移动到隔离目录
+	OrphanPolicyReport     OrphanPolicy = "report"     // 仅报告
+)
+
+// PlayMode /play 链接的播放方式
+type PlayMode string
+
+const (
+	PlayModeRedirect PlayMode = "redirect" // 302 跳转到直链
+	PlayModeProxy    PlayMode = "proxy"    // 由本服务中转数据流
+)
+
+// DefaultOrphanThreshold 默认安全阈值：孤立文件占比超过该百分比时中止清理
+const DefaultOrphanThreshold = 50
+
 type StrmConfig struct {
 	ID               int        `gorm:"primaryKey;autoIncrement" json:"id"`
 	UserID           int        `json:"userId"`
@@ -22,10 +42,28 @@ type StrmConfig struct {
 	AlistBasePath    string     `json:"alistBasePath" gorm:"type:varchar(255)"`
 	StrmOutputPath   string     `json:"strmOutputPath" gorm:"type:varchar(255)"`
 	DownloadEnabled  bool       `json:"downloadEnabled"`
-	DownloadInterval int        `json:"downloadInterval"`
+	DownloadInterval int        `json:"downloadInterval"`                                  // 伴随文件下载间隔（秒），0 表示不等待
 	UpdateMode       UpdateMode `json:"updateMode" gorm:"type:varchar(32)"`
 	ServiceID        int        `json:"serviceId"`
 	IsUseBackupUrl   bool       `json:"isUseBackupUrl"`
+	OrphanPolicy     OrphanPolicy `json:"orphanPolicy" gorm:"type:varchar(32)"`
+	OrphanThreshold  int        `json:"orphanThreshold"`                                   // 百分比，0 表示使用默认值
+	QuarantinePath   string     `json:"quarantinePath" gorm:"type:varchar(255)"`           // 为空时使用 StrmOutputPath/.quarantine
+	UrlTemplate      string     `json:"urlTemplate" gorm:"type:varchar(512)"`              // .strm 链接模板，为空时使用 /d/ 直链
+	PlayMode         PlayMode   `json:"playMode" gorm:"type:varchar(32)"`                  // 为空时使用 redirect
+	ProxyHeaders     string     `json:"proxyHeaders" gorm:"type:text"`                     // 代理请求附加的请求头，JSON 对象，如 {"Referer":"..."}
+	ProxyMaxStreams  int        `json:"proxyMaxStreams"`                                   // 所属用户同时代理的最大流数，多个配置同时播放时取最小的非零值，0 表示不限
+	ProxyRateLimit   int        `json:"proxyRateLimit"`                                    // 所属用户代理的总带宽（KB/s），多个配置同时播放时取最小的非零值，0 表示不限
+	IncludeExts      string     `json:"includeExts" gorm:"type:varchar(512)"`              // 视频扩展名，逗号分隔，为空时使用字典 video_ext
+	ExcludeExts      string     `json:"excludeExts" gorm:"type:varchar(512)"`              // 排除的扩展名，逗号分隔
+	MinFileSize      int64      `json:"minFileSize"`                                       // 视频最小大小（字节），0 表示不限
+	MaxFileSize      int64      `json:"maxFileSize"`                                       // 视频最大大小（字节），0 表示不限
+	ExcludePatterns  string     `json:"excludePatterns" gorm:"type:text"`                  // 排除规则，每行一条，glob（如 **/Extras/**）或 re: 开头的正则
+	RewriteRules     string     `json:"rewriteRules" gorm:"type:text"`                     // 路径改写规则，JSON 数组，按顺序应用
+	FileMode         string     `json:"fileMode" gorm:"type:varchar(8)"`                   // 输出文件权限（八进制），为空时使用 0644
+	DirMode          string     `json:"dirMode" gorm:"type:varchar(8)"`                    // 新建目录权限（八进制），为空时使用 0755
+	FileOwner        string     `json:"fileOwner" gorm:"type:varchar(32)"`                 // 输出文件属主 uid:gid，为空时不修改
+	PlaySecret       string     `json:"-" gorm:"type:varchar(64)"`                         // 播放链接密钥，轮换后已生成的 /play 链接失效
 	CreatedAt        time.Time  `json:"createdAt"`
 	UpdatedAt        time.Time  `json:"updatedAt"`
 }
@@ -88,16 +126,39 @@ func GetAllStrmConfigs(db *gorm.DB, userID, page, pageSize int) ([]*StrmConfig,
 	return configs, total, nil
 }
 
+// ListAllStrmConfigs 获取所有用户的全部配置
+func ListAllStrmConfigs(db *gorm.DB) ([]*StrmConfig, error) {
+	var configs []*StrmConfig
+	if err := db.Order("id ASC").Find(&configs).Error; err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
+
 func UpdateStrmConfig(db *gorm.DB, config *StrmConfig) error {
 	logger.Info("[DB] UpdateStrmConfig", zap.Int("id", config.ID))
 	config.UpdatedAt = time.Now()
-	if err := db.Model(&StrmConfig{}).Where("id = ?", config.ID).Updates(config).Error; err != nil {
+	// Select("*") 使 false/0/空字符串 等零值也能被更新
+	if err := db.Model(&StrmConfig{}).Where("id = ?", config.ID).Select("*").Omit("id", "created_at").Updates(config).Error; err != nil {
 		logger.Error("[DB] UpdateStrmConfig error", zap.Error(err))
 		return err
 	}
 	return nil
 }
 
+// UpdateStrmConfigPlaySecret 更新配置的播放链接密钥
+func UpdateStrmConfigPlaySecret(db *gorm.DB, id int, secret string) error {
+	logger.Info("[DB] UpdateStrmConfigPlaySecret", zap.Int("id", id))
+	if err := db.Model(&StrmConfig{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"play_secret": secret,
+		"updated_at":  time.Now(),
+	}).Error; err != nil {
+		logger.Error("[DB] UpdateStrmConfigPlaySecret error", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
 func DeleteStrmConfig(db *gorm.DB, id int) error {
 	logger.Info("[DB] DeleteStrmConfig", zap.Int("id", id))
 	if err := db.Delete(&StrmConfig{}, id).Error; err != nil {
@@ -0,0 +1,116 @@
+package model
+
+import (
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// StrmManifestEntry 上次成功扫描的远程文件清单，用于增量更新比对
+type StrmManifestEntry struct {
+	ID        int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	ConfigID  int       `json:"configId" gorm:"index"`
+	Path      string    `json:"path" gorm:"type:varchar(1024)"` // 相对 alistBasePath 的远程路径
+	Size      int64     `json:"size"`
+	Modified  time.Time `json:"modified"`
+	Hash      string    `json:"hash" gorm:"type:varchar(128)"`
+	StrmPath  string    `json:"strmPath" gorm:"type:varchar(1024)"` // 生成的本地 .strm 路径
+	Url       string    `json:"url" gorm:"type:varchar(2048)"`      // 写入 .strm 的链接
+	Sign      string    `json:"sign" gorm:"type:varchar(512)"`      // 链接使用的签名，用于判断是否即将过期
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// GetStrmManifest 获取配置的全部清单记录
+func GetStrmManifest(db *gorm.DB, configID int) ([]*StrmManifestEntry, error) {
+	var entries []*StrmManifestEntry
+	if err := db.Where("config_id = ?", configID).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// manifestBatchSize 批量写入和删除清单的每批条数
+const manifestBatchSize = 500
+
+// ReplaceStrmManifest 用本次扫描结果替换配置的清单：只新增新文件、更新有变化的记录、删除已不存在的记录，
+// 增量运行时绝大多数记录不变，不产生写入
+func ReplaceStrmManifest(db *gorm.DB, configID int, entries []*StrmManifestEntry) error {
+	logger.Info("[DB] ReplaceStrmManifest", zap.Int("config_id", configID), zap.Int("count", len(entries)))
+	now := time.Now()
+	var created, updated, deleted int
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var existing []*StrmManifestEntry
+		if err := tx.Where("config_id = ?", configID).Find(&existing).Error; err != nil {
+			return err
+		}
+		byPath := make(map[string]*StrmManifestEntry, len(existing))
+		for _, e := range existing {
+			byPath[e.Path] = e
+		}
+		var toCreate []*StrmManifestEntry
+		for _, e := range entries {
+			e.ConfigID = configID
+			old, ok := byPath[e.Path]
+			if !ok {
+				e.ID = 0
+				e.UpdatedAt = now
+				toCreate = append(toCreate, e)
+				continue
+			}
+			delete(byPath, e.Path)
+			e.ID = old.ID
+			if sameManifestEntry(old, e) {
+				e.UpdatedAt = old.UpdatedAt
+				continue
+			}
+			e.UpdatedAt = now
+			if err := tx.Model(&StrmManifestEntry{}).Where("id = ?", old.ID).
+				Select("size", "modified", "hash", "strm_path", "url", "sign", "updated_at").Updates(e).Error; err != nil {
+				return err
+			}
+			updated++
+		}
+		if len(toCreate) > 0 {
+			if err := tx.CreateInBatches(toCreate, manifestBatchSize).Error; err != nil {
+				return err
+			}
+			created = len(toCreate)
+		}
+		ids := make([]int, 0, len(byPath))
+		for _, e := range byPath {
+			ids = append(ids, e.ID)
+		}
+		for i := 0; i < len(ids); i += manifestBatchSize {
+			batch := ids[i:min(i+manifestBatchSize, len(ids))]
+			if err := tx.Where("id IN ?", batch).Delete(&StrmManifestEntry{}).Error; err != nil {
+				return err
+			}
+		}
+		deleted = len(ids)
+		return nil
+	})
+	if err != nil {
+		logger.Error("[DB] ReplaceStrmManifest error", zap.Error(err))
+		return err
+	}
+	logger.Info("[DB] ReplaceStrmManifest done", zap.Int("config_id", configID), zap.Int("created", created), zap.Int("updated", updated), zap.Int("deleted", deleted))
+	return nil
+}
+
+// sameManifestEntry 两条记录的内容是否一致
+func sameManifestEntry(a, b *StrmManifestEntry) bool {
+	return a.Size == b.Size && a.Modified.Equal(b.Modified) && a.Hash == b.Hash &&
+		a.StrmPath == b.StrmPath && a.Url == b.Url && a.Sign == b.Sign
+}
+
+// DeleteStrmManifest 删除配置的清单
+func DeleteStrmManifest(db *gorm.DB, configID int) error {
+	logger.Info("[DB] DeleteStrmManifest", zap.Int("config_id", configID))
+	if err := db.Where("config_id = ?", configID).Delete(&StrmManifestEntry{}).Error; err != nil {
+		logger.Error("[DB] DeleteStrmManifest error", zap.Error(err))
+		return err
+	}
+	return nil
+}
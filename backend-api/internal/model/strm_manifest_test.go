@@ -0,0 +1,65 @@
+package model
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestReplaceStrmManifestWritesOnlyChanges(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := MigrateIfNotExists(db); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entry := func(path, url string) *StrmManifestEntry {
+		return &StrmManifestEntry{Path: path, Size: 1, Modified: modified, StrmPath: "/out/" + path, Url: url}
+	}
+	if err := ReplaceStrmManifest(db, 1, []*StrmManifestEntry{entry("a.mkv", "u1"), entry("b.mkv", "u1"), entry("c.mkv", "u1")}); err != nil {
+		t.Fatal(err)
+	}
+	// 其他配置的清单不受影响
+	if err := ReplaceStrmManifest(db, 2, []*StrmManifestEntry{entry("a.mkv", "u1")}); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := GetStrmManifest(db, 1)
+	ids := make(map[string]int)
+	updatedAt := make(map[string]time.Time)
+	for _, e := range before {
+		ids[e.Path] = e.ID
+		updatedAt[e.Path] = e.UpdatedAt
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	// a 不变，b 链接变化，c 删除，d 新增
+	if err := ReplaceStrmManifest(db, 1, []*StrmManifestEntry{entry("a.mkv", "u1"), entry("b.mkv", "u2"), entry("d.mkv", "u1")}); err != nil {
+		t.Fatal(err)
+	}
+	after, err := GetStrmManifest(db, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]*StrmManifestEntry)
+	for _, e := range after {
+		got[e.Path] = e
+	}
+	if len(got) != 3 || got["c.mkv"] != nil || got["d.mkv"] == nil {
+		t.Fatalf("manifest = %v", got)
+	}
+	if got["a.mkv"].ID != ids["a.mkv"] || !got["a.mkv"].UpdatedAt.Equal(updatedAt["a.mkv"]) {
+		t.Error("未变化的记录不应改写")
+	}
+	if got["b.mkv"].ID != ids["b.mkv"] || got["b.mkv"].Url != "u2" || !got["b.mkv"].UpdatedAt.After(updatedAt["b.mkv"]) {
+		t.Errorf("b.mkv = %+v, 应原地更新", got["b.mkv"])
+	}
+	if other, _ := GetStrmManifest(db, 2); len(other) != 1 {
+		t.Errorf("config 2 manifest has %d entries, want 1", len(other))
+	}
+}
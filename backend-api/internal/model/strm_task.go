@@ -15,17 +15,41 @@ const (
 	TaskModeCheck  TaskMode = "check"
 )
 
+// ScheduleType 任务调度方式
+type ScheduleType string
+
+const (
+	ScheduleTypeCron     ScheduleType = "cron"     // cron 表达式
+	ScheduleTypeInterval ScheduleType = "interval" // 每隔 N 小时，以 ScheduledTime 为起点
+	ScheduleTypeOnce     ScheduleType = "once"     // 在 ScheduledTime 执行一次
+)
+
+// CatchUpPolicy 停机期间错过的执行如何处理
+type CatchUpPolicy string
+
+const (
+	CatchUpSkip CatchUpPolicy = "skip" // 跳过，等待下一次
+	CatchUpOnce CatchUpPolicy = "once" // 启动后立即补执行一次
+)
+
 type StrmTask struct {
-	ID            int       `gorm:"primaryKey;autoIncrement" json:"id"`
-	UserID        int       `json:"userId"`
-	Name          string    `json:"name" gorm:"type:varchar(128);index"`
-	ScheduledTime time.Time `json:"scheduledTime"`
-	TaskMode      TaskMode  `json:"taskMode" gorm:"type:varchar(32)"`
-	Enabled       bool      `json:"enabled"`
-	ServiceID     int       `json:"serviceId"`
-	ConfigID      int       `json:"configId"`
-	CreatedAt     time.Time `json:"createdAt"`
-	UpdatedAt     time.Time `json:"updatedAt"`
+	ID            int           `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID        int           `json:"userId"`
+	Name          string        `json:"name" gorm:"type:varchar(128);index"`
+	ScheduledTime time.Time     `json:"scheduledTime"`
+	TaskMode      TaskMode      `json:"taskMode" gorm:"type:varchar(32)"`
+	Enabled       bool          `json:"enabled"`
+	ServiceID     int           `json:"serviceId"`
+	ConfigID      int           `json:"configId"`
+	AutoRepair    bool          `json:"autoRepair"`                           // check 模式发现问题时自动修复
+	ScheduleType  ScheduleType  `json:"scheduleType" gorm:"type:varchar(16)"` // 为空时按 once 处理，兼容旧数据
+	CronExpr      string        `json:"cronExpr" gorm:"type:varchar(64)"`
+	IntervalHours int           `json:"intervalHours"`
+	CatchUp       CatchUpPolicy `json:"catchUp" gorm:"type:varchar(16)"` // 为空时使用环境变量 STRM_TASK_CATCHUP 的默认策略
+	NextRunAt     *time.Time    `json:"nextRunAt"`
+	LastRunAt     *time.Time    `json:"lastRunAt"`
+	CreatedAt     time.Time     `json:"createdAt"`
+	UpdatedAt     time.Time     `json:"updatedAt"`
 }
 
 func CreateStrmTask(db *gorm.DB, task *StrmTask) error {
@@ -48,6 +72,52 @@ func GetStrmTaskByID(db *gorm.DB, id int) (*StrmTask, error) {
 	return &task, nil
 }
 
+// StrmTaskQuery 任务列表筛选条件，零值表示不筛选
+type StrmTaskQuery struct {
+	UserID    int
+	ServiceID int
+	ConfigID  int
+	TaskMode  TaskMode
+	Enabled   *bool
+	Name      string // 名称模糊匹配
+}
+
+// ListStrmTasks 按条件分页获取任务，返回数据和总数
+func ListStrmTasks(db *gorm.DB, query *StrmTaskQuery, page, pageSize int) ([]*StrmTask, int64, error) {
+	var tasks []*StrmTask
+	var total int64
+	db = db.Model(&StrmTask{}).Where("user_id = ?", query.UserID)
+	if query.ServiceID > 0 {
+		db = db.Where("service_id = ?", query.ServiceID)
+	}
+	if query.ConfigID > 0 {
+		db = db.Where("config_id = ?", query.ConfigID)
+	}
+	if query.TaskMode != "" {
+		db = db.Where("task_mode = ?", query.TaskMode)
+	}
+	if query.Enabled != nil {
+		db = db.Where("enabled = ?", *query.Enabled)
+	}
+	if query.Name != "" {
+		db = db.Where("name LIKE ?", "%"+query.Name+"%")
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	}
+	offset := (page - 1) * pageSize
+	if err := db.Order("created_at DESC").Limit(pageSize).Offset(offset).Find(&tasks).Error; err != nil {
+		return nil, 0, err
+	}
+	return tasks, total, nil
+}
+
 func GetStrmTasksByServiceID(db *gorm.DB, serviceID int) ([]*StrmTask, error) {
 	var tasks []*StrmTask
 	if err := db.Where("service_id = ?", serviceID).Order("scheduled_time ASC").Find(&tasks).Error; err != nil {
@@ -75,7 +145,11 @@ func GetEnabledStrmTasks(db *gorm.DB) ([]*StrmTask, error) {
 func UpdateStrmTask(db *gorm.DB, task *StrmTask) error {
 	logger.Info("[DB] UpdateStrmTask", zap.Int("id", task.ID))
 	task.UpdatedAt = time.Now()
-	if err := db.Model(&StrmTask{}).Where("id = ?", task.ID).Updates(task).Error; err != nil {
+	// 显式指定列，保证 enabled/auto_repair 等字段可以更新为零值
+	if err := db.Model(&StrmTask{}).Where("id = ?", task.ID).
+		Select("name", "scheduled_time", "task_mode", "enabled", "service_id", "config_id", "auto_repair",
+			"schedule_type", "cron_expr", "interval_hours", "catch_up", "next_run_at", "updated_at").
+		Updates(task).Error; err != nil {
 		logger.Error("[DB] UpdateStrmTask error", zap.Error(err))
 		return err
 	}
@@ -107,4 +181,23 @@ func UpdateStrmTaskScheduledTime(db *gorm.DB, id int, scheduledTime time.Time) e
 		return err
 	}
 	return nil
-} 
+}
+
+// UpdateStrmTaskNextRun 更新下一次执行时间，nextRunAt 为 nil 表示不再调度
+func UpdateStrmTaskNextRun(db *gorm.DB, id int, nextRunAt *time.Time) error {
+	if err := db.Model(&StrmTask{}).Where("id = ?", id).Update("next_run_at", nextRunAt).Error; err != nil {
+		logger.Error("[DB] UpdateStrmTaskNextRun error", zap.Int("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// UpdateStrmTaskRunTimes 调度触发后记录本次执行时间和下一次执行时间
+func UpdateStrmTaskRunTimes(db *gorm.DB, id int, lastRunAt time.Time, nextRunAt *time.Time) error {
+	logger.Info("[DB] UpdateStrmTaskRunTimes", zap.Int("id", id), zap.Time("last_run_at", lastRunAt))
+	if err := db.Model(&StrmTask{}).Where("id = ?", id).Updates(map[string]interface{}{"last_run_at": lastRunAt, "next_run_at": nextRunAt}).Error; err != nil {
+		logger.Error("[DB] UpdateStrmTaskRunTimes error", zap.Error(err))
+		return err
+	}
+	return nil
+}
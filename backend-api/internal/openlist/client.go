@@ -0,0 +1,473 @@
+package openlist
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/util"
+	"go.uber.org/zap"
+)
+
+const defaultTimeout = 30 * time.Second
+
+// Client OpenList/AList API 客户端，基于 OpenListService 记录构建
+// 配置了 BackupUrl 时，主地址连接失败/超时/5xx 会自动切换到备用地址重试
+type Client struct {
+	primaryURL string
+	backupURL  string
+	token      string
+	httpClient *http.Client
+	rawClient  *http.Client // 下载文件用，不设置整体超时，由 ctx 控制
+	failover   bool
+	cooldown   time.Duration
+
+	// limiter 按服务共享的请求限速器，服务未配置限速时不等待
+	limiter *util.RateLimiter
+
+	// 账号密码登录：token 为空或失效(401)时自动登录并回调保存新 token
+	account        string
+	password       string
+	onTokenRefresh func(token string)
+	loginMutex     sync.Mutex
+
+	mutex      sync.Mutex
+	usedURL    string
+	failedOver bool
+}
+
+// Option 客户端可选配置
+type Option func(*Client)
+
+// WithHTTPClient 使用自定义 http.Client
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithTimeout 设置单次请求超时时间
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.httpClient = &http.Client{Timeout: timeout}
+	}
+}
+
+// WithFailover 是否允许切换到备用地址，默认在配置了 BackupUrl 时开启
+func WithFailover(enabled bool) Option {
+	return func(c *Client) {
+		c.failover = enabled
+	}
+}
+
+// WithCooldown 设置主地址故障后的熔断冷却时间
+func WithCooldown(cooldown time.Duration) Option {
+	return func(c *Client) {
+		c.cooldown = cooldown
+	}
+}
+
+// WithCredentials 设置登录账号和明文密码，用于自动获取/刷新 token
+func WithCredentials(account, password string) Option {
+	return func(c *Client) {
+		c.account = account
+		c.password = password
+	}
+}
+
+// WithTokenRefresh 设置 token 刷新后的回调，用于持久化新 token
+func WithTokenRefresh(fn func(token string)) Option {
+	return func(c *Client) {
+		c.onTokenRefresh = fn
+	}
+}
+
+// NewClient 根据 OpenListService 创建客户端
+func NewClient(service *model.OpenListService, opts ...Option) *Client {
+	c := &Client{
+		primaryURL: strings.TrimRight(service.ServiceUrl, "/"),
+		backupURL:  strings.TrimRight(service.BackupUrl, "/"),
+		token:      service.Token,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		rawClient:  &http.Client{},
+		failover:   service.BackupUrl != "",
+		cooldown:   DefaultCooldown,
+	}
+	// 未保存的服务（如连接测试）没有ID，不参与共享限速
+	if service.ID > 0 {
+		c.limiter = limiters.get(service.ID, service.RequestRateLimit)
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// BaseURL 返回最近一次请求使用的服务地址，尚未请求时返回主地址
+func (c *Client) BaseURL() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.usedURL == "" {
+		return c.primaryURL
+	}
+	return c.usedURL
+}
+
+// UsedURL 返回最近一次成功连通的服务地址
+func (c *Client) UsedURL() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.usedURL
+}
+
+// FailedOver 本客户端是否发生过主备切换
+func (c *Client) FailedOver() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.failedOver
+}
+
+// candidates 按健康状态排序候选地址，主地址熔断期内优先使用备用地址
+func (c *Client) candidates() []string {
+	if !c.failover || c.backupURL == "" || c.backupURL == c.primaryURL {
+		return []string{c.primaryURL}
+	}
+	if !endpoints.available(c.primaryURL) && endpoints.available(c.backupURL) {
+		return []string{c.backupURL, c.primaryURL}
+	}
+	return []string{c.primaryURL, c.backupURL}
+}
+
+func (c *Client) setUsed(base string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.usedURL != base {
+		if base != c.primaryURL {
+			c.failedOver = true
+			logger.Warn("[OpenList] 已切换到备用地址", zap.String("primary", c.primaryURL), zap.String("backup", base))
+		} else if c.usedURL != "" {
+			logger.Info("[OpenList] 已恢复使用主地址", zap.String("primary", c.primaryURL))
+		}
+	}
+	c.usedURL = base
+}
+
+// Token 返回当前使用的 token
+func (c *Client) Token() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.token
+}
+
+func (c *Client) canLogin() bool {
+	return c.account != "" && c.password != ""
+}
+
+// Login 使用账号密码登录，成功后客户端改用新 token
+func (c *Client) Login(ctx context.Context) (string, error) {
+	var resp LoginResp
+	req := &LoginReq{Username: c.account, Password: c.password}
+	if err := c.request(ctx, http.MethodPost, "/api/auth/login", req, &resp, false); err != nil {
+		logger.Error("[OpenList] 登录失败", zap.String("account", c.account), zap.Error(err))
+		return "", err
+	}
+	c.mutex.Lock()
+	c.token = resp.Token
+	c.mutex.Unlock()
+	logger.Info("[OpenList] 登录成功，已获取新token", zap.String("account", c.account))
+	if c.onTokenRefresh != nil {
+		c.onTokenRefresh(resp.Token)
+	}
+	return resp.Token, nil
+}
+
+// refreshToken 刷新 token，并发请求同时 401 时只登录一次
+func (c *Client) refreshToken(ctx context.Context, stale string) error {
+	c.loginMutex.Lock()
+	defer c.loginMutex.Unlock()
+	if current := c.Token(); current != "" && current != stale {
+		return nil
+	}
+	_, err := c.Login(ctx)
+	return err
+}
+
+// List 列出目录内容
+func (c *Client) List(ctx context.Context, req *ListReq) (*ListResp, error) {
+	var resp ListResp
+	if err := c.do(ctx, http.MethodPost, "/api/fs/list", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Get 获取文件/目录详情
+func (c *Client) Get(ctx context.Context, req *GetReq) (*GetResp, error) {
+	var resp GetResp
+	if err := c.do(ctx, http.MethodPost, "/api/fs/get", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Dirs 仅列出子目录
+func (c *Client) Dirs(ctx context.Context, req *DirsReq) ([]DirItem, error) {
+	var resp []DirItem
+	if err := c.do(ctx, http.MethodPost, "/api/fs/dirs", req, &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Me 获取当前 token 对应的用户信息
+func (c *Client) Me(ctx context.Context) (*MeResp, error) {
+	var resp MeResp
+	if err := c.do(ctx, http.MethodGet, "/api/me", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Open 通过 /d/ 链接下载文件，offset > 0 时使用 Range 断点续传，调用方负责关闭 Body。
+// 与 API 请求一样按候选地址故障切换、限流退避，并在 401 时重新登录后重试
+func (c *Client) Open(ctx context.Context, remotePath, sign string, offset int64) (*http.Response, error) {
+	var res *http.Response
+	err := c.withLogin(ctx, "/d"+remotePath, func() error {
+		return c.retryThrottled(ctx, "/d"+remotePath, func() error {
+			var err error
+			res, err = c.openCandidates(ctx, remotePath, sign, offset)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// openCandidates 按候选地址依次尝试打开下载链接
+func (c *Client) openCandidates(ctx context.Context, remotePath, sign string, offset int64) (*http.Response, error) {
+	var err error
+	candidates := c.candidates()
+	for i, base := range candidates {
+		if err = c.wait(ctx); err != nil {
+			return nil, err
+		}
+		var res *http.Response
+		res, err = c.openOnce(ctx, base, remotePath, sign, offset)
+		if !isFailoverError(ctx, err) {
+			if ctx.Err() == nil {
+				endpoints.markSuccess(base)
+				c.setUsed(base)
+			}
+			return res, err
+		}
+		endpoints.markFailure(base, err, c.cooldown)
+		if i < len(candidates)-1 {
+			logger.Warn("[OpenList] 地址不可用，尝试下一个地址", zap.String("failed", base), zap.String("next", candidates[i+1]), zap.Error(err))
+		}
+	}
+	return nil, err
+}
+
+// openOnce 向指定地址发送一次下载请求，非 200/206 时关闭响应并返回 HTTPError
+func (c *Client) openOnce(ctx context.Context, base, remotePath, sign string, offset int64) (*http.Response, error) {
+	u := base + "/d" + EncodePath(remotePath)
+	if sign != "" {
+		u += "?sign=" + neturl.QueryEscape(sign)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token := c.Token(); token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	res, err := c.rawClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusPartialContent {
+		io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+		return nil, &HTTPError{StatusCode: res.StatusCode, URL: u}
+	}
+	return res, nil
+}
+
+// EncodePath 对远程路径逐段进行 URL 编码，保留分隔符
+func EncodePath(remotePath string) string {
+	segments := strings.Split(remotePath, "/")
+	for i, s := range segments {
+		segments[i] = neturl.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// PublicSettings 获取站点公开设置（无需登录），其中 version 为服务端版本
+func (c *Client) PublicSettings(ctx context.Context) (map[string]interface{}, error) {
+	var resp map[string]interface{}
+	if err := c.request(ctx, http.MethodGet, "/api/public/settings", nil, &resp, false); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// do 发送需认证的请求，配置了账号密码时在 token 缺失或 401 时自动登录并重试一次
+func (c *Client) do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	return c.withLogin(ctx, path, func() error {
+		return c.request(ctx, method, path, body, out, true)
+	})
+}
+
+// withLogin 执行需认证的请求 fn，配置了账号密码时在 token 缺失或 401 时自动登录并重试一次
+func (c *Client) withLogin(ctx context.Context, path string, fn func() error) error {
+	if !c.canLogin() {
+		return fn()
+	}
+	token := c.Token()
+	if token == "" {
+		if err := c.refreshToken(ctx, ""); err != nil {
+			return err
+		}
+		token = c.Token()
+	}
+	err := fn()
+	if err == nil || !IsUnauthorized(err) {
+		return err
+	}
+	logger.Info("[OpenList] token已失效，重新登录", zap.String("path", path))
+	if err := c.refreshToken(ctx, token); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// request 发送请求并解析 OpenList 统一响应，遇到限流时指数退避后重试
+func (c *Client) request(ctx context.Context, method, path string, body interface{}, out interface{}, withAuth bool) error {
+	return c.retryThrottled(ctx, path, func() error {
+		return c.requestCandidates(ctx, method, path, body, out, withAuth)
+	})
+}
+
+// retryThrottled 执行 fn，遇到限流时指数退避后重试
+func (c *Client) retryThrottled(ctx context.Context, path string, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if !isThrottled(err) || attempt >= maxThrottleRetries {
+			return err
+		}
+		wait := throttleBackoff(attempt)
+		logger.Warn("[OpenList] 请求过于频繁，退避后重试", zap.String("path", path), zap.Int("attempt", attempt+1), zap.Duration("wait", wait))
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// requestCandidates 按候选地址依次尝试发送请求
+func (c *Client) requestCandidates(ctx context.Context, method, path string, body interface{}, out interface{}, withAuth bool) error {
+	var payload []byte
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = buf
+	}
+	var err error
+	candidates := c.candidates()
+	for i, base := range candidates {
+		token := ""
+		if withAuth {
+			token = c.Token()
+		}
+		if err = c.wait(ctx); err != nil {
+			return err
+		}
+		err = c.doOnce(ctx, base, token, method, path, payload, out)
+		if !isFailoverError(ctx, err) {
+			if ctx.Err() == nil {
+				endpoints.markSuccess(base)
+				c.setUsed(base)
+			}
+			return err
+		}
+		endpoints.markFailure(base, err, c.cooldown)
+		if i < len(candidates)-1 {
+			logger.Warn("[OpenList] 地址不可用，尝试下一个地址", zap.String("failed", base), zap.String("next", candidates[i+1]), zap.Error(err))
+		}
+	}
+	return err
+}
+
+// doOnce 向指定地址发送一次请求
+func (c *Client) doOnce(ctx context.Context, base, token, method, path string, payload []byte, out interface{}) error {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	url := base + path
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		// OpenList 直接使用 token 作为 Authorization，不带 Bearer 前缀
+		req.Header.Set("Authorization", token)
+	}
+	start := time.Now()
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		logger.Error("[OpenList] 请求失败", zap.String("url", url), zap.Error(err))
+		return err
+	}
+	defer res.Body.Close()
+	logger.Debug("[OpenList] 请求完成", zap.String("url", url), zap.Int("status", res.StatusCode), zap.Duration("cost", time.Since(start)))
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		io.Copy(io.Discard, res.Body)
+		return &HTTPError{StatusCode: res.StatusCode, URL: url}
+	}
+	var apiResp apiResponse[json.RawMessage]
+	if err := json.NewDecoder(res.Body).Decode(&apiResp); err != nil {
+		return fmt.Errorf("openlist %s: 解析响应失败: %w", path, err)
+	}
+	if apiResp.Code != CodeSuccess {
+		return &APIError{Code: apiResp.Code, Message: apiResp.Message, Path: path}
+	}
+	if out == nil || len(apiResp.Data) == 0 || string(apiResp.Data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(apiResp.Data, out); err != nil {
+		return fmt.Errorf("openlist %s: 解析data失败: %w", path, err)
+	}
+	return nil
+}
+
+// IsUnauthorized 判断是否为认证失败
+func IsUnauthorized(err error) bool {
+	return errors.Is(err, ErrUnauthorized)
+}
+
+// IsNotFound 判断是否为对象不存在
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
@@ -0,0 +1,120 @@
+package openlist
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+)
+
+// fakeServer 模拟 /d/ 下载和登录接口，token 不等于 valid 时 /d/ 返回 401
+type fakeServer struct {
+	*httptest.Server
+	mutex     sync.Mutex
+	valid     string
+	downloads int
+	logins    int
+	status    int // 非 0 时 /d/ 直接返回该状态码
+}
+
+func newFakeServer(t *testing.T, valid string) *fakeServer {
+	t.Helper()
+	f := &fakeServer{valid: valid}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+		switch {
+		case r.URL.Path == "/api/auth/login":
+			f.logins++
+			json.NewEncoder(w).Encode(map[string]interface{}{"code": 200, "data": map[string]string{"token": f.valid}})
+		case len(r.URL.Path) > 3 && r.URL.Path[:3] == "/d/":
+			f.downloads++
+			if f.status != 0 {
+				w.WriteHeader(f.status)
+				return
+			}
+			if r.Header.Get("Authorization") != f.valid {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.Header.Get("Range") != "" {
+				w.WriteHeader(http.StatusPartialContent)
+			}
+			w.Write([]byte(r.URL.EscapedPath() + "?" + r.URL.RawQuery))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func readBody(t *testing.T, res *http.Response) string {
+	t.Helper()
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestOpenFailsOverToBackup(t *testing.T) {
+	primary := newFakeServer(t, "tok")
+	primary.status = http.StatusBadGateway
+	backup := newFakeServer(t, "tok")
+	svc := &model.OpenListService{ServiceUrl: primary.URL, BackupUrl: backup.URL, Token: "tok"}
+	c := NewClient(svc, WithCooldown(0))
+
+	res, err := c.Open(context.Background(), "/电影/a b.mkv", "s:0", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := readBody(t, res); body != "/d/%E7%94%B5%E5%BD%B1/a%20b.mkv?sign=s%3A0" {
+		t.Errorf("body = %q", body)
+	}
+	if primary.downloads != 1 || backup.downloads != 1 {
+		t.Errorf("primary = %d, backup = %d", primary.downloads, backup.downloads)
+	}
+	if c.UsedURL() != backup.URL || !c.FailedOver() {
+		t.Errorf("usedURL = %s, failedOver = %v", c.UsedURL(), c.FailedOver())
+	}
+}
+
+func TestOpenReloginOnUnauthorized(t *testing.T) {
+	server := newFakeServer(t, "fresh")
+	svc := &model.OpenListService{ServiceUrl: server.URL, Token: "stale"}
+	var saved string
+	c := NewClient(svc, WithCredentials("admin", "pass"), WithTokenRefresh(func(token string) { saved = token }))
+
+	res, err := c.Open(context.Background(), "/a.mkv", "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusPartialContent {
+		t.Errorf("status = %d", res.StatusCode)
+	}
+	readBody(t, res)
+	if server.logins != 1 || saved != "fresh" || c.Token() != "fresh" {
+		t.Errorf("logins = %d, saved = %q, token = %q", server.logins, saved, c.Token())
+	}
+	if server.downloads != 2 {
+		t.Errorf("downloads = %d", server.downloads)
+	}
+}
+
+func TestOpenUnauthorizedWithoutCredentials(t *testing.T) {
+	server := newFakeServer(t, "fresh")
+	c := NewClient(&model.OpenListService{ServiceUrl: server.URL, Token: "stale"})
+	if _, err := c.Open(context.Background(), "/a.mkv", "", 0); !IsUnauthorized(err) {
+		t.Fatalf("err = %v", err)
+	}
+	if server.logins != 0 {
+		t.Errorf("未配置账号密码时不应登录")
+	}
+}
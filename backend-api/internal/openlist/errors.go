@@ -0,0 +1,102 @@
+package openlist
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// OpenList 业务错误码
+const (
+	CodeSuccess         = 200
+	CodeBadRequest      = 400
+	CodeUnauthorized    = 401
+	CodeForbidden       = 403
+	CodeNotFound        = 404
+	CodeTooManyRequests = 429
+	CodeInternalError   = 500
+)
+
+var (
+	ErrUnauthorized    = errors.New("openlist: 未授权或token已失效")
+	ErrForbidden       = errors.New("openlist: 无权限访问")
+	ErrNotFound        = errors.New("openlist: 对象不存在")
+	ErrTooManyRequests = errors.New("openlist: 请求过于频繁")
+)
+
+// throttleKeywords 后端网盘限流时的错误信息片段（已转小写），OpenList 对这类错误多以 code=500 原样返回，
+// 如 115 的「操作过于频繁」、阿里云盘的 TooManyRequests / request throttling
+var throttleKeywords = []string{
+	"too many requests",
+	"toomanyrequests",
+	"throttl",
+	"rate limit",
+	"ratelimit",
+	"flow limit",
+	"频繁",
+	"频率过快",
+	"频率过高",
+	"请求过多",
+	"次数过多",
+	"限流",
+}
+
+// isThrottleMessage 错误信息是否表示被限流
+func isThrottleMessage(msg string) bool {
+	msg = strings.ToLower(msg)
+	for _, k := range throttleKeywords {
+		if strings.Contains(msg, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// APIError OpenList 返回的非 200 业务错误
+type APIError struct {
+	Code    int
+	Message string
+	Path    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("openlist %s: code=%d message=%s", e.Path, e.Code, e.Message)
+}
+
+// Is 将业务错误码映射到哨兵错误，便于 errors.Is 判断
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.Code == CodeUnauthorized
+	case ErrForbidden:
+		return e.Code == CodeForbidden
+	case ErrNotFound:
+		// OpenList 对不存在的路径通常返回 500 + "object not found"
+		return e.Code == CodeNotFound || strings.Contains(strings.ToLower(e.Message), "not found")
+	case ErrTooManyRequests:
+		return e.Code == CodeTooManyRequests || isThrottleMessage(e.Message)
+	}
+	return false
+}
+
+// HTTPError 非 2xx 的 HTTP 状态码
+type HTTPError struct {
+	StatusCode int
+	URL        string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("openlist %s: http status %d", e.URL, e.StatusCode)
+}
+
+func (e *HTTPError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == CodeUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == CodeForbidden
+	case ErrTooManyRequests:
+		return e.StatusCode == CodeTooManyRequests
+	}
+	return false
+}
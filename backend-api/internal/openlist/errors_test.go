@@ -0,0 +1,48 @@
+package openlist
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestAPIErrorIsTooManyRequests(t *testing.T) {
+	cases := []struct {
+		code int
+		msg  string
+		want bool
+	}{
+		{CodeTooManyRequests, "", true},
+		{CodeInternalError, "Too Many Requests", true},
+		{CodeInternalError, "failed get objs: 操作过于频繁，请稍后再试", true},
+		{CodeInternalError, "访问太频繁", true},
+		{CodeInternalError, "请求频率过快，请稍后重试", true},
+		{CodeInternalError, "Request was denied due to request throttling.", true},
+		{CodeInternalError, `{"code":"TooManyRequests","message":"Too Many Requests"}`, true},
+		{CodeInternalError, "api rate limit exceeded", true},
+		{CodeInternalError, "接口限流中", true},
+		{CodeInternalError, "object not found", false},
+		{CodeInternalError, "failed get storage: storage not found", false},
+		{CodeInternalError, "context deadline exceeded", false},
+		{CodeUnauthorized, "token is expired", false},
+	}
+	for _, tc := range cases {
+		err := &APIError{Code: tc.code, Message: tc.msg, Path: "/api/fs/list"}
+		if got := errors.Is(err, ErrTooManyRequests); got != tc.want {
+			t.Errorf("code=%d msg=%q: Is(ErrTooManyRequests) = %v, want %v", tc.code, tc.msg, got, tc.want)
+		}
+		// 被包装后同样可识别
+		if got := isThrottled(fmt.Errorf("list: %w", err)); got != tc.want {
+			t.Errorf("code=%d msg=%q: isThrottled = %v, want %v", tc.code, tc.msg, got, tc.want)
+		}
+	}
+}
+
+func TestHTTPErrorIsTooManyRequests(t *testing.T) {
+	if !errors.Is(&HTTPError{StatusCode: CodeTooManyRequests}, ErrTooManyRequests) {
+		t.Error("http 429 应识别为限流")
+	}
+	if errors.Is(&HTTPError{StatusCode: CodeInternalError}, ErrTooManyRequests) {
+		t.Error("http 500 不应识别为限流")
+	}
+}
@@ -0,0 +1,108 @@
+package openlist
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultCooldown 主地址故障后的熔断冷却时间，冷却期内请求直接走备用地址
+const DefaultCooldown = 60 * time.Second
+
+// endpointState 单个服务地址的熔断状态
+type endpointState struct {
+	failures  int
+	openUntil time.Time
+	lastError string
+}
+
+// breaker 按服务地址记录健康状态，进程内所有客户端共享
+type breaker struct {
+	mutex  sync.Mutex
+	states map[string]*endpointState
+}
+
+var endpoints = &breaker{states: make(map[string]*endpointState)}
+
+// available 地址未处于熔断期则可用（冷却结束后半开，允许再次尝试）
+func (b *breaker) available(url string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	st, ok := b.states[url]
+	if !ok {
+		return true
+	}
+	return time.Now().After(st.openUntil)
+}
+
+func (b *breaker) markFailure(url string, err error, cooldown time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	st, ok := b.states[url]
+	if !ok {
+		st = &endpointState{}
+		b.states[url] = st
+	}
+	st.failures++
+	st.openUntil = time.Now().Add(cooldown)
+	st.lastError = err.Error()
+}
+
+func (b *breaker) markSuccess(url string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	delete(b.states, url)
+}
+
+// EndpointStatus 地址熔断状态快照
+type EndpointStatus struct {
+	URL       string    `json:"url"`
+	Healthy   bool      `json:"healthy"`
+	Failures  int       `json:"failures"`
+	OpenUntil time.Time `json:"openUntil"`
+	LastError string    `json:"lastError"`
+}
+
+// GetEndpointStatus 查询地址当前熔断状态
+func GetEndpointStatus(url string) EndpointStatus {
+	endpoints.mutex.Lock()
+	defer endpoints.mutex.Unlock()
+	st, ok := endpoints.states[url]
+	if !ok {
+		return EndpointStatus{URL: url, Healthy: true}
+	}
+	return EndpointStatus{
+		URL:       url,
+		Healthy:   time.Now().After(st.openUntil),
+		Failures:  st.failures,
+		OpenUntil: st.openUntil,
+		LastError: st.lastError,
+	}
+}
+
+// isFailoverError 判断错误是否应切换到备用地址：连接错误、超时、5xx
+func isFailoverError(ctx context.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+	// 调用方主动取消不算故障
+	if ctx.Err() != nil {
+		return false
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
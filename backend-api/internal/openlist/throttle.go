@@ -0,0 +1,67 @@
+package openlist
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/util"
+)
+
+const (
+	// maxThrottleRetries 请求过于频繁时最多重试次数
+	maxThrottleRetries = 5
+	// throttleBackoffBase 首次退避等待时间，之后每次翻倍
+	throttleBackoffBase = time.Second
+	// throttleBackoffMax 单次退避等待上限
+	throttleBackoffMax = 30 * time.Second
+)
+
+// limiterRegistry 按服务ID共享的请求限速器，进程内同一服务的所有客户端共用一个令牌桶
+type limiterRegistry struct {
+	mutex    sync.Mutex
+	limiters map[int]*util.RateLimiter
+}
+
+var limiters = &limiterRegistry{limiters: make(map[int]*util.RateLimiter)}
+
+// get 获取服务的限速器并按当前配置调整速率，rate <= 0 表示不限速
+func (r *limiterRegistry) get(serviceID int, rate float64) *util.RateLimiter {
+	burst := int(math.Ceil(rate))
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	l, ok := r.limiters[serviceID]
+	if !ok {
+		l = util.NewRateLimiter(rate, burst)
+		r.limiters[serviceID] = l
+		return l
+	}
+	l.SetRate(rate, burst)
+	return l
+}
+
+// wait 发送请求前获取令牌
+func (c *Client) wait(ctx context.Context) error {
+	if c.limiter == nil {
+		return nil
+	}
+	return c.limiter.Wait(ctx)
+}
+
+// throttleBackoff 第 attempt 次重试前的等待时间，指数增长并加入 ±20% 抖动
+func throttleBackoff(attempt int) time.Duration {
+	d := throttleBackoffBase << uint(attempt)
+	if d <= 0 || d > throttleBackoffMax {
+		d = throttleBackoffMax
+	}
+	jitter := time.Duration((rand.Float64()*0.4 - 0.2) * float64(d))
+	return d + jitter
+}
+
+// isThrottled 是否为 OpenList 或后端网盘的限流错误
+func isThrottled(err error) bool {
+	return err != nil && errors.Is(err, ErrTooManyRequests)
+}
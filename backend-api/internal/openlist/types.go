@@ -0,0 +1,143 @@
+package openlist
+
+import "time"
+
+// 用户角色
+const (
+	RoleGeneral = 0
+	RoleGuest   = 1
+	RoleAdmin   = 2
+)
+
+// permissionNames 用户权限位含义，按 bit 位顺序
+var permissionNames = []string{
+	"see_hides",
+	"access_without_password",
+	"offline_download",
+	"write",
+	"rename",
+	"move",
+	"copy",
+	"remove",
+	"webdav_read",
+	"webdav_manage",
+	"ftp_read",
+	"ftp_manage",
+	"read_archives",
+	"decompress",
+}
+
+// apiResponse OpenList 统一响应结构，data 延迟解析
+type apiResponse[T any] struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    T      `json:"data"`
+}
+
+// Object 文件/目录对象，对应 /api/fs/list 中的 content 项
+type Object struct {
+	Name     string            `json:"name"`
+	Size     int64             `json:"size"`
+	IsDir    bool              `json:"is_dir"`
+	Modified time.Time         `json:"modified"`
+	Created  time.Time         `json:"created"`
+	Sign     string            `json:"sign"`
+	Thumb    string            `json:"thumb"`
+	Type     int               `json:"type"`
+	HashInfo map[string]string `json:"hash_info"`
+}
+
+// ListReq /api/fs/list 请求参数
+type ListReq struct {
+	Path     string `json:"path"`
+	Password string `json:"password"`
+	Page     int    `json:"page"`
+	PerPage  int    `json:"per_page"`
+	Refresh  bool   `json:"refresh"`
+}
+
+// ListResp /api/fs/list 响应数据
+type ListResp struct {
+	Content  []Object `json:"content"`
+	Total    int64    `json:"total"`
+	Readme   string   `json:"readme"`
+	Header   string   `json:"header"`
+	Write    bool     `json:"write"`
+	Provider string   `json:"provider"`
+}
+
+// GetReq /api/fs/get 请求参数
+type GetReq struct {
+	Path     string `json:"path"`
+	Password string `json:"password"`
+}
+
+// GetResp /api/fs/get 响应数据
+type GetResp struct {
+	Object
+	RawURL   string   `json:"raw_url"`
+	Readme   string   `json:"readme"`
+	Header   string   `json:"header"`
+	Provider string   `json:"provider"`
+	Related  []Object `json:"related"`
+}
+
+// DirsReq /api/fs/dirs 请求参数
+type DirsReq struct {
+	Path      string `json:"path"`
+	Password  string `json:"password"`
+	ForceRoot bool   `json:"force_root"`
+}
+
+// DirItem /api/fs/dirs 响应项
+type DirItem struct {
+	Name     string    `json:"name"`
+	Modified time.Time `json:"modified"`
+}
+
+// LoginReq /api/auth/login 请求参数
+type LoginReq struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	OtpCode  string `json:"otp_code"`
+}
+
+// LoginResp /api/auth/login 响应数据
+type LoginResp struct {
+	Token string `json:"token"`
+}
+
+// MeResp /api/me 响应数据
+type MeResp struct {
+	ID         int    `json:"id"`
+	Username   string `json:"username"`
+	BasePath   string `json:"base_path"`
+	Role       int    `json:"role"`
+	Disabled   bool   `json:"disabled"`
+	Permission int    `json:"permission"`
+	SsoID      string `json:"sso_id"`
+	Otp        bool   `json:"otp"`
+}
+
+// Permissions 将权限位解析为权限名称列表
+func (m *MeResp) Permissions() []string {
+	perms := []string{}
+	for i, name := range permissionNames {
+		if m.Permission&(1<<i) != 0 {
+			perms = append(perms, name)
+		}
+	}
+	return perms
+}
+
+// RoleName 返回角色名称
+func (m *MeResp) RoleName() string {
+	switch m.Role {
+	case RoleAdmin:
+		return "admin"
+	case RoleGuest:
+		return "guest"
+	default:
+		return "general"
+	}
+}
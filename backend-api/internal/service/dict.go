@@ -1,8 +1,11 @@
 package service
 
 import (
+	"strings"
+
 	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
 	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/strm"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -31,3 +34,61 @@ func ListDicts(db *gorm.DB, dictType string) ([]*model.Dict, error) {
 	logger.Info("[Service] ListDicts called", zap.String("type", dictType))
 	return model.ListDicts(db, dictType)
 } 
+
+// defaultExtDicts 扩展名字典类型及内置默认值
+var defaultExtDicts = []struct {
+	dictType    string
+	description string
+	exts        []string
+}{
+	{model.DictTypeVideoExt, "视频扩展名", strm.DefaultVideoExts},
+	{model.DictTypeSubtitleExt, "字幕扩展名", strm.DefaultSubtitleExts},
+	{model.DictTypeImageExt, "图片扩展名", strm.DefaultImageExts},
+}
+
+// SeedDefaultDicts 为尚无记录的扩展名字典类型写入内置默认值，已有记录的类型保持管理员的修改
+func SeedDefaultDicts(db *gorm.DB) error {
+	for _, d := range defaultExtDicts {
+		count, err := model.CountDictsByType(db, d.dictType)
+		if err != nil {
+			logger.Error("[Service] SeedDefaultDicts 查询失败", zap.String("type", d.dictType), zap.Error(err))
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		logger.Info("[Service] SeedDefaultDicts 写入默认值", zap.String("type", d.dictType), zap.Int("count", len(d.exts)))
+		for _, ext := range d.exts {
+			dict := &model.Dict{Type: d.dictType, Key: strings.TrimPrefix(ext, "."), Value: ext, Description: d.description}
+			if err := model.CreateDict(db, dict); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// LoadExtensions 从字典表读取全局默认扩展名，某类型没有记录时使用内置默认值
+func LoadExtensions(db *gorm.DB) (strm.Extensions, error) {
+	ext := strm.DefaultExtensions()
+	targets := map[string]*[]string{
+		model.DictTypeVideoExt:    &ext.Video,
+		model.DictTypeSubtitleExt: &ext.Subtitle,
+		model.DictTypeImageExt:    &ext.Image,
+	}
+	for dictType, target := range targets {
+		dicts, err := model.ListDicts(db, dictType)
+		if err != nil {
+			return ext, err
+		}
+		if len(dicts) == 0 {
+			continue
+		}
+		values := make([]string, 0, len(dicts))
+		for _, d := range dicts {
+			values = append(values, strm.SplitList(d.Value)...)
+		}
+		*target = values
+	}
+	return ext, nil
+}
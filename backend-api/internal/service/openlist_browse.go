@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"path"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/openlist"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRemotePathNotFound = errors.New("远程路径不存在")
+	ErrRemotePathNotDir   = errors.New("远程路径不是目录")
+)
+
+// BrowseOpenListDir 浏览远程目录，dirsOnly 为 true 时仅返回子目录
+func BrowseOpenListDir(ctx context.Context, db *gorm.DB, svc *model.OpenListService, dir string, dirsOnly bool, page, pageSize int) ([]model.OpenListBrowseItem, int64, error) {
+	logger.Info("[Service] BrowseOpenListDir called", zap.Int("service_id", svc.ID), zap.String("path", dir), zap.Bool("dirsOnly", dirsOnly))
+	if dir == "" {
+		dir = "/"
+	}
+	client, err := NewOpenListClient(db, svc)
+	if err != nil {
+		return nil, 0, err
+	}
+	if dirsOnly {
+		// /api/fs/dirs 不支持分页，本地分页
+		dirs, err := client.Dirs(ctx, &openlist.DirsReq{Path: dir})
+		if err != nil {
+			return nil, 0, wrapRemotePathError(err)
+		}
+		total := int64(len(dirs))
+		start, end := pageRange(len(dirs), page, pageSize)
+		items := make([]model.OpenListBrowseItem, 0, end-start)
+		for _, d := range dirs[start:end] {
+			items = append(items, model.OpenListBrowseItem{
+				Name:     d.Name,
+				Path:     path.Join(dir, d.Name),
+				IsDir:    true,
+				Modified: d.Modified.Format("2006-01-02T15:04:05Z07:00"),
+			})
+		}
+		return items, total, nil
+	}
+	resp, err := client.List(ctx, &openlist.ListReq{Path: dir, Page: page, PerPage: pageSize})
+	if err != nil {
+		return nil, 0, wrapRemotePathError(err)
+	}
+	items := make([]model.OpenListBrowseItem, 0, len(resp.Content))
+	for _, obj := range resp.Content {
+		items = append(items, model.OpenListBrowseItem{
+			Name:     obj.Name,
+			Path:     path.Join(dir, obj.Name),
+			IsDir:    obj.IsDir,
+			Size:     obj.Size,
+			Modified: obj.Modified.Format("2006-01-02T15:04:05Z07:00"),
+		})
+	}
+	return items, resp.Total, nil
+}
+
+// ValidateRemoteDir 校验远程路径在服务上存在且为目录
+func ValidateRemoteDir(ctx context.Context, db *gorm.DB, svc *model.OpenListService, dir string) error {
+	client, err := NewOpenListClient(db, svc)
+	if err != nil {
+		return err
+	}
+	obj, err := client.Get(ctx, &openlist.GetReq{Path: dir})
+	if err != nil {
+		return wrapRemotePathError(err)
+	}
+	if !obj.IsDir {
+		return ErrRemotePathNotDir
+	}
+	return nil
+}
+
+func wrapRemotePathError(err error) error {
+	if openlist.IsNotFound(err) {
+		return ErrRemotePathNotFound
+	}
+	return err
+}
+
+// pageRange 计算本地分页的切片范围
+func pageRange(total, page, pageSize int) (int, int) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	}
+	start := (page - 1) * pageSize
+	if start > total {
+		start = total
+	}
+	end := start + pageSize
+	if end > total {
+		end = total
+	}
+	return start, end
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultHealthInterval      = 5 * time.Minute
+	defaultHealthRetentionDays = 7
+	healthCheckTimeout         = 15 * time.Second
+	// healthCheckConcurrency 同时检查的服务数上限
+	healthCheckConcurrency = 8
+)
+
+// OpenListHealthChecker 定期检查所有已启用的 OpenList 服务
+type OpenListHealthChecker struct {
+	db            *gorm.DB
+	interval      time.Duration
+	retentionDays int
+	statuses      map[int]*model.OpenListHealthRecord
+	mutex         sync.RWMutex
+}
+
+var (
+	healthChecker     *OpenListHealthChecker
+	healthCheckerOnce sync.Once
+)
+
+// StartOpenListHealthChecker 启动健康检查（单例），间隔由环境变量 OPENLIST_HEALTH_INTERVAL 配置（如 5m），
+// 历史保留天数由 OPENLIST_HEALTH_RETENTION_DAYS 配置
+func StartOpenListHealthChecker(db *gorm.DB) *OpenListHealthChecker {
+	healthCheckerOnce.Do(func() {
+		interval := defaultHealthInterval
+		if v := os.Getenv("OPENLIST_HEALTH_INTERVAL"); v != "" {
+			if d, err := time.ParseDuration(v); err == nil && d > 0 {
+				interval = d
+			} else {
+				logger.Error("[HealthChecker] OPENLIST_HEALTH_INTERVAL 格式错误，使用默认值", zap.String("value", v))
+			}
+		}
+		retentionDays := defaultHealthRetentionDays
+		if v := os.Getenv("OPENLIST_HEALTH_RETENTION_DAYS"); v != "" {
+			if n, err := strconv.Atoi(v); err == nil && n > 0 {
+				retentionDays = n
+			}
+		}
+		healthChecker = &OpenListHealthChecker{
+			db:            db,
+			interval:      interval,
+			retentionDays: retentionDays,
+			statuses:      make(map[int]*model.OpenListHealthRecord),
+		}
+		logger.Info("[HealthChecker] 启动", zap.Duration("interval", interval), zap.Int("retentionDays", retentionDays))
+		go healthChecker.run()
+	})
+	return healthChecker
+}
+
+// GetOpenListHealthChecker 获取健康检查实例，未启动时返回 nil
+func GetOpenListHealthChecker() *OpenListHealthChecker {
+	return healthChecker
+}
+
+func (hc *OpenListHealthChecker) run() {
+	hc.checkAll()
+	ticker := time.NewTicker(hc.interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		hc.checkAll()
+		if err := model.DeleteOpenListHealthRecordsBefore(hc.db, time.Now().AddDate(0, 0, -hc.retentionDays)); err != nil {
+			logger.Error("[HealthChecker] 清理历史记录失败", zap.Error(err))
+		}
+	}
+}
+
+// checkAll 并发检查所有已启用的服务，并清除已删除或已停用服务的内存状态
+func (hc *OpenListHealthChecker) checkAll() {
+	services, err := model.GetEnabledOpenListServices(hc.db)
+	if err != nil {
+		logger.Error("[HealthChecker] 获取服务列表失败", zap.Error(err))
+		return
+	}
+	hc.prune(services)
+
+	queue := make(chan *model.OpenListService)
+	var wg sync.WaitGroup
+	for i := 0; i < min(healthCheckConcurrency, len(services)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for svc := range queue {
+				hc.Check(svc)
+			}
+		}()
+	}
+	for _, svc := range services {
+		queue <- svc
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// prune 只保留仍启用的服务的状态
+func (hc *OpenListHealthChecker) prune(enabled []*model.OpenListService) {
+	keep := make(map[int]bool, len(enabled))
+	for _, svc := range enabled {
+		keep[svc.ID] = true
+	}
+	hc.mutex.Lock()
+	defer hc.mutex.Unlock()
+	for id := range hc.statuses {
+		if !keep[id] {
+			delete(hc.statuses, id)
+		}
+	}
+}
+
+// Check 检查单个服务并记录结果；服务不可用期间，实际执行的任务由 ExecuteStrmTask 记为跳过
+func (hc *OpenListHealthChecker) Check(svc *model.OpenListService) *model.OpenListHealthRecord {
+	record := &model.OpenListHealthRecord{ServiceID: svc.ID, Url: svc.ServiceUrl}
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+	client, err := NewOpenListClient(hc.db, svc)
+	if err == nil {
+		start := time.Now()
+		_, err = client.Me(ctx)
+		record.LatencyMs = time.Since(start).Milliseconds()
+		record.Url = client.BaseURL()
+	}
+	if err != nil {
+		record.Status = model.HealthStatusDown
+		record.Error = err.Error()
+	} else {
+		record.Status = model.HealthStatusUp
+	}
+	if err := model.CreateOpenListHealthRecord(hc.db, record); err != nil {
+		logger.Error("[HealthChecker] 保存检查记录失败", zap.Int("service_id", svc.ID), zap.Error(err))
+	}
+
+	hc.mutex.Lock()
+	prev := hc.statuses[svc.ID]
+	hc.statuses[svc.ID] = record
+	hc.mutex.Unlock()
+
+	if record.Status == model.HealthStatusDown && (prev == nil || prev.Status != model.HealthStatusDown) {
+		logger.Warn("[HealthChecker] 服务不可用", zap.Int("service_id", svc.ID), zap.String("name", svc.Name), zap.String("error", record.Error))
+	} else if record.Status == model.HealthStatusUp && prev != nil && prev.Status == model.HealthStatusDown {
+		logger.Info("[HealthChecker] 服务已恢复", zap.Int("service_id", svc.ID), zap.String("name", svc.Name))
+	}
+	return record
+}
+
+// Status 获取服务当前健康状态，内存中没有时从数据库读取最近一次记录
+func (hc *OpenListHealthChecker) Status(serviceID int) *model.OpenListHealthRecord {
+	hc.mutex.RLock()
+	record, ok := hc.statuses[serviceID]
+	hc.mutex.RUnlock()
+	if ok {
+		return record
+	}
+	record, err := model.GetLatestOpenListHealthRecord(hc.db, serviceID)
+	if err != nil {
+		return nil
+	}
+	return record
+}
+
+// IsOpenListServiceDown 服务最近一次检查是否不可用，健康检查未启动或尚未检查时返回 false
+func IsOpenListServiceDown(serviceID int) bool {
+	hc := GetOpenListHealthChecker()
+	if hc == nil {
+		return false
+	}
+	record := hc.Status(serviceID)
+	return record != nil && record.Status == model.HealthStatusDown
+}
+
+// LogNameForTaskMode 根据任务模式返回日志名称
+func LogNameForTaskMode(mode model.TaskMode) model.LogName {
+	if mode == model.TaskModeCheck {
+		return model.LogNameCheck
+	}
+	return model.LogNameCreate
+}
+
+// GetOpenListHealthRecords 分页获取服务健康检查历史
+func GetOpenListHealthRecords(db *gorm.DB, serviceID, page, pageSize int) ([]*model.OpenListHealthRecord, int64, error) {
+	return model.GetOpenListHealthRecords(db, serviceID, page, pageSize)
+}
+
+// GetAllOpenListServicesByUserID 获取用户全部OpenList服务
+func GetAllOpenListServicesByUserID(db *gorm.DB, userID int) ([]*model.OpenListService, error) {
+	return model.GetAllOpenListServicesByUserID(db, userID)
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+)
+
+func TestHealthCheckAllConcurrentAndPrune(t *testing.T) {
+	db := newTestDB(t)
+	fake := newFakeOpenList(t, nil)
+	fake.setHook(func(api, p string) {
+		if api == "/api/me" {
+			time.Sleep(200 * time.Millisecond)
+		}
+	})
+	var ids []int
+	for i := 0; i < 4; i++ {
+		svc := &model.OpenListService{UserID: 1, Name: "svc", ServiceUrl: fake.URL, Enabled: true}
+		if err := db.Create(svc).Error; err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, svc.ID)
+	}
+	disabled := &model.OpenListService{UserID: 1, Name: "off", ServiceUrl: fake.URL, Enabled: true}
+	if err := db.Create(disabled).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(disabled).Update("enabled", false).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	hc := &OpenListHealthChecker{db: db, statuses: map[int]*model.OpenListHealthRecord{
+		disabled.ID: {ServiceID: disabled.ID, Status: model.HealthStatusDown},
+		9999:        {ServiceID: 9999, Status: model.HealthStatusUp},
+	}}
+	start := time.Now()
+	hc.checkAll()
+	// 4 个服务各耗时约 200ms，并发检查时总耗时远小于 800ms
+	if d := time.Since(start); d > 600*time.Millisecond {
+		t.Errorf("checkAll 耗时 %v，应并发检查", d)
+	}
+	if len(hc.statuses) != len(ids) {
+		t.Errorf("statuses = %v", hc.statuses)
+	}
+	for _, id := range ids {
+		if record := hc.statuses[id]; record == nil || record.Status != model.HealthStatusUp {
+			t.Errorf("服务 %d 状态 = %+v", id, record)
+		}
+	}
+}
+
+func TestExecuteStrmTaskSkippedWhenServiceDown(t *testing.T) {
+	db := newTestDB(t)
+	svc, _, task := createTestTask(t, db, "http://127.0.0.1:1", t.TempDir())
+	hc := &OpenListHealthChecker{db: db, statuses: map[int]*model.OpenListHealthRecord{}}
+	prev := healthChecker
+	healthChecker = hc
+	t.Cleanup(func() { healthChecker = prev })
+
+	// 服务转为不可用时只记录检查结果，不为任务写日志
+	if record := hc.Check(svc); record.Status != model.HealthStatusDown {
+		t.Fatalf("status = %s, want down", record.Status)
+	}
+	if records, _ := model.GetLogRecordsByTaskID(db, task.ID); len(records) != 0 {
+		t.Fatalf("服务转为不可用时不应写任务日志, records = %d", len(records))
+	}
+
+	// 每次实际跳过的执行各记一条 skipped
+	for i := 0; i < 2; i++ {
+		if err := ExecuteStrmTask(context.Background(), db, task); !errors.Is(err, ErrTaskServiceDown) {
+			t.Fatalf("err = %v, want ErrTaskServiceDown", err)
+		}
+	}
+	records, err := model.GetLogRecordsByTaskID(db, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("records = %d, want 2", len(records))
+	}
+	for _, r := range records {
+		if r.TaskStatus != model.TaskStatusSkipped {
+			t.Errorf("record status = %s, want skipped", r.TaskStatus)
+		}
+	}
+}
@@ -1,7 +1,15 @@
 package service
 
 import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
 	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/openlist"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/util"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
@@ -21,6 +29,119 @@ func UpdateOpenListService(db *gorm.DB, service *model.OpenListService) error {
 	return model.UpdateOpenListService(db, service)
 }
 
+// UpdateOpenListServiceSign 更新签名密钥及有效期
+func UpdateOpenListServiceSign(db *gorm.DB, id int, secret string, expireHours int) error {
+	return model.UpdateOpenListServiceSign(db, id, secret, expireHours)
+}
+
+// UpdateOpenListServiceThrottle 更新遍历并发数、请求限速和任务并发数
+func UpdateOpenListServiceThrottle(db *gorm.DB, id int, concurrency int, rateLimit float64, taskConcurrency int) error {
+	return model.UpdateOpenListServiceThrottle(db, id, concurrency, rateLimit, taskConcurrency)
+}
+
 func DeleteOpenListService(db *gorm.DB, id int) error {
 	return model.DeleteOpenListService(db, id)
-} 
+}
+
+// NewOpenListClient 根据已保存的服务创建客户端，配置了密码时自动登录并将刷新后的token写回数据库
+func NewOpenListClient(db *gorm.DB, svc *model.OpenListService, opts ...openlist.Option) (*openlist.Client, error) {
+	password, err := util.DecryptString(svc.Password)
+	if err != nil {
+		logger.Error("[Service] NewOpenListClient 密码解密失败", zap.Int("service_id", svc.ID), zap.Error(err))
+		return nil, err
+	}
+	if password != "" {
+		serviceID := svc.ID
+		opts = append([]openlist.Option{
+			openlist.WithCredentials(svc.Account, password),
+			openlist.WithTokenRefresh(func(token string) {
+				if err := model.UpdateOpenListServiceToken(db, serviceID, token); err != nil {
+					logger.Error("[Service] 保存刷新后的token失败", zap.Int("service_id", serviceID), zap.Error(err))
+				}
+			}),
+		}, opts...)
+	}
+	return openlist.NewClient(svc, opts...), nil
+}
+
+// NewOpenListClientForConfig 根据Strm配置创建OpenList客户端，IsUseBackupUrl 决定主地址故障时是否切换到备用地址
+func NewOpenListClientForConfig(db *gorm.DB, cfg *model.StrmConfig) (*openlist.Client, *model.OpenListService, error) {
+	svc, err := model.GetOpenListServiceByID(db, cfg.ServiceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := NewOpenListClient(db, svc, openlist.WithFailover(cfg.IsUseBackupUrl && svc.BackupUrl != ""))
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, svc, nil
+}
+
+// TestOpenListService 分别测试主地址和备用地址的连通性与认证，返回诊断结果
+// password 为明文密码，非空时先登录获取token再调用 /api/me
+func TestOpenListService(ctx context.Context, svc *model.OpenListService, password string) *model.OpenListTestResult {
+	logger.Info("[Service] TestOpenListService called", zap.String("service_url", svc.ServiceUrl), zap.String("backup_url", svc.BackupUrl))
+	result := &model.OpenListTestResult{}
+	primary, user := testOpenListEndpoint(ctx, svc, password, svc.ServiceUrl)
+	result.Primary = *primary
+	result.User = user
+	if svc.BackupUrl != "" {
+		backup, backupUser := testOpenListEndpoint(ctx, svc, password, svc.BackupUrl)
+		result.Backup = backup
+		if result.User == nil {
+			result.User = backupUser
+		}
+	}
+	result.Success = result.Primary.Authenticated || (result.Backup != nil && result.Backup.Authenticated)
+	return result
+}
+
+// testOpenListEndpoint 测试单个地址，不做主备切换
+func testOpenListEndpoint(ctx context.Context, svc *model.OpenListService, password, url string) (*model.OpenListEndpointResult, *model.OpenListUserResult) {
+	target := *svc
+	target.ServiceUrl = url
+	target.BackupUrl = ""
+	client := openlist.NewClient(&target, openlist.WithFailover(false), openlist.WithTimeout(10*time.Second), openlist.WithCredentials(svc.Account, password))
+	result := &model.OpenListEndpointResult{URL: url}
+
+	settings, err := client.PublicSettings(ctx)
+	if err != nil {
+		result.Error = err.Error()
+		logger.Error("[Service] TestOpenListService 地址不可访问", zap.String("url", url), zap.Error(err))
+		return result, nil
+	}
+	result.Reachable = true
+	if v, ok := settings["version"]; ok {
+		result.Version = fmt.Sprintf("%v", v)
+	}
+
+	if password != "" {
+		if _, err := client.Login(ctx); err != nil {
+			result.Error = "登录失败: " + err.Error()
+			logger.Error("[Service] TestOpenListService 登录失败", zap.String("url", url), zap.Error(err))
+			return result, nil
+		}
+		result.Login = true
+	}
+
+	start := time.Now()
+	me, err := client.Me(ctx)
+	result.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		logger.Error("[Service] TestOpenListService 认证失败", zap.String("url", url), zap.Error(err))
+		return result, nil
+	}
+	if me.Role == openlist.RoleGuest {
+		result.Error = "token无效，当前为游客身份"
+		return result, nil
+	}
+	result.Authenticated = true
+	return result, &model.OpenListUserResult{
+		Username:    me.Username,
+		BasePath:    me.BasePath,
+		Role:        me.RoleName(),
+		Disabled:    me.Disabled,
+		Permissions: me.Permissions(),
+	}
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/strm"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// CheckStrm 检查配置输出目录中已有的 .strm 是否仍可播放，repair 为 true 时自动修复；
+// 运行过程记录为 LogNameCheck 日志，taskID 为 0 表示手动检查
+func CheckStrm(ctx context.Context, db *gorm.DB, cfg *model.StrmConfig, taskID int, repair bool) (*strm.CheckReport, error) {
+	logger.Info("[Service] CheckStrm called", zap.Int("config_id", cfg.ID), zap.Int("task_id", taskID), zap.Bool("repair", repair))
+	record, err := startLogRecord(db, cfg, taskID, model.LogNameCheck)
+	if err != nil {
+		return nil, err
+	}
+	var opts []strm.Option
+	if taskID > 0 {
+		publishStrmTaskProgress(taskID, record.ID, model.TaskStatusRunning, nil)
+		opts = append(opts, strmProgressOption(taskID, record.ID))
+	}
+	report, err := checkStrm(ctx, db, cfg, repair, opts...)
+	var result interface{} = report
+	usedURL := ""
+	switch {
+	case report == nil && err != nil:
+		result = map[string]string{"error": err.Error()}
+	case report != nil:
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+		}
+		usedURL = report.UsedURL
+	}
+	finishLogRecord(ctx, db, record, result, usedURL, err)
+	return report, err
+}
+
+func checkStrm(ctx context.Context, db *gorm.DB, cfg *model.StrmConfig, repair bool, opts ...strm.Option) (*strm.CheckReport, error) {
+	generator, err := newStrmGenerator(db, cfg, opts...)
+	if err != nil {
+		return nil, err
+	}
+	report, err := generator.Check(ctx, repair)
+	if err != nil || !repair {
+		return report, err
+	}
+	// 修复会改写链接或删除失效文件，同步更新清单
+	if err := model.ReplaceStrmManifest(db, cfg.ID, generator.Manifest()); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// finishLogRecord 按运行结果更新日志状态，结果序列化为 JSON 保存；被暂停或取消时按 ctx 的取消原因记录
+func finishLogRecord(ctx context.Context, db *gorm.DB, record *model.LogRecord, result interface{}, usedURL string, runErr error) {
+	status := model.TaskStatusCompleted
+	switch cause := context.Cause(ctx); {
+	case runErr == nil:
+	case errors.Is(cause, strm.ErrPaused):
+		status = model.TaskStatusPaused
+	case errors.Is(cause, strm.ErrCancelled):
+		status = model.TaskStatusCancelled
+	default:
+		status = model.TaskStatusError
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		logger.Error("[Service] 序列化运行结果失败", zap.Int("log_id", record.ID), zap.Error(err))
+	}
+	if err := model.FinishLogRecord(db, record.ID, status, usedURL, string(data)); err != nil {
+		logger.Error("[Service] 更新运行日志失败", zap.Int("log_id", record.ID), zap.Error(err))
+	}
+	finishStrmTaskProgress(record.TaskID, record.ID, status, runErr)
+}
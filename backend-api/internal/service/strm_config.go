@@ -1,9 +1,13 @@
 package service
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/strm"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/util"
 	"gorm.io/gorm"
 )
 
@@ -20,8 +24,14 @@ func CopyStrmConfigs(db *gorm.DB, ids []int) error {
 		if err != nil || cfg == nil {
 			return err
 		}
+		outputPath, err := copyOutputPath(db, cfg.StrmOutputPath)
+		if err != nil {
+			return err
+		}
 		cfg.ID = 0
 		cfg.Name = cfg.Name + "-复制"
+		cfg.StrmOutputPath = outputPath
+		cfg.PlaySecret = util.NewPlaySecret()
 		cfg.CreatedAt = time.Now()
 		cfg.UpdatedAt = time.Now()
 		err = model.CreateStrmConfig(db, cfg)
@@ -32,16 +42,65 @@ func CopyStrmConfigs(db *gorm.DB, ids []int) error {
 	return nil
 }
 
+// copyOutputPath 为复制的配置选择不与已有配置重叠的输出目录
+func copyOutputPath(db *gorm.DB, outputPath string) (string, error) {
+	base := strings.TrimRight(outputPath, "/\\") + "-copy"
+	for i := 1; ; i++ {
+		candidate := base
+		if i > 1 {
+			candidate = fmt.Sprintf("%s%d", base, i)
+		}
+		conflict, err := FindOutputPathConflict(db, 0, candidate)
+		if err != nil {
+			return "", err
+		}
+		if conflict == nil {
+			return candidate, nil
+		}
+	}
+}
+
+// FindOutputPathConflict 查找输出目录与 outputPath 相同或互相包含的其他配置，excludeID 为正在编辑的配置
+func FindOutputPathConflict(db *gorm.DB, excludeID int, outputPath string) (*model.StrmConfig, error) {
+	configs, err := model.ListAllStrmConfigs(db)
+	if err != nil {
+		return nil, err
+	}
+	for _, cfg := range configs {
+		if cfg.ID == excludeID || cfg.StrmOutputPath == "" {
+			continue
+		}
+		if strm.PathsOverlap(cfg.StrmOutputPath, outputPath) {
+			return cfg, nil
+		}
+	}
+	return nil, nil
+}
+
 func CreateStrmConfig(db *gorm.DB, config *model.StrmConfig) error {
+	config.PlaySecret = util.NewPlaySecret()
 	return model.CreateStrmConfig(db, config)
 }
 
+// RotateStrmPlaySecret 轮换配置的播放链接密钥，已生成的 /play 链接立即失效，下次生成时重写 .strm
+func RotateStrmPlaySecret(db *gorm.DB, cfg *model.StrmConfig) error {
+	secret := util.NewPlaySecret()
+	if err := model.UpdateStrmConfigPlaySecret(db, cfg.ID, secret); err != nil {
+		return err
+	}
+	cfg.PlaySecret = secret
+	return nil
+}
+
 func UpdateStrmConfig(db *gorm.DB, config *model.StrmConfig) error {
 	return model.UpdateStrmConfig(db, config)
 }
 
 func DeleteStrmConfig(db *gorm.DB, id int) error {
-	return model.DeleteStrmConfig(db, id)
+	if err := model.DeleteStrmConfig(db, id); err != nil {
+		return err
+	}
+	return model.DeleteStrmManifest(db, id)
 }
 
 func GetStrmConfigByID(db *gorm.DB, id int) (*model.StrmConfig, error) {
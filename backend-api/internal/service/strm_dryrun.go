@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/strm"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// dryRunReportTTL 试运行报告保留时间
+	dryRunReportTTL = time.Hour
+	// dryRunReportMax 最多保留的报告数，超出时淘汰最早的
+	dryRunReportMax = 20
+)
+
+var ErrDryRunReportNotFound = errors.New("报告不存在或已过期")
+
+// TaskModeDryRun 队列中试运行的模式
+const TaskModeDryRun model.TaskMode = "dry-run"
+
+// DryRunReport 试运行报告，试运行在任务队列中执行，Status 为 completed 后 Items 通过分页接口或导出获取。
+// 明细超出上限时 Items 只保留前一部分，Counts 为全部操作的数量
+type DryRunReport struct {
+	ID        string                  `json:"reportId"`
+	ConfigID  int                     `json:"configId"`
+	UserID    int                     `json:"-"`
+	JobID     int64                   `json:"jobId"`
+	Status    model.TaskStatus        `json:"status"` // queued/running/completed/error
+	Error     string                  `json:"error,omitempty"`
+	Summary   *strm.Summary           `json:"summary"`
+	Counts    map[strm.DiffAction]int `json:"counts"`
+	Truncated bool                    `json:"truncated"` // 明细超出上限，只保留了前一部分
+	Items     []*strm.DiffItem        `json:"-"`
+	CreatedAt time.Time               `json:"createdAt"`
+	ExpiresAt time.Time               `json:"expiresAt"`
+}
+
+var (
+	dryRunReports      = make(map[string]*DryRunReport)
+	dryRunReportsMutex sync.Mutex
+)
+
+// StartDryRunStrm 将试运行加入任务队列，立即返回排队中的报告。试运行完整遍历和比对但不修改本地文件，也不保存清单；
+// 该配置已有试运行在排队时返回已有的报告和 true
+func StartDryRunStrm(db *gorm.DB, cfg *model.StrmConfig, userID int) (*DryRunReport, bool, error) {
+	logger.Info("[Service] StartDryRunStrm called", zap.Int("config_id", cfg.ID))
+	q := GetStrmTaskQueue()
+	if q == nil {
+		return nil, false, ErrQueueNotReady
+	}
+	now := time.Now()
+	report := &DryRunReport{
+		ID:        newReportID(),
+		ConfigID:  cfg.ID,
+		UserID:    userID,
+		Status:    TaskStatusQueued,
+		Counts:    map[strm.DiffAction]int{},
+		CreatedAt: now,
+		ExpiresAt: now.Add(dryRunReportTTL),
+	}
+	saveDryRunReport(report)
+	task := &model.StrmTask{
+		Name:      cfg.Name,
+		TaskMode:  TaskModeDryRun,
+		UserID:    userID,
+		ServiceID: cfg.ServiceID,
+		ConfigID:  cfg.ID,
+	}
+	job, duplicated, err := q.EnqueueFunc(task, JobPriorityManual, func(ctx context.Context) error {
+		return runDryRunStrm(ctx, db, cfg, report.ID)
+	})
+	if err != nil || duplicated {
+		dryRunReportsMutex.Lock()
+		delete(dryRunReports, report.ID)
+		dryRunReportsMutex.Unlock()
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if duplicated {
+		existing, err := queuedDryRunReport(cfg.ID, userID)
+		return existing, true, err
+	}
+	updateDryRunReport(report.ID, func(r *DryRunReport) { r.JobID = job.ID })
+	report, err = GetDryRunReport(report.ID, userID)
+	return report, false, err
+}
+
+// queuedDryRunReport 该配置排队中的试运行报告，队列按配置去重，同一配置最多一个
+func queuedDryRunReport(configID, userID int) (*DryRunReport, error) {
+	dryRunReportsMutex.Lock()
+	id := ""
+	for _, r := range dryRunReports {
+		if r.ConfigID == configID && r.Status == TaskStatusQueued {
+			id = r.ID
+			break
+		}
+	}
+	dryRunReportsMutex.Unlock()
+	if id == "" {
+		return nil, ErrDryRunReportNotFound
+	}
+	return GetDryRunReport(id, userID)
+}
+
+// runDryRunStrm 在队列中执行试运行并写入报告
+func runDryRunStrm(ctx context.Context, db *gorm.DB, cfg *model.StrmConfig, reportID string) error {
+	updateDryRunReport(reportID, func(r *DryRunReport) { r.Status = model.TaskStatusRunning })
+	generator, err := newStrmGenerator(db, cfg, strm.WithDryRun())
+	var summary *strm.Summary
+	if err == nil {
+		summary, err = generator.Run(ctx)
+	}
+	updateDryRunReport(reportID, func(r *DryRunReport) {
+		r.ExpiresAt = time.Now().Add(dryRunReportTTL)
+		if err != nil {
+			r.Status = model.TaskStatusError
+			r.Error = err.Error()
+			return
+		}
+		r.Status = model.TaskStatusCompleted
+		r.Summary = summary
+		r.Counts = generator.DiffCounts()
+		r.Truncated = generator.DiffTruncated()
+		r.Items = generator.Diff()
+	})
+	return err
+}
+
+// updateDryRunReport 在锁内修改报告，报告已过期或被淘汰时忽略
+func updateDryRunReport(id string, fn func(r *DryRunReport)) {
+	dryRunReportsMutex.Lock()
+	defer dryRunReportsMutex.Unlock()
+	if report, ok := dryRunReports[id]; ok {
+		fn(report)
+	}
+}
+
+// GetDryRunReport 获取当前用户的试运行报告，返回副本，执行中的试运行写入报告不影响调用方
+func GetDryRunReport(id string, userID int) (*DryRunReport, error) {
+	dryRunReportsMutex.Lock()
+	defer dryRunReportsMutex.Unlock()
+	report, ok := dryRunReports[id]
+	if !ok || report.UserID != userID || time.Now().After(report.ExpiresAt) {
+		return nil, ErrDryRunReportNotFound
+	}
+	r := *report
+	return &r, nil
+}
+
+// FilterItems 按操作类型筛选报告条目，action 为空时返回全部
+func (r *DryRunReport) FilterItems(action strm.DiffAction) []*strm.DiffItem {
+	if action == "" {
+		return r.Items
+	}
+	items := []*strm.DiffItem{}
+	for _, item := range r.Items {
+		if item.Action == action {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func saveDryRunReport(report *DryRunReport) {
+	dryRunReportsMutex.Lock()
+	defer dryRunReportsMutex.Unlock()
+	now := time.Now()
+	var oldest *DryRunReport
+	for id, r := range dryRunReports {
+		if now.After(r.ExpiresAt) {
+			delete(dryRunReports, id)
+			continue
+		}
+		if oldest == nil || r.CreatedAt.Before(oldest.CreatedAt) {
+			oldest = r
+		}
+	}
+	if len(dryRunReports) >= dryRunReportMax && oldest != nil {
+		delete(dryRunReports, oldest.ID)
+	}
+	dryRunReports[report.ID] = report
+}
+
+func newReportID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
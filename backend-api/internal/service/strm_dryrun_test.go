@@ -0,0 +1,93 @@
+package service
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/strm"
+)
+
+// waitDryRunReport 等待试运行报告进入指定状态
+func waitDryRunReport(t *testing.T, id string, status model.TaskStatus) *DryRunReport {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		report, err := GetDryRunReport(id, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Status == status {
+			return report
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("试运行报告未进入 %s 状态", status)
+	return nil
+}
+
+func TestDryRunStrmRunsInQueue(t *testing.T) {
+	db := newTestDB(t)
+	fake := newFakeOpenList(t, map[string][]string{
+		"/media":       {"Movie/"},
+		"/media/Movie": {"Movie.mkv", "Movie.srt"},
+	})
+	out := t.TempDir()
+	_, cfg, _ := createTestTask(t, db, fake.URL, out)
+	useTestQueue(t, db, 1)
+
+	// 配置被占用时试运行在队列中等待
+	release := make(chan struct{})
+	locked := make(chan struct{})
+	go WithStrmConfigLock(cfg.ID, func() error {
+		close(locked)
+		<-release
+		return nil
+	})
+	<-locked
+	report, duplicated, err := StartDryRunStrm(db, cfg, 1)
+	if err != nil || duplicated {
+		t.Fatalf("err = %v, duplicated = %v", err, duplicated)
+	}
+	if report.Status != TaskStatusQueued || report.JobID == 0 {
+		t.Fatalf("report = %+v", report)
+	}
+	// 排队中再次试运行返回已有的报告
+	again, duplicated, err := StartDryRunStrm(db, cfg, 1)
+	if err != nil || !duplicated || again.ID != report.ID {
+		t.Fatalf("重复试运行 = %+v, duplicated = %v, err = %v", again, duplicated, err)
+	}
+	jobs := GetStrmTaskQueue().Jobs()
+	if len(jobs) != 1 || jobs[0].Task.TaskMode != TaskModeDryRun || jobs[0].Task.ConfigID != cfg.ID {
+		t.Fatalf("jobs = %+v", jobs)
+	}
+	if _, err := GetDryRunReport(report.ID, 2); err != ErrDryRunReportNotFound {
+		t.Errorf("其他用户不应读取报告, err = %v", err)
+	}
+	close(release)
+
+	report = waitDryRunReport(t, report.ID, model.TaskStatusCompleted)
+	if report.Counts[strm.DiffCreate] != 1 || len(report.Items) == 0 || report.Truncated {
+		t.Errorf("report = %+v", report)
+	}
+	if entries, _ := os.ReadDir(out); len(entries) != 0 {
+		t.Errorf("试运行不应写入文件: %v", entries)
+	}
+}
+
+func TestDryRunStrmError(t *testing.T) {
+	db := newTestDB(t)
+	fake := newFakeOpenList(t, map[string][]string{})
+	_, cfg, _ := createTestTask(t, db, fake.URL, t.TempDir())
+	useTestQueue(t, db, 1)
+
+	report, _, err := StartDryRunStrm(db, cfg, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report = waitDryRunReport(t, report.ID, model.TaskStatusError)
+	if report.Error == "" {
+		t.Errorf("report = %+v", report)
+	}
+}
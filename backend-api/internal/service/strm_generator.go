@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/strm"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/util"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// StartGenerateStrm 以手动优先级将配置的生成加入任务队列，执行时重新读取配置，结果记录到运行日志；
+// 该配置已有生成在排队时返回已有的 job 和 true
+func StartGenerateStrm(db *gorm.DB, cfg *model.StrmConfig, userID int) (*StrmQueueJob, bool, error) {
+	logger.Info("[Service] StartGenerateStrm called", zap.Int("config_id", cfg.ID))
+	q := GetStrmTaskQueue()
+	if q == nil {
+		return nil, false, ErrQueueNotReady
+	}
+	task := &model.StrmTask{
+		Name:      cfg.Name,
+		TaskMode:  model.TaskModeCreate,
+		UserID:    userID,
+		ServiceID: cfg.ServiceID,
+		ConfigID:  cfg.ID,
+	}
+	return q.EnqueueFunc(task, JobPriorityManual, func(ctx context.Context) error {
+		latest, err := model.GetStrmConfigByID(db, cfg.ID)
+		if err != nil {
+			logger.Error("[Service] 配置不存在", zap.Int("config_id", cfg.ID), zap.Error(err))
+			return err
+		}
+		_, err = generateStrmWithLog(ctx, db, latest, 0)
+		return err
+	})
+}
+
+// generateStrm 执行生成，resume 不为空时从暂停的进度继续；被暂停或取消时返回停止时的进度
+func generateStrm(ctx context.Context, db *gorm.DB, cfg *model.StrmConfig, resume *strm.Checkpoint, opts ...strm.Option) (*strm.Summary, *strm.Checkpoint, error) {
+	logger.Info("[Service] GenerateStrm called", zap.Int("config_id", cfg.ID), zap.Bool("resume", resume != nil))
+	if resume != nil {
+		opts = append(opts, strm.WithCheckpoint(resume))
+	}
+	generator, err := newStrmGenerator(db, cfg, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	summary, err := generator.Run(ctx)
+	if err != nil {
+		return summary, generator.Checkpoint(), err
+	}
+	// 仅在完整扫描成功后保存清单
+	if err := model.ReplaceStrmManifest(db, cfg.ID, generator.Manifest()); err != nil {
+		return summary, nil, err
+	}
+	return summary, nil, nil
+}
+
+// newStrmGenerator 加载客户端、上次清单、签名密钥和扩展名字典，创建生成器
+func newStrmGenerator(db *gorm.DB, cfg *model.StrmConfig, opts ...strm.Option) (*strm.Generator, error) {
+	client, svc, err := NewOpenListClientForConfig(db, cfg)
+	if err != nil {
+		logger.Error("[Service] 创建客户端失败", zap.Int("config_id", cfg.ID), zap.Error(err))
+		return nil, err
+	}
+	previous, err := model.GetStrmManifest(db, cfg.ID)
+	if err != nil {
+		logger.Error("[Service] 读取清单失败", zap.Int("config_id", cfg.ID), zap.Error(err))
+		return nil, err
+	}
+	signSecret, err := util.DecryptString(svc.SignSecret)
+	if err != nil {
+		logger.Error("[Service] 签名密钥解密失败", zap.Int("service_id", svc.ID), zap.Error(err))
+		return nil, err
+	}
+	extensions, err := LoadExtensions(db)
+	if err != nil {
+		logger.Error("[Service] 读取扩展名字典失败", zap.Int("config_id", cfg.ID), zap.Error(err))
+		return nil, err
+	}
+	opts = append([]strm.Option{
+		strm.WithPreviousManifest(previous),
+		strm.WithSignSecret(signSecret),
+		strm.WithPlayBaseURL(PlayBaseURL()),
+		strm.WithExtensions(extensions),
+	}, opts...)
+	return strm.NewGenerator(cfg, svc, client, opts...), nil
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+)
+
+func TestStartGenerateStrmRunsInQueue(t *testing.T) {
+	db := newTestDB(t)
+	useTestQueue(t, db, 1)
+	fake := newFakeOpenList(t, testLibrary())
+	out := t.TempDir()
+	_, cfg, _ := createTestTask(t, db, fake.URL, out)
+
+	job, duplicated, err := StartGenerateStrm(db, cfg, cfg.UserID)
+	if err != nil || duplicated {
+		t.Fatalf("err = %v, duplicated = %v", err, duplicated)
+	}
+	if job.Task.ID != 0 || job.Task.ConfigID != cfg.ID || job.Task.TaskMode != model.TaskModeCreate {
+		t.Errorf("job task = %+v", job.Task)
+	}
+
+	var record model.LogRecord
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		err := db.Where("config_id = ? AND task_status = ?", cfg.ID, model.TaskStatusCompleted).First(&record).Error
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if record.ID == 0 {
+		t.Fatal("生成未完成")
+	}
+	if record.Name != model.LogNameCreate || record.TaskID != 0 || record.UserID != cfg.UserID {
+		t.Errorf("log = %+v", record)
+	}
+	_, summary := logResult(t, db, record.ID)
+	if summary.Created != 9 {
+		t.Errorf("created = %d", summary.Created)
+	}
+	if _, err := os.Stat(filepath.Join(out, "ShowB", "B2.strm")); err != nil {
+		t.Error(err)
+	}
+}
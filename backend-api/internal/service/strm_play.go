@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/openlist"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/strm"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// playCacheTTL 直链缓存时间，云盘直链通常有效期较短，不宜过长
+	playCacheTTL = 2 * time.Minute
+	// playCacheMaxSize 超过该数量时清理过期缓存
+	playCacheMaxSize = 1000
+)
+
+var (
+	ErrPlayConfigNotFound = errors.New("配置不存在")
+	ErrPlayPathInvalid    = errors.New("播放路径非法")
+)
+
+// playCacheEntry 已解析的直链
+type playCacheEntry struct {
+	url       string
+	expiresAt time.Time
+}
+
+var (
+	playCache      = make(map[string]*playCacheEntry)
+	playCacheMutex sync.Mutex
+)
+
+// PlayBaseURL 本服务对外访问地址，由环境变量 PLAY_BASE_URL 配置（如 http://192.168.1.2:8080）
+func PlayBaseURL() string {
+	return strings.TrimRight(os.Getenv("PLAY_BASE_URL"), "/")
+}
+
+// GetPlayConfig 获取播放链接对应的配置
+func GetPlayConfig(db *gorm.DB, configID int) (*model.StrmConfig, error) {
+	cfg, err := model.GetStrmConfigByID(db, configID)
+	if err != nil || cfg == nil {
+		return nil, ErrPlayConfigNotFound
+	}
+	return cfg, nil
+}
+
+// ResolvePlayURL 解析播放链接对应的当前直链，短时间内重复请求直接使用缓存
+func ResolvePlayURL(ctx context.Context, db *gorm.DB, cfg *model.StrmConfig, relPath string) (string, error) {
+	relPath = strings.TrimPrefix(relPath, "/")
+	// 拒绝 .. 等跳出 alistBasePath 的路径
+	if relPath == "" || path.Clean("/"+relPath) != "/"+relPath {
+		return "", ErrPlayPathInvalid
+	}
+	configID := cfg.ID
+	key := playCacheKey(configID, relPath)
+	if url, ok := getPlayCache(key); ok {
+		return url, nil
+	}
+	client, _, err := NewOpenListClientForConfig(db, cfg)
+	if err != nil {
+		return "", err
+	}
+	remotePath := path.Join("/", cfg.AlistBasePath, relPath)
+	resp, err := client.Get(ctx, &openlist.GetReq{Path: remotePath})
+	if err != nil {
+		logger.Error("[Service] ResolvePlayURL 获取直链失败", zap.Int("config_id", configID), zap.String("path", remotePath), zap.Error(err))
+		return "", wrapRemotePathError(err)
+	}
+	if resp.IsDir {
+		return "", ErrPlayPathInvalid
+	}
+	url := resp.RawURL
+	// 部分存储不返回 raw_url（如仅支持本地代理），退回 OpenList /d/ 直链
+	if url == "" {
+		url = strm.DirectURL(client.BaseURL(), remotePath, resp.Sign)
+	}
+	setPlayCache(key, url)
+	return url, nil
+}
+
+func playCacheKey(configID int, relPath string) string {
+	return strconv.Itoa(configID) + ":" + strings.TrimPrefix(relPath, "/")
+}
+
+// invalidatePlayCache 直链失效（如上游返回 403）时清除缓存
+func invalidatePlayCache(configID int, relPath string) {
+	playCacheMutex.Lock()
+	defer playCacheMutex.Unlock()
+	delete(playCache, playCacheKey(configID, relPath))
+}
+
+func getPlayCache(key string) (string, bool) {
+	playCacheMutex.Lock()
+	defer playCacheMutex.Unlock()
+	entry, ok := playCache[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return "", false
+	}
+	return entry.url, true
+}
+
+func setPlayCache(key, url string) {
+	playCacheMutex.Lock()
+	defer playCacheMutex.Unlock()
+	now := time.Now()
+	if len(playCache) >= playCacheMaxSize {
+		for k, v := range playCache {
+			if now.After(v.expiresAt) {
+				delete(playCache, k)
+			}
+		}
+		if len(playCache) >= playCacheMaxSize {
+			playCache = make(map[string]*playCacheEntry)
+		}
+	}
+	playCache[key] = &playCacheEntry{url: url, expiresAt: now.Add(playCacheTTL)}
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/strm"
+)
+
+// 仅用于进度推送的任务状态，其余状态与运行日志一致
+const (
+	TaskStatusQueued model.TaskStatus = "queued" // 已加入队列，等待执行
+	TaskStatusIdle   model.TaskStatus = "idle"   // 服务启动后尚未执行过
+)
+
+// StrmTaskProgress 任务的最新进度，Progress 在任务开始执行前为空，结束后保留最后一次的进度
+type StrmTaskProgress struct {
+	TaskID    int              `json:"taskId"`
+	LogID     int              `json:"logId"`
+	Status    model.TaskStatus `json:"status"`
+	Progress  *strm.Progress   `json:"progress"`
+	UpdatedAt time.Time        `json:"updatedAt"`
+}
+
+// Finished 是否为一次执行的最终状态，暂停的任务仍可恢复，不算结束
+func (p StrmTaskProgress) Finished() bool {
+	switch p.Status {
+	case model.TaskStatusCompleted, model.TaskStatusError, model.TaskStatusCancelled, model.TaskStatusSkipped:
+		return true
+	}
+	return false
+}
+
+// strmProgressHub 保存各任务的最新进度并推送给订阅者。订阅通道容量为 1，
+// 订阅者来不及读取时只保留最新一条，慢连接不会阻塞任务执行
+type strmProgressHub struct {
+	latest      map[int]*StrmTaskProgress
+	subscribers map[int]map[chan StrmTaskProgress]struct{}
+	mutex       sync.Mutex
+}
+
+var strmProgress = &strmProgressHub{
+	latest:      make(map[int]*StrmTaskProgress),
+	subscribers: make(map[int]map[chan StrmTaskProgress]struct{}),
+}
+
+// publishStrmTaskProgress 更新任务状态并推送；progress 为空时沿用同一次执行的上一次进度
+func publishStrmTaskProgress(taskID, logID int, status model.TaskStatus, progress *strm.Progress) {
+	if taskID <= 0 {
+		return
+	}
+	h := strmProgress
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if progress == nil {
+		if prev := h.latest[taskID]; prev != nil && prev.LogID == logID {
+			progress = prev.Progress
+		}
+	}
+	p := &StrmTaskProgress{
+		TaskID:    taskID,
+		LogID:     logID,
+		Status:    status,
+		Progress:  progress,
+		UpdatedAt: time.Now(),
+	}
+	h.latest[taskID] = p
+	for ch := range h.subscribers[taskID] {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- *p
+	}
+}
+
+// finishStrmTaskProgress 推送执行结束的状态，执行出错时将错误记为进度的最后一个错误
+func finishStrmTaskProgress(taskID, logID int, status model.TaskStatus, runErr error) {
+	if status != model.TaskStatusError || runErr == nil {
+		publishStrmTaskProgress(taskID, logID, status, nil)
+		return
+	}
+	var progress strm.Progress
+	if prev := GetStrmTaskProgress(taskID); prev.LogID == logID && prev.Progress != nil {
+		progress = *prev.Progress
+	}
+	progress.LastError = runErr.Error()
+	progress.EtaSeconds = -1
+	publishStrmTaskProgress(taskID, logID, status, &progress)
+}
+
+// strmProgressOption 生成器进度回调，推送为执行中状态
+func strmProgressOption(taskID, logID int) strm.Option {
+	return strm.WithProgress(func(p strm.Progress) {
+		publishStrmTaskProgress(taskID, logID, model.TaskStatusRunning, &p)
+	})
+}
+
+// GetStrmTaskProgress 任务的最新进度，服务启动后尚未执行过时返回 idle 状态
+func GetStrmTaskProgress(taskID int) StrmTaskProgress {
+	h := strmProgress
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if p := h.latest[taskID]; p != nil {
+		return *p
+	}
+	return StrmTaskProgress{TaskID: taskID, Status: TaskStatusIdle}
+}
+
+// SubscribeStrmTaskProgress 订阅任务进度，返回的通道先收到当前进度；不再需要时须调用 unsubscribe
+func SubscribeStrmTaskProgress(taskID int) (<-chan StrmTaskProgress, func()) {
+	h := strmProgress
+	ch := make(chan StrmTaskProgress, 1)
+	h.mutex.Lock()
+	if h.subscribers[taskID] == nil {
+		h.subscribers[taskID] = make(map[chan StrmTaskProgress]struct{})
+	}
+	h.subscribers[taskID][ch] = struct{}{}
+	if p := h.latest[taskID]; p != nil {
+		ch <- *p
+	} else {
+		ch <- StrmTaskProgress{TaskID: taskID, Status: TaskStatusIdle}
+	}
+	h.mutex.Unlock()
+	return ch, func() {
+		h.mutex.Lock()
+		delete(h.subscribers[taskID], ch)
+		if len(h.subscribers[taskID]) == 0 {
+			delete(h.subscribers, taskID)
+		}
+		h.mutex.Unlock()
+	}
+}
+
+// removeStrmTaskProgress 任务删除后清除保存的进度
+func removeStrmTaskProgress(taskID int) {
+	h := strmProgress
+	h.mutex.Lock()
+	delete(h.latest, taskID)
+	h.mutex.Unlock()
+}
+
+// progressTicketTTL 进度订阅凭证的有效期
+const progressTicketTTL = 30 * time.Second
+
+// progressTicket 进度订阅凭证，EventSource 无法设置请求头，用绑定任务和用户的短期凭证代替 accessToken 放在查询参数中；
+// 有效期内可重复使用，便于 EventSource 断线自动重连
+type progressTicket struct {
+	userID    int
+	taskID    int
+	expiresAt time.Time
+}
+
+var (
+	progressTickets      = make(map[string]progressTicket)
+	progressTicketsMutex sync.Mutex
+)
+
+// IssueStrmProgressTicket 为用户签发订阅任务进度的凭证
+func IssueStrmProgressTicket(userID, taskID int) (string, time.Time) {
+	b := make([]byte, 16)
+	rand.Read(b)
+	ticket := hex.EncodeToString(b)
+	now := time.Now()
+	expiresAt := now.Add(progressTicketTTL)
+	progressTicketsMutex.Lock()
+	defer progressTicketsMutex.Unlock()
+	for k, t := range progressTickets {
+		if now.After(t.expiresAt) {
+			delete(progressTickets, k)
+		}
+	}
+	progressTickets[ticket] = progressTicket{userID: userID, taskID: taskID, expiresAt: expiresAt}
+	return ticket, expiresAt
+}
+
+// RedeemStrmProgressTicket 校验凭证，返回签发时的用户 ID；凭证不存在、已过期或任务不符时返回 false。
+// 凭证在有效期内可重复使用，只在过期后移除
+func RedeemStrmProgressTicket(ticket string, taskID int) (int, bool) {
+	progressTicketsMutex.Lock()
+	defer progressTicketsMutex.Unlock()
+	t, ok := progressTickets[ticket]
+	if !ok {
+		return 0, false
+	}
+	if time.Now().After(t.expiresAt) {
+		delete(progressTickets, ticket)
+		return 0, false
+	}
+	if t.taskID != taskID {
+		return 0, false
+	}
+	return t.userID, true
+}
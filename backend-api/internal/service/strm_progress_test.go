@@ -0,0 +1,41 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStrmProgressTicket(t *testing.T) {
+	ticket, _ := IssueStrmProgressTicket(7, 42)
+
+	// 任务不符时拒绝，但不作废凭证
+	if _, ok := RedeemStrmProgressTicket(ticket, 43); ok {
+		t.Error("任务不符的凭证应被拒绝")
+	}
+	// 有效期内可重复使用
+	for i := 0; i < 2; i++ {
+		userID, ok := RedeemStrmProgressTicket(ticket, 42)
+		if !ok || userID != 7 {
+			t.Fatalf("第 %d 次使用: userID = %d, ok = %v", i+1, userID, ok)
+		}
+	}
+	if _, ok := RedeemStrmProgressTicket("unknown", 42); ok {
+		t.Error("不存在的凭证应被拒绝")
+	}
+
+	// 过期后拒绝并移除
+	progressTicketsMutex.Lock()
+	tk := progressTickets[ticket]
+	tk.expiresAt = time.Now().Add(-time.Second)
+	progressTickets[ticket] = tk
+	progressTicketsMutex.Unlock()
+	if _, ok := RedeemStrmProgressTicket(ticket, 42); ok {
+		t.Error("过期的凭证应被拒绝")
+	}
+	progressTicketsMutex.Lock()
+	_, exists := progressTickets[ticket]
+	progressTicketsMutex.Unlock()
+	if exists {
+		t.Error("过期的凭证应被移除")
+	}
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/util"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const proxyBufferSize = 32 * 1024
+
+var (
+	ErrTooManyStreams = errors.New("同时播放的流数已达上限")
+	ErrUpstream       = errors.New("上游返回错误")
+)
+
+// proxyClient 代理播放共用的 HTTP 客户端，复用到上游的连接
+var proxyClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   15 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   16,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+	},
+}
+
+// proxyRequestHeaders 透传给上游的客户端请求头
+var proxyRequestHeaders = []string{"Range", "If-Range", "If-Modified-Since", "If-None-Match", "User-Agent", "Accept"}
+
+// proxyResponseHeaders 透传给客户端的上游响应头
+var proxyResponseHeaders = []string{
+	"Content-Type", "Content-Length", "Content-Range", "Accept-Ranges",
+	"Last-Modified", "ETag", "Content-Disposition", "Cache-Control",
+}
+
+// streamLimit 单个配置的代理限制及其活动流数
+type streamLimit struct {
+	maxStreams int
+	rate       int
+	active     int
+}
+
+// userStreams 单个用户的代理流状态，该用户所有配置的流共用一个计数和限速器。
+// 多个配置同时播放时，流数和带宽各取这些配置中最小的非零值，0 表示不限
+type userStreams struct {
+	active  int
+	configs map[int]*streamLimit
+	limiter *util.RateLimiter
+}
+
+// limits 计算有活动流的配置的最严限制，跳过 excludeID 对应的配置
+func (us *userStreams) limits(excludeID int) (maxStreams, rate int) {
+	for id, l := range us.configs {
+		if id == excludeID {
+			continue
+		}
+		maxStreams = minLimit(maxStreams, l.maxStreams)
+		rate = minLimit(rate, l.rate)
+	}
+	return maxStreams, rate
+}
+
+// minLimit 返回两个限制中较小的非零值，0 表示不限
+func minLimit(a, b int) int {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+var (
+	proxyUsers      = make(map[int]*userStreams)
+	proxyUsersMutex sync.Mutex
+)
+
+// ParseProxyHeaders 解析配置中的附加请求头，空字符串返回 nil
+func ParseProxyHeaders(raw string) (map[string]string, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	headers := map[string]string{}
+	if err := json.Unmarshal([]byte(raw), &headers); err != nil {
+		return nil, err
+	}
+	return headers, nil
+}
+
+// acquireStream 占用所属用户的一个流名额，并按各配置中最严的限制更新带宽。
+// 本次请求的配置以最新的设置参与计算
+func acquireStream(cfg *model.StrmConfig) (*util.RateLimiter, func(), error) {
+	proxyUsersMutex.Lock()
+	defer proxyUsersMutex.Unlock()
+	us, ok := proxyUsers[cfg.UserID]
+	if !ok {
+		us = &userStreams{configs: map[int]*streamLimit{}, limiter: util.NewRateLimiter(0, 1)}
+		proxyUsers[cfg.UserID] = us
+	}
+	maxStreams, rate := us.limits(cfg.ID)
+	maxStreams = minLimit(maxStreams, cfg.ProxyMaxStreams)
+	rate = minLimit(rate, cfg.ProxyRateLimit)
+	if maxStreams > 0 && us.active >= maxStreams {
+		return nil, nil, ErrTooManyStreams
+	}
+	l, ok := us.configs[cfg.ID]
+	if !ok {
+		l = &streamLimit{}
+		us.configs[cfg.ID] = l
+	}
+	l.maxStreams, l.rate = cfg.ProxyMaxStreams, cfg.ProxyRateLimit
+	l.active++
+	us.active++
+	us.limiter.SetRate(float64(rate*1024), rate*1024)
+	release := func() {
+		proxyUsersMutex.Lock()
+		defer proxyUsersMutex.Unlock()
+		l.active--
+		if l.active == 0 {
+			delete(us.configs, cfg.ID)
+		}
+		us.active--
+		if us.active == 0 {
+			delete(proxyUsers, cfg.UserID)
+			return
+		}
+		// 限制最严的配置结束播放后放宽带宽
+		_, rate := us.limits(0)
+		us.limiter.SetRate(float64(rate*1024), rate*1024)
+	}
+	return us.limiter, release, nil
+}
+
+// ProxyPlay 以反向代理方式输出文件，支持 Range；直链失效时刷新一次后重试。
+// 仅在写出响应头之前返回错误，由调用方返回错误状态
+func ProxyPlay(db *gorm.DB, cfg *model.StrmConfig, relPath string, w http.ResponseWriter, r *http.Request) error {
+	limiter, release, err := acquireStream(cfg)
+	if err != nil {
+		return err
+	}
+	defer release()
+	headers, _ := ParseProxyHeaders(cfg.ProxyHeaders)
+
+	var resp *http.Response
+	for attempt := 0; attempt < 2; attempt++ {
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		url, err := ResolvePlayURL(ctx, db, cfg, relPath)
+		cancel()
+		if err != nil {
+			return err
+		}
+		resp, err = openUpstream(r, url, headers)
+		if err != nil {
+			return err
+		}
+		// 缓存的直链可能已过期，刷新后重试一次
+		if attempt == 0 && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusGone) {
+			resp.Body.Close()
+			invalidatePlayCache(cfg.ID, relPath)
+			continue
+		}
+		break
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		logger.Error("[Service] ProxyPlay 上游返回错误", zap.Int("config_id", cfg.ID), zap.String("path", relPath), zap.Int("status", resp.StatusCode))
+		return ErrUpstream
+	}
+
+	for _, h := range proxyResponseHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			w.Header().Set(h, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	if r.Method == http.MethodHead {
+		return nil
+	}
+	// 响应头已写出，传输中断（多为客户端拖动进度或关闭播放）只记录日志
+	if err := copyWithLimit(r.Context(), w, resp.Body, limiter); err != nil {
+		logger.Info("[Service] ProxyPlay 传输中断", zap.Int("config_id", cfg.ID), zap.String("path", relPath), zap.Error(err))
+	}
+	return nil
+}
+
+// openUpstream 请求上游直链，透传 Range 等请求头并附加配置的请求头
+func openUpstream(r *http.Request, url string, headers map[string]string) (*http.Response, error) {
+	method := http.MethodGet
+	if r.Method == http.MethodHead {
+		method = http.MethodHead
+	}
+	req, err := http.NewRequestWithContext(r.Context(), method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range proxyRequestHeaders {
+		if v := r.Header.Get(h); v != "" {
+			req.Header.Set(h, v)
+		}
+	}
+	// 禁止传输层自动 gzip，保证 Content-Length/Content-Range 与原文件一致
+	req.Header.Set("Accept-Encoding", "identity")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return proxyClient.Do(req)
+}
+
+// copyWithLimit 按用户带宽限速复制数据，客户端断开时结束
+func copyWithLimit(ctx context.Context, w io.Writer, body io.Reader, limiter *util.RateLimiter) error {
+	buf := make([]byte, proxyBufferSize)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if err := limiter.WaitN(ctx, n); err != nil {
+				return err
+			}
+			if _, err := w.Write(buf[:n]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
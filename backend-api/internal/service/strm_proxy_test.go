@@ -0,0 +1,79 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+)
+
+func userStreamState(userID int) (active int, ok bool) {
+	proxyUsersMutex.Lock()
+	defer proxyUsersMutex.Unlock()
+	us, ok := proxyUsers[userID]
+	if !ok {
+		return 0, false
+	}
+	return us.active, true
+}
+
+func TestAcquireStreamPerUser(t *testing.T) {
+	a := &model.StrmConfig{ID: 101, UserID: 1, ProxyMaxStreams: 3, ProxyRateLimit: 0}
+	b := &model.StrmConfig{ID: 102, UserID: 1, ProxyMaxStreams: 2, ProxyRateLimit: 100}
+	other := &model.StrmConfig{ID: 103, UserID: 2, ProxyMaxStreams: 1}
+
+	limiterA, releaseA, err := acquireStream(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiterB, releaseB, err := acquireStream(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limiterA != limiterB {
+		t.Fatal("同一用户的配置应共用限速器")
+	}
+	if active, _ := userStreamState(1); active != 2 {
+		t.Errorf("active = %d, want 2", active)
+	}
+	// a 允许 3 个流，但 b 正在播放，取较小的 2
+	if _, _, err := acquireStream(a); !errors.Is(err, ErrTooManyStreams) {
+		t.Errorf("超出最严的流数上限应报错, err = %v", err)
+	}
+	// 其他用户不受影响
+	_, releaseOther, err := acquireStream(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	releaseOther()
+	if _, ok := userStreamState(2); ok {
+		t.Error("没有活动流的用户应释放状态")
+	}
+
+	// b 结束后只剩 a 的限制
+	releaseB()
+	_, releaseA2, err := acquireStream(a)
+	if err != nil {
+		t.Fatalf("b 结束后应按 a 的上限放行: %v", err)
+	}
+	releaseA2()
+	releaseA()
+	if _, ok := userStreamState(1); ok {
+		t.Error("没有活动流的用户应释放状态")
+	}
+}
+
+func TestMinLimit(t *testing.T) {
+	cases := []struct{ a, b, want int }{
+		{0, 0, 0},
+		{0, 5, 5},
+		{5, 0, 5},
+		{3, 5, 3},
+		{5, 3, 3},
+	}
+	for _, c := range cases {
+		if got := minLimit(c.a, c.b); got != c.want {
+			t.Errorf("minLimit(%d, %d) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
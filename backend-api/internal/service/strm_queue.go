@@ -0,0 +1,349 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/strm"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultQueueWorkers = 2
+	defaultQueueSize    = 100
+)
+
+var (
+	ErrQueueFull     = errors.New("任务队列已满")
+	ErrQueueNotReady = errors.New("任务队列未启动")
+	ErrConfigBusy    = errors.New("该配置有任务正在执行")
+)
+
+// JobPriority 队列优先级，数值大的先执行
+type JobPriority int
+
+const (
+	JobPriorityScheduled JobPriority = 0
+	JobPriorityManual    JobPriority = 10
+)
+
+func (p JobPriority) String() string {
+	if p >= JobPriorityManual {
+		return "manual"
+	}
+	return "scheduled"
+}
+
+// JobState 队列中任务的状态
+type JobState string
+
+const (
+	JobStateQueued  JobState = "queued"
+	JobStateRunning JobState = "running"
+)
+
+// StrmQueueJob 一次排队的任务执行
+type StrmQueueJob struct {
+	ID         int64
+	Task       model.StrmTask
+	Priority   JobPriority
+	State      JobState
+	EnqueuedAt time.Time
+	StartedAt  time.Time
+	// serviceLimit 入队时读取的服务任务并发数
+	serviceLimit int
+	// run 不属于已保存任务的执行（试运行、手动生成），Task 只用于展示以及服务、配置的并发控制
+	run func(ctx context.Context) error
+	// ctx 开始执行时创建，通过 cancel 传入取消原因暂停或取消执行
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+}
+
+// StrmTaskQueue 任务执行队列：固定数量的 worker，按服务限制并发，同一配置同一时间只执行一个任务
+type StrmTaskQueue struct {
+	db      *gorm.DB
+	workers int
+	size    int
+	seq     int64
+	queued  []*StrmQueueJob
+	running map[int64]*StrmQueueJob
+	// serviceRunning 各服务正在执行的任务数
+	serviceRunning map[int]int
+	mutex          sync.Mutex
+	cond           *sync.Cond
+}
+
+var (
+	strmTaskQueue     *StrmTaskQueue
+	strmTaskQueueOnce sync.Once
+)
+
+// StartStrmTaskQueue 启动任务队列（单例），worker 数由环境变量 STRM_QUEUE_WORKERS 配置，
+// 排队上限由 STRM_QUEUE_SIZE 配置
+func StartStrmTaskQueue(db *gorm.DB) *StrmTaskQueue {
+	strmTaskQueueOnce.Do(func() {
+		workers := envPositiveInt("STRM_QUEUE_WORKERS", defaultQueueWorkers)
+		size := envPositiveInt("STRM_QUEUE_SIZE", defaultQueueSize)
+		strmTaskQueue = newStrmTaskQueue(db, workers, size)
+		logger.Info("[Queue] 启动", zap.Int("workers", workers), zap.Int("size", size))
+	})
+	return strmTaskQueue
+}
+
+// GetStrmTaskQueue 获取任务队列，未启动时返回 nil
+func GetStrmTaskQueue() *StrmTaskQueue {
+	return strmTaskQueue
+}
+
+func envPositiveInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		logger.Error("[Queue] 环境变量格式错误，使用默认值", zap.String("key", key), zap.String("value", v))
+		return def
+	}
+	return n
+}
+
+func newStrmTaskQueue(db *gorm.DB, workers, size int) *StrmTaskQueue {
+	q := &StrmTaskQueue{
+		db:             db,
+		workers:        workers,
+		size:           size,
+		running:        make(map[int64]*StrmQueueJob),
+		serviceRunning: make(map[int]int),
+	}
+	q.cond = sync.NewCond(&q.mutex)
+	for i := 0; i < workers; i++ {
+		go q.work()
+	}
+	return q
+}
+
+// Enqueue 将任务加入队列。同一配置已有相同模式的执行在排队时不重复入队，返回已有的 job 和 true；
+// 手动执行会将已排队的定时执行提升为手动优先级。生成、检查和试运行按模式分别去重，互不合并，执行时由配置锁保证互斥
+func (q *StrmTaskQueue) Enqueue(task *model.StrmTask, priority JobPriority) (*StrmQueueJob, bool, error) {
+	return q.enqueue(task, priority, nil)
+}
+
+// EnqueueFunc 将不属于已保存任务的执行加入队列，与任务一样受服务并发数和配置锁限制，去重规则同 Enqueue；
+// task 只需填写名称、模式、用户、服务和配置
+func (q *StrmTaskQueue) EnqueueFunc(task *model.StrmTask, priority JobPriority, run func(ctx context.Context) error) (*StrmQueueJob, bool, error) {
+	return q.enqueue(task, priority, run)
+}
+
+// queueMode 去重使用的执行模式，未设置模式的旧任务按生成处理
+func queueMode(task *model.StrmTask) model.TaskMode {
+	if task.TaskMode == "" {
+		return model.TaskModeCreate
+	}
+	return task.TaskMode
+}
+
+func (q *StrmTaskQueue) enqueue(task *model.StrmTask, priority JobPriority, run func(ctx context.Context) error) (*StrmQueueJob, bool, error) {
+	limit := 1
+	if svc, err := model.GetOpenListServiceByID(q.db, task.ServiceID); err == nil && svc.TaskConcurrency > 0 {
+		limit = svc.TaskConcurrency
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for _, job := range q.queued {
+		if job.Task.ConfigID == task.ConfigID && queueMode(&job.Task) == queueMode(task) {
+			if priority > job.Priority {
+				job.Priority = priority
+			}
+			logger.Info("[Queue] 配置已在队列中，忽略重复执行", zap.Int("task_id", task.ID), zap.Int("config_id", task.ConfigID), zap.String("mode", string(queueMode(task))), zap.Int64("job_id", job.ID))
+			return job, true, nil
+		}
+	}
+	if len(q.queued) >= q.size {
+		return nil, false, ErrQueueFull
+	}
+	q.seq++
+	job := &StrmQueueJob{
+		ID:           q.seq,
+		Task:         *task,
+		Priority:     priority,
+		State:        JobStateQueued,
+		EnqueuedAt:   time.Now(),
+		serviceLimit: limit,
+		run:          run,
+	}
+	q.queued = append(q.queued, job)
+	logger.Info("[Queue] 入队", zap.Int64("job_id", job.ID), zap.Int("task_id", task.ID), zap.String("priority", priority.String()))
+	if run == nil {
+		publishStrmTaskProgress(task.ID, 0, TaskStatusQueued, nil)
+	}
+	q.cond.Broadcast()
+	return job, false, nil
+}
+
+// Jobs 当前执行中和排队中的任务，执行中的在前，排队的按执行顺序排列
+func (q *StrmTaskQueue) Jobs() []StrmQueueJob {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	jobs := make([]StrmQueueJob, 0, len(q.running)+len(q.queued))
+	for _, job := range q.running {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].StartedAt.Before(jobs[j].StartedAt) })
+	for _, job := range q.ordered() {
+		jobs = append(jobs, *job)
+	}
+	return jobs
+}
+
+// ordered 按优先级和入队顺序排列的排队任务，调用方须持有锁
+func (q *StrmTaskQueue) ordered() []*StrmQueueJob {
+	jobs := append([]*StrmQueueJob(nil), q.queued...)
+	sort.SliceStable(jobs, func(i, j int) bool {
+		if jobs[i].Priority != jobs[j].Priority {
+			return jobs[i].Priority > jobs[j].Priority
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+	return jobs
+}
+
+// next 取出下一个可执行的任务：服务并发未满且配置空闲，调用方须持有锁
+func (q *StrmTaskQueue) next() *StrmQueueJob {
+	for _, job := range q.ordered() {
+		if q.serviceRunning[job.Task.ServiceID] >= job.serviceLimit {
+			continue
+		}
+		if !tryLockStrmConfig(job.Task.ConfigID) {
+			continue
+		}
+		for i, v := range q.queued {
+			if v == job {
+				q.queued = append(q.queued[:i], q.queued[i+1:]...)
+				break
+			}
+		}
+		job.State = JobStateRunning
+		job.StartedAt = time.Now()
+		job.ctx, job.cancel = context.WithCancelCause(context.Background())
+		q.running[job.ID] = job
+		q.serviceRunning[job.Task.ServiceID]++
+		return job
+	}
+	return nil
+}
+
+func (q *StrmTaskQueue) work() {
+	for {
+		q.mutex.Lock()
+		job := q.next()
+		for job == nil {
+			q.cond.Wait()
+			job = q.next()
+		}
+		q.mutex.Unlock()
+
+		q.execute(job)
+		job.cancel(nil)
+
+		q.mutex.Lock()
+		delete(q.running, job.ID)
+		q.serviceRunning[job.Task.ServiceID]--
+		unlockStrmConfig(job.Task.ConfigID)
+		q.cond.Broadcast()
+		q.mutex.Unlock()
+	}
+}
+
+func (q *StrmTaskQueue) execute(job *StrmQueueJob) {
+	logger.Info("[Queue] 开始执行", zap.Int64("job_id", job.ID), zap.Int("task_id", job.Task.ID), zap.Duration("waited", job.StartedAt.Sub(job.EnqueuedAt)))
+	run := job.run
+	if run == nil {
+		run = func(ctx context.Context) error { return ExecuteStrmTask(ctx, q.db, &job.Task) }
+	}
+	if err := run(job.ctx); err != nil {
+		logger.Error("[Queue] 任务执行失败", zap.Int64("job_id", job.ID), zap.Int("task_id", job.Task.ID), zap.Error(err))
+	}
+}
+
+// Stop 停止任务：排队中的直接移出队列，执行中的以 cause 为原因取消其 context；任务不在队列中时返回 false
+func (q *StrmTaskQueue) Stop(taskID int, cause error) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for _, job := range q.running {
+		if job.run == nil && job.Task.ID == taskID {
+			logger.Info("[Queue] 停止执行", zap.Int64("job_id", job.ID), zap.Int("task_id", taskID), zap.Error(cause))
+			job.cancel(cause)
+			return true
+		}
+	}
+	// 排队中的任务还没有进度可保存，暂停不适用
+	if !errors.Is(cause, strm.ErrCancelled) {
+		return false
+	}
+	for i, job := range q.queued {
+		if job.run == nil && job.Task.ID == taskID {
+			logger.Info("[Queue] 移出队列", zap.Int64("job_id", job.ID), zap.Int("task_id", taskID))
+			q.queued = append(q.queued[:i], q.queued[i+1:]...)
+			publishStrmTaskProgress(taskID, 0, model.TaskStatusCancelled, nil)
+			return true
+		}
+	}
+	return false
+}
+
+// EnqueueStrmTask 将任务加入全局队列
+func EnqueueStrmTask(task *model.StrmTask, priority JobPriority) (*StrmQueueJob, bool, error) {
+	q := GetStrmTaskQueue()
+	if q == nil {
+		return nil, false, ErrQueueNotReady
+	}
+	return q.Enqueue(task, priority)
+}
+
+// busyConfigs 正在生成或检查的配置，队列任务和手动生成/检查共用，保证同一输出目录不会被并发写入
+var (
+	busyConfigs      = make(map[int]bool)
+	busyConfigsMutex sync.Mutex
+)
+
+func tryLockStrmConfig(configID int) bool {
+	busyConfigsMutex.Lock()
+	defer busyConfigsMutex.Unlock()
+	if busyConfigs[configID] {
+		return false
+	}
+	busyConfigs[configID] = true
+	return true
+}
+
+func unlockStrmConfig(configID int) {
+	busyConfigsMutex.Lock()
+	delete(busyConfigs, configID)
+	busyConfigsMutex.Unlock()
+}
+
+// WithStrmConfigLock 在配置空闲时执行 fn，配置有任务正在执行时返回 ErrConfigBusy
+func WithStrmConfigLock(configID int, fn func() error) error {
+	if !tryLockStrmConfig(configID) {
+		return ErrConfigBusy
+	}
+	defer func() {
+		unlockStrmConfig(configID)
+		// 唤醒等待该配置的排队任务
+		if q := GetStrmTaskQueue(); q != nil {
+			q.mutex.Lock()
+			q.cond.Broadcast()
+			q.mutex.Unlock()
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,363 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/strm"
+)
+
+// waitUntil 等待 cond 成立
+func waitUntil(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("等待超时: %s", what)
+}
+
+// blockQueue 占住队列的全部 worker，返回释放函数
+func blockQueue(t *testing.T, q *StrmTaskQueue, workers int) func() {
+	t.Helper()
+	release := make(chan struct{})
+	for i := 0; i < workers; i++ {
+		task := &model.StrmTask{Name: "blocker", ServiceID: 9000 + i, ConfigID: 9000 + i}
+		if _, _, err := q.EnqueueFunc(task, JobPriorityManual, func(ctx context.Context) error {
+			<-release
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitUntil(t, "占住 worker", func() bool { return runningJobs(q) == workers })
+	var once sync.Once
+	return func() { once.Do(func() { close(release) }) }
+}
+
+func runningJobs(q *StrmTaskQueue) int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.running)
+}
+
+func TestStrmQueuePriorityOrder(t *testing.T) {
+	db := newTestDB(t)
+	q := useTestQueue(t, db, 1)
+	release := blockQueue(t, q, 1)
+	defer release()
+
+	var mutex sync.Mutex
+	var order []string
+	enqueue := func(name string, configID int, priority JobPriority) {
+		task := &model.StrmTask{Name: name, ServiceID: 1, ConfigID: configID}
+		if _, _, err := q.EnqueueFunc(task, priority, func(ctx context.Context) error {
+			mutex.Lock()
+			order = append(order, name)
+			mutex.Unlock()
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	enqueue("scheduled1", 1, JobPriorityScheduled)
+	enqueue("manual1", 2, JobPriorityManual)
+	enqueue("scheduled2", 3, JobPriorityScheduled)
+	enqueue("manual2", 4, JobPriorityManual)
+
+	want := []string{"manual1", "manual2", "scheduled1", "scheduled2"}
+	jobs := q.Jobs()
+	if len(jobs) != 5 || jobs[0].State != JobStateRunning {
+		t.Fatalf("jobs = %+v", jobs)
+	}
+	for i, name := range want {
+		if jobs[i+1].Task.Name != name || jobs[i+1].State != JobStateQueued {
+			t.Errorf("Jobs()[%d] = %s %s, want %s", i+1, jobs[i+1].Task.Name, jobs[i+1].State, name)
+		}
+	}
+
+	release()
+	waitUntil(t, "全部执行完", func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(order) == len(want)
+	})
+	if !equalNames(order, want) {
+		t.Errorf("执行顺序 = %v, want %v", order, want)
+	}
+}
+
+func equalNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestStrmQueueDedupAndPromote(t *testing.T) {
+	db := newTestDB(t)
+	q := useTestQueue(t, db, 1)
+	release := blockQueue(t, q, 1)
+	defer release()
+
+	task := &model.StrmTask{ID: 42, Name: "task", ServiceID: 1, ConfigID: 1, TaskMode: model.TaskModeCreate}
+	first, duplicated, err := q.Enqueue(task, JobPriorityScheduled)
+	if err != nil || duplicated {
+		t.Fatalf("err = %v, duplicated = %v", err, duplicated)
+	}
+	if p := GetStrmTaskProgress(task.ID); p.Status != TaskStatusQueued {
+		t.Errorf("入队后进度 = %s", p.Status)
+	}
+	second, duplicated, err := q.Enqueue(task, JobPriorityManual)
+	if err != nil || !duplicated || second != first {
+		t.Fatalf("重复入队应返回已有的 job: err = %v, duplicated = %v", err, duplicated)
+	}
+	if first.Priority != JobPriorityManual {
+		t.Errorf("手动执行应提升优先级，priority = %s", first.Priority)
+	}
+	run := func(ctx context.Context) error { return nil }
+	// 手动生成同样按配置去重
+	third, duplicated, err := q.EnqueueFunc(&model.StrmTask{ServiceID: 1, ConfigID: 1}, JobPriorityManual, run)
+	if err != nil || !duplicated || third != first {
+		t.Fatalf("同一配置的手动生成应返回已有的 job: err = %v, duplicated = %v", err, duplicated)
+	}
+	// 同一配置的不同模式、不同配置分别入队
+	for _, other := range []*model.StrmTask{
+		{ServiceID: 1, ConfigID: 1, TaskMode: model.TaskModeCheck},
+		{ServiceID: 1, ConfigID: 1, TaskMode: TaskModeDryRun},
+		{ServiceID: 1, ConfigID: 2},
+	} {
+		if _, duplicated, err := q.EnqueueFunc(other, JobPriorityManual, run); err != nil || duplicated {
+			t.Fatalf("config %d %s: err = %v, duplicated = %v", other.ConfigID, other.TaskMode, err, duplicated)
+		}
+	}
+	if n := len(q.Jobs()); n != 5 {
+		t.Errorf("jobs = %d, want 5", n)
+	}
+	if !q.Stop(task.ID, strm.ErrCancelled) {
+		t.Fatal("排队中的任务应能取消")
+	}
+}
+
+func TestStrmQueueFull(t *testing.T) {
+	db := newTestDB(t)
+	q := newStrmTaskQueue(db, 1, 2)
+	release := blockQueue(t, q, 1)
+	defer release()
+	run := func(ctx context.Context) error { return nil }
+	for i := 0; i < 2; i++ {
+		if _, _, err := q.EnqueueFunc(&model.StrmTask{ServiceID: 1, ConfigID: i + 1}, JobPriorityManual, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := q.EnqueueFunc(&model.StrmTask{ServiceID: 1, ConfigID: 3}, JobPriorityManual, run); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("err = %v, want ErrQueueFull", err)
+	}
+}
+
+func TestStrmQueueServiceLimit(t *testing.T) {
+	db := newTestDB(t)
+	limited := &model.OpenListService{UserID: 1, Name: "limited", ServiceUrl: "http://a", Enabled: true, TaskConcurrency: 2}
+	single := &model.OpenListService{UserID: 1, Name: "single", ServiceUrl: "http://b", Enabled: true}
+	for _, svc := range []*model.OpenListService{limited, single} {
+		if err := db.Create(svc).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	q := useTestQueue(t, db, 4)
+
+	var mutex sync.Mutex
+	running := make(map[int]int)
+	peak := make(map[int]int)
+	total, peakTotal := 0, 0
+	var wg sync.WaitGroup
+	enqueue := func(serviceID, configID int) {
+		wg.Add(1)
+		task := &model.StrmTask{ServiceID: serviceID, ConfigID: configID}
+		if _, _, err := q.EnqueueFunc(task, JobPriorityManual, func(ctx context.Context) error {
+			defer wg.Done()
+			mutex.Lock()
+			running[serviceID]++
+			total++
+			peak[serviceID] = max(peak[serviceID], running[serviceID])
+			peakTotal = max(peakTotal, total)
+			mutex.Unlock()
+			time.Sleep(50 * time.Millisecond)
+			mutex.Lock()
+			running[serviceID]--
+			total--
+			mutex.Unlock()
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 4; i++ {
+		enqueue(limited.ID, 100+i)
+	}
+	for i := 0; i < 2; i++ {
+		enqueue(single.ID, 200+i)
+	}
+	wg.Wait()
+	if peak[limited.ID] != 2 {
+		t.Errorf("limited 最大并发 = %d, want 2", peak[limited.ID])
+	}
+	// 未设置并发数的服务默认一次一个
+	if peak[single.ID] != 1 {
+		t.Errorf("single 最大并发 = %d, want 1", peak[single.ID])
+	}
+	if peakTotal != 3 {
+		t.Errorf("总并发 = %d, want 3", peakTotal)
+	}
+}
+
+func TestStrmQueueConfigExclusive(t *testing.T) {
+	db := newTestDB(t)
+	q := useTestQueue(t, db, 2)
+	const configID = 300
+
+	// 手动生成占用配置时，队列中同一配置的任务等待
+	locked := make(chan struct{})
+	unlock := make(chan struct{})
+	lockDone := make(chan error, 1)
+	go func() {
+		lockDone <- WithStrmConfigLock(configID, func() error {
+			close(locked)
+			<-unlock
+			return nil
+		})
+	}()
+	<-locked
+
+	var mutex sync.Mutex
+	active, peak, runs := 0, 0, 0
+	started := make(chan struct{}, 2)
+	proceed := make(chan struct{})
+	for i, mode := range []model.TaskMode{model.TaskModeCreate, model.TaskModeCheck} {
+		// 不同服务、不同模式，只受配置锁限制
+		task := &model.StrmTask{ServiceID: 300 + i, ConfigID: configID, TaskMode: mode}
+		if _, _, err := q.EnqueueFunc(task, JobPriorityManual, func(ctx context.Context) error {
+			mutex.Lock()
+			active++
+			runs++
+			peak = max(peak, active)
+			mutex.Unlock()
+			started <- struct{}{}
+			<-proceed
+			mutex.Lock()
+			active--
+			mutex.Unlock()
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case <-started:
+		t.Fatal("配置被占用时不应开始执行")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(unlock)
+	if err := <-lockDone; err != nil {
+		t.Fatal(err)
+	}
+	// 释放配置锁后唤醒排队任务
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("释放配置后任务未执行")
+	}
+	// 队列任务执行期间手动生成返回 ErrConfigBusy
+	if err := WithStrmConfigLock(configID, func() error { return nil }); !errors.Is(err, ErrConfigBusy) {
+		t.Errorf("err = %v, want ErrConfigBusy", err)
+	}
+	select {
+	case <-started:
+		t.Fatal("同一配置的任务不应同时执行")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(proceed)
+	<-started
+	waitUntil(t, "全部执行完", func() bool { return len(q.Jobs()) == 0 })
+	if peak != 1 || runs != 2 {
+		t.Errorf("peak = %d, runs = %d", peak, runs)
+	}
+	if err := WithStrmConfigLock(configID, func() error { return nil }); err != nil {
+		t.Errorf("执行完后配置应释放: %v", err)
+	}
+}
+
+func TestStrmQueueStop(t *testing.T) {
+	db := newTestDB(t)
+	q := useTestQueue(t, db, 1)
+	fake := newFakeOpenList(t, testLibrary())
+	_, _, task := createTestTask(t, db, fake.URL, t.TempDir())
+
+	// 排队中：暂停不适用，取消移出队列
+	release := blockQueue(t, q, 1)
+	if _, _, err := q.Enqueue(task, JobPriorityManual); err != nil {
+		t.Fatal(err)
+	}
+	if q.Stop(task.ID, strm.ErrPaused) {
+		t.Error("排队中的任务不能暂停")
+	}
+	if !q.Stop(task.ID, strm.ErrCancelled) {
+		t.Fatal("排队中的任务应能取消")
+	}
+	if p := GetStrmTaskProgress(task.ID); p.Status != model.TaskStatusCancelled {
+		t.Errorf("progress = %s, want cancelled", p.Status)
+	}
+	if n := len(q.Jobs()); n != 1 {
+		t.Errorf("取消后 jobs = %d, want 1", n)
+	}
+	if q.Stop(task.ID, strm.ErrCancelled) {
+		t.Error("已移出队列的任务不应再次取消成功")
+	}
+	// 不属于任务的执行不受 Stop 影响
+	if _, _, err := q.EnqueueFunc(&model.StrmTask{ServiceID: 1, ConfigID: 1}, JobPriorityManual, func(ctx context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if q.Stop(0, strm.ErrCancelled) {
+		t.Error("Stop 不应作用于不属于任务的执行")
+	}
+	release()
+	waitUntil(t, "队列清空", func() bool { return len(q.Jobs()) == 0 })
+
+	// 执行中：取消 context，日志记为 cancelled
+	listing := make(chan struct{})
+	var once sync.Once
+	fake.setHook(func(api, p string) {
+		if api == "/api/fs/list" && p == "/media/ShowA" {
+			once.Do(func() { close(listing) })
+			time.Sleep(100 * time.Millisecond)
+		}
+	})
+	if _, _, err := q.Enqueue(task, JobPriorityManual); err != nil {
+		t.Fatal(err)
+	}
+	<-listing
+	if !q.Stop(task.ID, strm.ErrCancelled) {
+		t.Fatal("执行中的任务应能取消")
+	}
+	done := waitTaskStatus(t, task.ID, model.TaskStatusCancelled)
+	if record, _ := logResult(t, db, done.LogID); record.TaskStatus != model.TaskStatusCancelled {
+		t.Errorf("log status = %s, want cancelled", record.TaskStatus)
+	}
+	waitUntil(t, "队列清空", func() bool { return len(q.Jobs()) == 0 })
+	if q.Stop(task.ID, strm.ErrCancelled) {
+		t.Error("已结束的任务不应取消成功")
+	}
+}
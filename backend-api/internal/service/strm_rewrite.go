@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"path"
+	"path/filepath"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/openlist"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/strm"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// PreviewRewrite 遍历远程目录，对前 limit 个视频文件展示改写后的本地 .strm 路径，不写入任何文件
+func PreviewRewrite(ctx context.Context, db *gorm.DB, svc *model.OpenListService, cfg *model.StrmConfig, limit int) ([]*model.StrmRewritePreviewItem, error) {
+	logger.Info("[Service] PreviewRewrite called", zap.Int("service_id", svc.ID), zap.String("base", cfg.AlistBasePath), zap.Int("limit", limit))
+	rewriter, err := strm.ParseRewriteRules(cfg.RewriteRules)
+	if err != nil {
+		return nil, err
+	}
+	extensions, err := LoadExtensions(db)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := strm.NewFilter(cfg, extensions)
+	if err != nil {
+		return nil, err
+	}
+	client, err := NewOpenListClient(db, svc)
+	if err != nil {
+		return nil, err
+	}
+	items := []*model.StrmRewritePreviewItem{}
+	err = strm.Walk(ctx, client, cfg.AlistBasePath, func(dir string, obj openlist.Object) error {
+		remotePath := path.Join(dir, obj.Name)
+		rel := strm.RelativePath(cfg.AlistBasePath, remotePath)
+		if obj.IsDir {
+			if filter.SkipDir(rel) {
+				return strm.SkipDir
+			}
+			return nil
+		}
+		if !filter.IsVideo(rel, obj) {
+			return nil
+		}
+		item := &model.StrmRewritePreviewItem{RemotePath: remotePath}
+		if localRel, err := rewriter.Apply(rel); err != nil {
+			item.Error = err.Error()
+		} else {
+			item.LocalPath = filepath.ToSlash(strm.StrmFilePath("", localRel))
+		}
+		items = append(items, item)
+		if len(items) >= limit {
+			return errWalkStop
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errWalkStop) {
+		return nil, wrapRemotePathError(err)
+	}
+	// 标记改写后重名的文件
+	seen := make(map[string]string, len(items))
+	for _, item := range items {
+		if item.LocalPath == "" {
+			continue
+		}
+		if owner, ok := seen[item.LocalPath]; ok {
+			item.Error = "与 " + owner + " 改写后路径冲突"
+			continue
+		}
+		seen[item.LocalPath] = item.RemotePath
+	}
+	return items, nil
+}
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/util"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const maxIntervalHours = 24 * 365
+
+// StrmScheduler 按任务的调度方式定时执行已启用的任务，任务增删改后通过 Reload/Remove 即时生效
+type StrmScheduler struct {
+	db      *gorm.DB
+	catchUp model.CatchUpPolicy
+	entries map[int]*model.StrmTask // 已启用且有下一次执行时间的任务，NextRunAt 非空
+	mutex   sync.Mutex
+	wake    chan struct{}
+}
+
+var (
+	strmScheduler     *StrmScheduler
+	strmSchedulerOnce sync.Once
+)
+
+// StartStrmScheduler 启动任务调度（单例），默认补执行策略由环境变量 STRM_TASK_CATCHUP 配置（skip/once，默认 once）
+func StartStrmScheduler(db *gorm.DB) *StrmScheduler {
+	strmSchedulerOnce.Do(func() {
+		catchUp := model.CatchUpOnce
+		if v := os.Getenv("STRM_TASK_CATCHUP"); v != "" {
+			if p := model.CatchUpPolicy(v); p == model.CatchUpSkip || p == model.CatchUpOnce {
+				catchUp = p
+			} else {
+				logger.Error("[Scheduler] STRM_TASK_CATCHUP 格式错误，使用默认值", zap.String("value", v))
+			}
+		}
+		strmScheduler = &StrmScheduler{
+			db:      db,
+			catchUp: catchUp,
+			entries: make(map[int]*model.StrmTask),
+			wake:    make(chan struct{}, 1),
+		}
+		strmScheduler.load()
+		logger.Info("[Scheduler] 启动", zap.String("catchUp", string(catchUp)), zap.Int("tasks", len(strmScheduler.entries)))
+		go strmScheduler.run()
+	})
+	return strmScheduler
+}
+
+// GetStrmScheduler 获取调度实例，未启动时返回 nil
+func GetStrmScheduler() *StrmScheduler {
+	return strmScheduler
+}
+
+// ValidateStrmTaskSchedule 校验任务调度参数
+func ValidateStrmTaskSchedule(task *model.StrmTask) error {
+	switch task.ScheduleType {
+	case model.ScheduleTypeCron:
+		schedule, err := util.ParseCron(task.CronExpr)
+		if err != nil {
+			return err
+		}
+		if schedule.Next(time.Now()).IsZero() {
+			return errors.New("cron 表达式没有可执行的时间")
+		}
+	case model.ScheduleTypeInterval:
+		if task.IntervalHours < 1 || task.IntervalHours > maxIntervalHours {
+			return fmt.Errorf("intervalHours 须在 1-%d 之间", maxIntervalHours)
+		}
+	case model.ScheduleTypeOnce, "":
+		if task.ScheduledTime.IsZero() {
+			return errors.New("单次任务须指定 scheduledTime")
+		}
+	default:
+		return errors.New("scheduleType 仅支持 cron/interval/once")
+	}
+	switch task.CatchUp {
+	case "", model.CatchUpSkip, model.CatchUpOnce:
+	default:
+		return errors.New("catchUp 仅支持 skip/once")
+	}
+	return nil
+}
+
+// NextStrmTaskRun 计算任务在 after 之后的下一次执行时间，未启用或不再执行时返回 nil
+func NextStrmTaskRun(task *model.StrmTask, after time.Time) *time.Time {
+	if !task.Enabled {
+		return nil
+	}
+	var next time.Time
+	switch task.ScheduleType {
+	case model.ScheduleTypeCron:
+		schedule, err := util.ParseCron(task.CronExpr)
+		if err != nil {
+			return nil
+		}
+		next = schedule.Next(after)
+	case model.ScheduleTypeInterval:
+		if task.IntervalHours <= 0 {
+			return nil
+		}
+		// 以 ScheduledTime（未设置时为创建时间）为起点对齐，重启不会导致执行时间漂移
+		anchor := task.ScheduledTime
+		if anchor.IsZero() {
+			anchor = task.CreatedAt
+		}
+		interval := time.Duration(task.IntervalHours) * time.Hour
+		next = anchor
+		if !after.Before(anchor) {
+			next = anchor.Add((after.Sub(anchor)/interval + 1) * interval)
+		}
+	default:
+		// 单次任务执行过后不再调度
+		if task.ScheduledTime.After(after) && (task.LastRunAt == nil || task.LastRunAt.Before(task.ScheduledTime)) {
+			next = task.ScheduledTime
+		}
+	}
+	if next.IsZero() {
+		return nil
+	}
+	return &next
+}
+
+// load 启动时加载已启用任务，处理停机期间错过的执行
+func (s *StrmScheduler) load() {
+	tasks, err := model.GetEnabledStrmTasks(s.db)
+	if err != nil {
+		logger.Error("[Scheduler] 加载任务失败", zap.Error(err))
+		return
+	}
+	now := time.Now()
+	for _, task := range tasks {
+		next := NextStrmTaskRun(task, now)
+		if task.NextRunAt != nil && task.NextRunAt.Before(now) {
+			policy := task.CatchUp
+			if policy == "" {
+				policy = s.catchUp
+			}
+			logger.Info("[Scheduler] 任务错过执行", zap.Int("task_id", task.ID), zap.Time("missed", *task.NextRunAt), zap.String("catchUp", string(policy)))
+			if policy == model.CatchUpOnce {
+				next = &now
+			}
+		}
+		if next == nil || task.NextRunAt == nil || !next.Equal(*task.NextRunAt) {
+			if err := model.UpdateStrmTaskNextRun(s.db, task.ID, next); err != nil {
+				logger.Error("[Scheduler] 更新下次执行时间失败", zap.Int("task_id", task.ID), zap.Error(err))
+			}
+		}
+		task.NextRunAt = next
+		if next != nil {
+			s.entries[task.ID] = task
+		}
+	}
+}
+
+// Reload 任务新增、修改或启停后重新调度，task.NextRunAt 须已按新配置计算
+func (s *StrmScheduler) Reload(task *model.StrmTask) {
+	s.mutex.Lock()
+	if task.NextRunAt == nil {
+		delete(s.entries, task.ID)
+	} else {
+		t := *task
+		s.entries[task.ID] = &t
+	}
+	s.mutex.Unlock()
+	s.notify()
+}
+
+// Remove 任务删除后取消调度
+func (s *StrmScheduler) Remove(id int) {
+	s.mutex.Lock()
+	delete(s.entries, id)
+	s.mutex.Unlock()
+	s.notify()
+}
+
+func (s *StrmScheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *StrmScheduler) run() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		s.fireDue()
+		wait := s.untilNext()
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+		select {
+		case <-timer.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// untilNext 距最近一次执行的等待时间，没有待执行任务时最多等待一小时
+func (s *StrmScheduler) untilNext() time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	wait := time.Hour
+	now := time.Now()
+	for _, task := range s.entries {
+		if d := task.NextRunAt.Sub(now); d < wait {
+			wait = d
+		}
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return wait
+}
+
+// fireDue 执行所有已到时间的任务并计算下一次执行时间
+func (s *StrmScheduler) fireDue() {
+	now := time.Now()
+	var due []*model.StrmTask
+	s.mutex.Lock()
+	for id, task := range s.entries {
+		if task.NextRunAt.After(now) {
+			continue
+		}
+		due = append(due, task)
+		task.LastRunAt = &now
+		task.NextRunAt = NextStrmTaskRun(task, now)
+		if task.NextRunAt == nil {
+			delete(s.entries, id)
+		}
+	}
+	s.mutex.Unlock()
+
+	for _, task := range due {
+		if err := model.UpdateStrmTaskRunTimes(s.db, task.ID, now, task.NextRunAt); err != nil {
+			logger.Error("[Scheduler] 更新执行时间失败", zap.Int("task_id", task.ID), zap.Error(err))
+		}
+		logger.Info("[Scheduler] 触发任务", zap.Int("task_id", task.ID), zap.String("name", task.Name))
+		if _, _, err := EnqueueStrmTask(task, JobPriorityScheduled); err != nil {
+			logger.Error("[Scheduler] 任务入队失败", zap.Int("task_id", task.ID), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+)
+
+func TestNextStrmTaskRunInterval(t *testing.T) {
+	anchor := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	task := &model.StrmTask{Enabled: true, ScheduleType: model.ScheduleTypeInterval, IntervalHours: 6, ScheduledTime: anchor}
+	cases := []struct {
+		after time.Duration
+		want  time.Duration
+	}{
+		{-time.Hour, 0}, // 起点之前，首次在起点执行
+		{0, 6 * time.Hour},
+		{time.Minute, 6 * time.Hour},
+		{12 * time.Hour, 18 * time.Hour}, // 恰好在执行点时取下一个
+		{13*time.Hour + 30*time.Minute, 18 * time.Hour},
+		{30 * 24 * time.Hour, 30*24*time.Hour + 6*time.Hour},
+	}
+	for _, tc := range cases {
+		next := NextStrmTaskRun(task, anchor.Add(tc.after))
+		if next == nil || !next.Equal(anchor.Add(tc.want)) {
+			t.Errorf("after anchor%+v: next = %v, want anchor%+v", tc.after, next, tc.want)
+		}
+	}
+
+	// 未设置 ScheduledTime 时以创建时间为起点
+	created := anchor.Add(17 * time.Minute)
+	task = &model.StrmTask{Enabled: true, ScheduleType: model.ScheduleTypeInterval, IntervalHours: 1, CreatedAt: created}
+	if next := NextStrmTaskRun(task, created.Add(150*time.Minute)); next == nil || !next.Equal(created.Add(3*time.Hour)) {
+		t.Errorf("next = %v, want %v", next, created.Add(3*time.Hour))
+	}
+
+	task.IntervalHours = 0
+	if next := NextStrmTaskRun(task, anchor); next != nil {
+		t.Errorf("间隔为 0 时 next = %v", next)
+	}
+}
+
+func TestNextStrmTaskRunOnce(t *testing.T) {
+	scheduled := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	before := scheduled.Add(-time.Hour)
+	cases := []struct {
+		name     string
+		schedule model.ScheduleType
+		after    time.Time
+		lastRun  *time.Time
+		want     bool
+	}{
+		{"尚未执行", model.ScheduleTypeOnce, before, nil, true},
+		{"旧数据未设置调度方式", "", before, nil, true},
+		{"已过执行时间", model.ScheduleTypeOnce, scheduled, nil, false},
+		{"上次执行早于执行时间", model.ScheduleTypeOnce, before, &before, true},
+		{"已执行过", model.ScheduleTypeOnce, before, &scheduled, false},
+	}
+	for _, tc := range cases {
+		task := &model.StrmTask{Enabled: true, ScheduleType: tc.schedule, ScheduledTime: scheduled, LastRunAt: tc.lastRun}
+		next := NextStrmTaskRun(task, tc.after)
+		if got := next != nil; got != tc.want {
+			t.Errorf("%s: next = %v", tc.name, next)
+		} else if got && !next.Equal(scheduled) {
+			t.Errorf("%s: next = %v, want %v", tc.name, next, scheduled)
+		}
+	}
+}
+
+func TestNextStrmTaskRunCron(t *testing.T) {
+	after := time.Date(2024, 1, 1, 8, 7, 0, 0, time.UTC)
+	task := &model.StrmTask{Enabled: true, ScheduleType: model.ScheduleTypeCron, CronExpr: "*/15 * * * *"}
+	if next := NextStrmTaskRun(task, after); next == nil || !next.Equal(after.Add(8*time.Minute)) {
+		t.Errorf("next = %v", next)
+	}
+	task.CronExpr = "invalid"
+	if next := NextStrmTaskRun(task, after); next != nil {
+		t.Errorf("无效表达式 next = %v", next)
+	}
+	task = &model.StrmTask{ScheduleType: model.ScheduleTypeCron, CronExpr: "* * * * *"}
+	if next := NextStrmTaskRun(task, after); next != nil {
+		t.Errorf("未启用的任务 next = %v", next)
+	}
+}
+
+func TestStrmSchedulerLoadCatchUp(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now()
+	missed := now.Add(-3 * time.Hour)
+	future := now.Add(time.Hour)
+	create := func(name string, catchUp model.CatchUpPolicy, schedule model.ScheduleType, nextRunAt *time.Time) *model.StrmTask {
+		task := &model.StrmTask{
+			UserID:        1,
+			Name:          name,
+			Enabled:       true,
+			TaskMode:      model.TaskModeCreate,
+			ScheduleType:  schedule,
+			IntervalHours: 24,
+			ScheduledTime: now.Add(-100 * time.Hour),
+			CatchUp:       catchUp,
+			NextRunAt:     nextRunAt,
+		}
+		if schedule == model.ScheduleTypeOnce {
+			task.ScheduledTime = missed
+		}
+		if err := model.CreateStrmTask(db, task); err != nil {
+			t.Fatal(err)
+		}
+		return task
+	}
+	once := create("once", model.CatchUpOnce, model.ScheduleTypeInterval, &missed)
+	skip := create("skip", model.CatchUpSkip, model.ScheduleTypeInterval, &missed)
+	byDefault := create("default", "", model.ScheduleTypeInterval, &missed)
+	onTime := create("onTime", model.CatchUpSkip, model.ScheduleTypeInterval, &future)
+	missedOnce := create("missedOnce", model.CatchUpOnce, model.ScheduleTypeOnce, &missed)
+	expired := create("expired", model.CatchUpSkip, model.ScheduleTypeOnce, &missed)
+	disabled := create("disabled", model.CatchUpOnce, model.ScheduleTypeInterval, &missed)
+	if err := db.Model(disabled).Update("enabled", false).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	s := &StrmScheduler{db: db, catchUp: model.CatchUpSkip, entries: make(map[int]*model.StrmTask)}
+	s.load()
+
+	// 间隔任务以 ScheduledTime 为起点，每 24 小时一次
+	regular := NextStrmTaskRun(once, now)
+	assertNext := func(task *model.StrmTask, want *time.Time) {
+		t.Helper()
+		entry, ok := s.entries[task.ID]
+		if want == nil {
+			if ok {
+				t.Errorf("%s 不应调度: %v", task.Name, entry.NextRunAt)
+			}
+		} else if !ok || entry.NextRunAt.Sub(*want).Abs() > time.Second {
+			t.Errorf("%s next = %v, want %v", task.Name, entry, want)
+		}
+		saved, err := model.GetStrmTaskByID(db, task.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (saved.NextRunAt == nil) != (want == nil) || (want != nil && saved.NextRunAt.Sub(*want).Abs() > time.Second) {
+			t.Errorf("%s 保存的 next = %v, want %v", task.Name, saved.NextRunAt, want)
+		}
+	}
+	assertNext(once, &now)
+	assertNext(skip, regular)
+	// 未设置策略时使用调度器默认策略
+	assertNext(byDefault, regular)
+	assertNext(onTime, regular)
+	assertNext(missedOnce, &now)
+	assertNext(expired, nil)
+	if _, ok := s.entries[disabled.ID]; ok {
+		t.Error("未启用的任务不应调度")
+	}
+}
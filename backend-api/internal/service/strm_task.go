@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/strm"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTaskServiceDown  = errors.New("OpenList服务不可用")
+	ErrTaskNotRunning   = errors.New("任务未在执行")
+	ErrTaskNotPaused    = errors.New("任务未暂停")
+	ErrPauseUnsupported = errors.New("检查任务不支持暂停")
+)
+
+func ListStrmTasks(db *gorm.DB, query *model.StrmTaskQuery, page, pageSize int) ([]*model.StrmTask, int64, error) {
+	return model.ListStrmTasks(db, query, page, pageSize)
+}
+
+// CreateStrmTask 新增任务并加入调度
+func CreateStrmTask(db *gorm.DB, task *model.StrmTask) error {
+	now := time.Now()
+	// 间隔任务未指定起点时从创建时开始计时
+	if task.ScheduleType == model.ScheduleTypeInterval && task.ScheduledTime.IsZero() {
+		task.ScheduledTime = now
+	}
+	task.NextRunAt = NextStrmTaskRun(task, now)
+	if err := model.CreateStrmTask(db, task); err != nil {
+		return err
+	}
+	reloadStrmTaskSchedule(task)
+	return nil
+}
+
+// UpdateStrmTask 修改任务并按新的调度方式重新计算下一次执行时间
+func UpdateStrmTask(db *gorm.DB, task *model.StrmTask) error {
+	task.NextRunAt = NextStrmTaskRun(task, time.Now())
+	if err := model.UpdateStrmTask(db, task); err != nil {
+		return err
+	}
+	reloadStrmTaskSchedule(task)
+	return nil
+}
+
+// ToggleStrmTask 启用或停用任务，停用后不再调度
+func ToggleStrmTask(db *gorm.DB, task *model.StrmTask, enabled bool) error {
+	if err := model.ToggleStrmTaskEnabled(db, task.ID, enabled); err != nil {
+		return err
+	}
+	task.Enabled = enabled
+	task.NextRunAt = NextStrmTaskRun(task, time.Now())
+	if err := model.UpdateStrmTaskNextRun(db, task.ID, task.NextRunAt); err != nil {
+		return err
+	}
+	reloadStrmTaskSchedule(task)
+	return nil
+}
+
+func reloadStrmTaskSchedule(task *model.StrmTask) {
+	if s := GetStrmScheduler(); s != nil {
+		s.Reload(task)
+	}
+}
+
+func GetStrmTaskByID(db *gorm.DB, id int) (*model.StrmTask, error) {
+	return model.GetStrmTaskByID(db, id)
+}
+
+// DeleteStrmTask 删除任务及其运行日志
+func DeleteStrmTask(db *gorm.DB, id int) error {
+	if err := model.DeleteStrmTask(db, id); err != nil {
+		return err
+	}
+	if s := GetStrmScheduler(); s != nil {
+		s.Remove(id)
+	}
+	if q := GetStrmTaskQueue(); q != nil {
+		q.Stop(id, strm.ErrCancelled)
+	}
+	if err := model.DeleteStrmTaskCheckpoint(db, id); err != nil {
+		return err
+	}
+	removeStrmTaskProgress(id)
+	return model.DeleteLogRecordsByTaskID(db, id)
+}
+
+// CopyStrmTasks 复制任务，名称追加“-复制”，复制出的任务默认停用，避免与原任务重复执行
+func CopyStrmTasks(db *gorm.DB, tasks []*model.StrmTask) error {
+	for _, task := range tasks {
+		task.ID = 0
+		task.Name = task.Name + "-复制"
+		task.Enabled = false
+		task.NextRunAt = nil
+		task.LastRunAt = nil
+		if err := model.CreateStrmTask(db, task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExecuteStrmTask 按任务模式执行生成或检查，运行过程记录到日志；
+// 依赖的 OpenList 服务不可用时记录 skipped 并返回 ErrTaskServiceDown。
+// 调用方须保证同一配置不会并发执行，一般通过任务队列调用
+func ExecuteStrmTask(ctx context.Context, db *gorm.DB, task *model.StrmTask) error {
+	logger.Info("[Service] ExecuteStrmTask called", zap.Int("task_id", task.ID), zap.String("mode", string(task.TaskMode)))
+	if IsOpenListServiceDown(task.ServiceID) {
+		record := &model.LogRecord{
+			UserID:     task.UserID,
+			Name:       LogNameForTaskMode(task.TaskMode),
+			TaskStatus: model.TaskStatusSkipped,
+			TaskID:     task.ID,
+			ConfigID:   task.ConfigID,
+		}
+		if err := model.CreateLogRecord(db, record); err != nil {
+			logger.Error("[Service] 记录任务跳过失败", zap.Int("task_id", task.ID), zap.Error(err))
+		}
+		publishStrmTaskProgress(task.ID, record.ID, model.TaskStatusSkipped, nil)
+		return ErrTaskServiceDown
+	}
+	cfg, err := model.GetStrmConfigByID(db, task.ConfigID)
+	if err != nil {
+		logger.Error("[Service] 任务配置不存在", zap.Int("task_id", task.ID), zap.Int("config_id", task.ConfigID), zap.Error(err))
+		return err
+	}
+	if task.TaskMode == model.TaskModeCheck {
+		_, err = CheckStrm(ctx, db, cfg, task.ID, task.AutoRepair)
+		return err
+	}
+	_, err = generateStrmWithLog(ctx, db, cfg, task.ID)
+	return err
+}
+
+// generateStrmWithLog 执行生成并记录为 LogNameCreate 日志。任务有暂停保存的进度时从进度继续并沿用原日志；
+// 被暂停时保存进度，其他情况结束后清除进度
+func generateStrmWithLog(ctx context.Context, db *gorm.DB, cfg *model.StrmConfig, taskID int) (*strm.Summary, error) {
+	var record *model.LogRecord
+	resume, logID := loadStrmTaskCheckpoint(db, cfg, taskID)
+	if resume != nil {
+		if r, err := model.GetLogRecordByID(db, logID); err == nil {
+			record = r
+			if err := model.UpdateLogRecordStatus(db, record.ID, model.TaskStatusRunning); err != nil {
+				logger.Error("[Service] 更新运行日志失败", zap.Int("log_id", record.ID), zap.Error(err))
+			}
+		}
+	}
+	if record == nil {
+		var err error
+		if record, err = startLogRecord(db, cfg, taskID, model.LogNameCreate); err != nil {
+			return nil, err
+		}
+	}
+	var opts []strm.Option
+	if taskID > 0 {
+		publishStrmTaskProgress(taskID, record.ID, model.TaskStatusRunning, nil)
+		opts = append(opts, strmProgressOption(taskID, record.ID))
+	}
+	summary, stopped, err := generateStrm(ctx, db, cfg, resume, opts...)
+	if taskID > 0 {
+		if err != nil && errors.Is(context.Cause(ctx), strm.ErrPaused) {
+			// 尚未开始遍历就被暂停时从头开始
+			if stopped == nil {
+				stopped = &strm.Checkpoint{Pending: []string{cfg.AlistBasePath}, Summary: summary}
+			}
+			saveStrmTaskCheckpoint(db, cfg, taskID, record.ID, stopped)
+		} else if resume != nil {
+			if err := model.DeleteStrmTaskCheckpoint(db, taskID); err != nil {
+				logger.Error("[Service] 清除任务进度失败", zap.Int("task_id", taskID), zap.Error(err))
+			}
+		}
+	}
+	var result interface{} = summary
+	usedURL := ""
+	switch {
+	case summary == nil && err != nil:
+		result = map[string]string{"error": err.Error()}
+	case summary != nil:
+		// 暂停或取消不计为错误
+		if err != nil && ctx.Err() == nil {
+			summary.Errors = append(summary.Errors, err.Error())
+		}
+		usedURL = summary.UsedURL
+	}
+	finishLogRecord(ctx, db, record, result, usedURL, err)
+	return summary, err
+}
+
+// loadStrmTaskCheckpoint 读取任务暂停时保存的进度及对应的日志 ID，没有或无法解析时返回 nil；
+// 暂停后任务改用了其他配置或配置被修改时，进度中的目录和本地路径已不可靠，作废进度并将原日志标记为 cancelled
+func loadStrmTaskCheckpoint(db *gorm.DB, cfg *model.StrmConfig, taskID int) (*strm.Checkpoint, int) {
+	if taskID <= 0 {
+		return nil, 0
+	}
+	saved, err := model.GetStrmTaskCheckpoint(db, taskID)
+	if err != nil || saved == nil {
+		return nil, 0
+	}
+	if saved.ConfigID != cfg.ID || !saved.ConfigUpdatedAt.Equal(cfg.UpdatedAt) {
+		logger.Info("[Service] 配置已修改，任务进度作废，从头执行", zap.Int("task_id", taskID), zap.Int("config_id", cfg.ID), zap.Int("saved_config_id", saved.ConfigID))
+		if err := model.DeleteStrmTaskCheckpoint(db, taskID); err != nil {
+			logger.Error("[Service] 清除任务进度失败", zap.Int("task_id", taskID), zap.Error(err))
+		}
+		if err := model.UpdateLogRecordStatus(db, saved.LogID, model.TaskStatusCancelled); err != nil {
+			logger.Error("[Service] 更新运行日志失败", zap.Int("log_id", saved.LogID), zap.Error(err))
+		}
+		return nil, 0
+	}
+	var cp strm.Checkpoint
+	if err := json.Unmarshal([]byte(saved.Data), &cp); err != nil {
+		logger.Error("[Service] 任务进度解析失败，从头执行", zap.Int("task_id", taskID), zap.Error(err))
+		return nil, 0
+	}
+	return &cp, saved.LogID
+}
+
+func saveStrmTaskCheckpoint(db *gorm.DB, cfg *model.StrmConfig, taskID, logID int, cp *strm.Checkpoint) {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		logger.Error("[Service] 序列化任务进度失败", zap.Int("task_id", taskID), zap.Error(err))
+		return
+	}
+	if err := model.SaveStrmTaskCheckpoint(db, &model.StrmTaskCheckpoint{
+		TaskID:          taskID,
+		LogID:           logID,
+		ConfigID:        cfg.ID,
+		ConfigUpdatedAt: cfg.UpdatedAt,
+		Data:            string(data),
+	}); err != nil {
+		logger.Error("[Service] 保存任务进度失败", zap.Int("task_id", taskID), zap.Error(err))
+	}
+}
+
+// PauseStrmTask 暂停执行中的生成任务，停止后保存进度，可通过 ResumeStrmTask 继续
+func PauseStrmTask(task *model.StrmTask) error {
+	if task.TaskMode == model.TaskModeCheck {
+		return ErrPauseUnsupported
+	}
+	q := GetStrmTaskQueue()
+	if q == nil || !q.Stop(task.ID, strm.ErrPaused) {
+		return ErrTaskNotRunning
+	}
+	return nil
+}
+
+// CancelStrmTask 取消任务：排队中的移出队列，执行中的停止，已暂停的清除进度并将日志标记为 cancelled
+func CancelStrmTask(db *gorm.DB, task *model.StrmTask) error {
+	if q := GetStrmTaskQueue(); q != nil && q.Stop(task.ID, strm.ErrCancelled) {
+		return nil
+	}
+	saved, err := model.GetStrmTaskCheckpoint(db, task.ID)
+	if err != nil {
+		return err
+	}
+	if saved == nil {
+		return ErrTaskNotRunning
+	}
+	if err := model.DeleteStrmTaskCheckpoint(db, task.ID); err != nil {
+		return err
+	}
+	if err := model.UpdateLogRecordStatus(db, saved.LogID, model.TaskStatusCancelled); err != nil {
+		return err
+	}
+	publishStrmTaskProgress(task.ID, saved.LogID, model.TaskStatusCancelled, nil)
+	return nil
+}
+
+// ResumeStrmTask 将已暂停的任务以手动优先级加入队列，执行时从保存的进度继续
+func ResumeStrmTask(db *gorm.DB, task *model.StrmTask) (*StrmQueueJob, bool, error) {
+	saved, err := model.GetStrmTaskCheckpoint(db, task.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	if saved == nil {
+		return nil, false, ErrTaskNotPaused
+	}
+	return EnqueueStrmTask(task, JobPriorityManual)
+}
+
+// startLogRecord 创建状态为 running 的运行日志
+func startLogRecord(db *gorm.DB, cfg *model.StrmConfig, taskID int, name model.LogName) (*model.LogRecord, error) {
+	record := &model.LogRecord{
+		UserID:     cfg.UserID,
+		Name:       name,
+		TaskStatus: model.TaskStatusRunning,
+		TaskID:     taskID,
+		ConfigID:   cfg.ID,
+	}
+	if err := model.CreateLogRecord(db, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
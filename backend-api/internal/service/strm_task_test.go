@@ -0,0 +1,165 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/strm"
+	"gorm.io/gorm"
+)
+
+func testLibrary() map[string][]string {
+	return map[string][]string{
+		"/media":       {"ShowA/", "ShowB/", "ShowC/"},
+		"/media/ShowA": {"A1.mkv", "A2.mkv", "A3.mkv"},
+		"/media/ShowB": {"B1.mkv", "B2.mkv", "B3.mkv"},
+		"/media/ShowC": {"C1.mkv", "C2.mkv", "C3.mkv"},
+	}
+}
+
+// pauseOnList 任务拉取 dir 时暂停任务，返回暂停后的进度
+func pauseOnList(t *testing.T, db *gorm.DB, fake *fakeOpenList, task *model.StrmTask, dir string) StrmTaskProgress {
+	t.Helper()
+	var once sync.Once
+	fake.setHook(func(api, p string) {
+		if api == "/api/fs/list" && p == dir {
+			once.Do(func() {
+				if err := PauseStrmTask(task); err != nil {
+					t.Errorf("PauseStrmTask: %v", err)
+				}
+			})
+		}
+	})
+	if _, _, err := EnqueueStrmTask(task, JobPriorityManual); err != nil {
+		t.Fatal(err)
+	}
+	paused := waitTaskStatus(t, task.ID, model.TaskStatusPaused)
+	fake.setHook(nil)
+	return paused
+}
+
+func logResult(t *testing.T, db *gorm.DB, logID int) (*model.LogRecord, *strm.Summary) {
+	t.Helper()
+	record, err := model.GetLogRecordByID(db, logID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary strm.Summary
+	if record.Result != "" {
+		if err := json.Unmarshal([]byte(record.Result), &summary); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return record, &summary
+}
+
+func TestPauseAndResumeStrmTask(t *testing.T) {
+	db := newTestDB(t)
+	useTestQueue(t, db, 1)
+	fake := newFakeOpenList(t, testLibrary())
+	_, cfg, task := createTestTask(t, db, fake.URL, t.TempDir())
+
+	paused := pauseOnList(t, db, fake, task, "/media/ShowB")
+	saved, err := model.GetStrmTaskCheckpoint(db, task.ID)
+	if err != nil || saved == nil {
+		t.Fatalf("暂停后应保存进度: %v", err)
+	}
+	if saved.LogID != paused.LogID || saved.ConfigID != cfg.ID {
+		t.Errorf("checkpoint = log %d config %d, want log %d config %d", saved.LogID, saved.ConfigID, paused.LogID, cfg.ID)
+	}
+	if record, _ := logResult(t, db, paused.LogID); record.TaskStatus != model.TaskStatusPaused {
+		t.Errorf("log status = %s, want paused", record.TaskStatus)
+	}
+
+	if _, _, err := ResumeStrmTask(db, task); err != nil {
+		t.Fatal(err)
+	}
+	done := waitTaskStatus(t, task.ID, model.TaskStatusCompleted)
+	if done.LogID != paused.LogID {
+		t.Errorf("恢复后应沿用原日志 %d，实际 %d", paused.LogID, done.LogID)
+	}
+	record, summary := logResult(t, db, done.LogID)
+	if record.TaskStatus != model.TaskStatusCompleted || summary.Scanned != 9 || summary.Created != 9 {
+		t.Errorf("log = %s scanned %d created %d, want completed 9/9", record.TaskStatus, summary.Scanned, summary.Created)
+	}
+	if saved, _ := model.GetStrmTaskCheckpoint(db, task.ID); saved != nil {
+		t.Error("完成后应清除进度")
+	}
+	entries, err := model.GetStrmManifest(db, cfg.ID)
+	if err != nil || len(entries) != 9 {
+		t.Errorf("manifest has %d entries, want 9 (%v)", len(entries), err)
+	}
+	if records, _ := model.GetLogRecordsByTaskID(db, task.ID); len(records) != 1 {
+		t.Errorf("应只有 1 条运行日志，实际 %d", len(records))
+	}
+}
+
+func TestCancelPausedStrmTask(t *testing.T) {
+	db := newTestDB(t)
+	useTestQueue(t, db, 1)
+	fake := newFakeOpenList(t, testLibrary())
+	_, _, task := createTestTask(t, db, fake.URL, t.TempDir())
+
+	paused := pauseOnList(t, db, fake, task, "/media/ShowB")
+	if err := CancelStrmTask(db, task); err != nil {
+		t.Fatal(err)
+	}
+	if saved, _ := model.GetStrmTaskCheckpoint(db, task.ID); saved != nil {
+		t.Error("取消后应清除进度")
+	}
+	if record, _ := logResult(t, db, paused.LogID); record.TaskStatus != model.TaskStatusCancelled {
+		t.Errorf("log status = %s, want cancelled", record.TaskStatus)
+	}
+	if p := GetStrmTaskProgress(task.ID); p.Status != model.TaskStatusCancelled {
+		t.Errorf("progress status = %s, want cancelled", p.Status)
+	}
+	if _, _, err := ResumeStrmTask(db, task); !errors.Is(err, ErrTaskNotPaused) {
+		t.Errorf("ResumeStrmTask err = %v, want ErrTaskNotPaused", err)
+	}
+	if err := CancelStrmTask(db, task); !errors.Is(err, ErrTaskNotRunning) {
+		t.Errorf("CancelStrmTask err = %v, want ErrTaskNotRunning", err)
+	}
+}
+
+func TestResumeAfterConfigChangeStartsOver(t *testing.T) {
+	db := newTestDB(t)
+	useTestQueue(t, db, 1)
+	fake := newFakeOpenList(t, testLibrary())
+	_, cfg, task := createTestTask(t, db, fake.URL, t.TempDir())
+
+	paused := pauseOnList(t, db, fake, task, "/media/ShowB")
+	cfg.StrmOutputPath = t.TempDir()
+	if err := model.UpdateStrmConfig(db, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ResumeStrmTask(db, task); err != nil {
+		t.Fatal(err)
+	}
+	done := waitTaskStatus(t, task.ID, model.TaskStatusCompleted)
+	if done.LogID == paused.LogID {
+		t.Fatal("配置修改后应从头执行并使用新日志")
+	}
+	if record, _ := logResult(t, db, paused.LogID); record.TaskStatus != model.TaskStatusCancelled {
+		t.Errorf("原日志状态 = %s, want cancelled", record.TaskStatus)
+	}
+	if _, summary := logResult(t, db, done.LogID); summary.Scanned != 9 || summary.Created != 9 {
+		t.Errorf("scanned %d created %d, want 9/9", summary.Scanned, summary.Created)
+	}
+}
+
+func TestPauseStrmTaskErrors(t *testing.T) {
+	db := newTestDB(t)
+	useTestQueue(t, db, 1)
+	_, _, task := createTestTask(t, db, "http://127.0.0.1:1", t.TempDir())
+	if err := PauseStrmTask(task); !errors.Is(err, ErrTaskNotRunning) {
+		t.Errorf("err = %v, want ErrTaskNotRunning", err)
+	}
+	check := *task
+	check.TaskMode = model.TaskModeCheck
+	if err := PauseStrmTask(&check); !errors.Is(err, ErrPauseUnsupported) {
+		t.Errorf("err = %v, want ErrPauseUnsupported", err)
+	}
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/openlist"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/strm"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// errWalkStop 已取得所需文件，提前终止遍历
+var errWalkStop = errors.New("walk stopped")
+
+// PreviewStrmUrl 用示例文件渲染链接模板；samplePath 为空时按配置的过滤条件取 AlistBasePath 下第一个视频文件，
+// 远程没有视频时使用虚构的示例路径。cfg 未保存（ID 为 0）时播放链接仅作示意
+func PreviewStrmUrl(ctx context.Context, db *gorm.DB, svc *model.OpenListService, cfg *model.StrmConfig, samplePath string) (*model.StrmUrlPreviewResponse, error) {
+	logger.Info("[Service] PreviewStrmUrl called", zap.Int("service_id", svc.ID), zap.String("template", cfg.UrlTemplate))
+	client, err := NewOpenListClient(db, svc)
+	if err != nil {
+		return nil, err
+	}
+	basePath := "/" + strings.Trim(cfg.AlistBasePath, "/")
+	if samplePath == "" {
+		extensions, err := LoadExtensions(db)
+		if err != nil {
+			return nil, err
+		}
+		filter, err := strm.NewFilter(cfg, extensions)
+		if err != nil {
+			return nil, err
+		}
+		err = strm.Walk(ctx, client, basePath, func(dir string, obj openlist.Object) error {
+			remotePath := path.Join(dir, obj.Name)
+			rel := strm.RelativePath(basePath, remotePath)
+			if obj.IsDir {
+				if filter.SkipDir(rel) {
+					return strm.SkipDir
+				}
+				return nil
+			}
+			if filter.IsVideo(rel, obj) {
+				samplePath = remotePath
+				return errWalkStop
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errWalkStop) {
+			return nil, wrapRemotePathError(err)
+		}
+	}
+	vars := strm.URLVars{ServiceURL: svc.ServiceUrl}
+	if samplePath == "" {
+		vars.Path = path.Join(basePath, "示例电影 (2024)", "示例电影 (2024).mkv")
+	} else {
+		resp, err := client.Get(ctx, &openlist.GetReq{Path: samplePath})
+		if err != nil {
+			return nil, wrapRemotePathError(err)
+		}
+		vars.Path = samplePath
+		vars.Sign = resp.Sign
+		vars.RawURL = resp.RawURL
+	}
+	vars.RelPath = strm.RelativePath(basePath, vars.Path)
+	illustrative := false
+	tmpl := cfg.UrlTemplate
+	if base := PlayBaseURL(); base != "" {
+		// 配置尚未保存时配置ID为 0，生成的链接无法播放
+		vars.PlayURL = strm.PlayURL(base, cfg.ID, cfg.PlaySecret, vars.RelPath)
+		illustrative = cfg.ID == 0 && strm.NeedsPlayURL(tmpl)
+	}
+	if tmpl == "" {
+		tmpl = strm.DefaultURLTemplate
+	}
+	return &model.StrmUrlPreviewResponse{
+		Template:     tmpl,
+		SamplePath:   vars.Path,
+		Url:          strm.RenderURL(tmpl, vars),
+		Illustrative: illustrative,
+	}, nil
+}
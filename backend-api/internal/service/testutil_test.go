@@ -0,0 +1,132 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// newTestDB 创建临时 SQLite 数据库并完成迁移
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_busy_timeout=5000"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := model.MigrateIfNotExists(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// fakeOpenList 模拟 OpenList 的 fs/list 和 fs/get，目录树以路径到子项名称的映射表示，名称以 / 结尾的为目录
+type fakeOpenList struct {
+	*httptest.Server
+	tree  map[string][]string
+	mutex sync.Mutex
+	// hook 每次请求前调用，api 为接口路径
+	hook func(api, p string)
+}
+
+func newFakeOpenList(t *testing.T, tree map[string][]string) *fakeOpenList {
+	t.Helper()
+	f := &fakeOpenList{tree: tree}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeOpenList) setHook(hook func(api, p string)) {
+	f.mutex.Lock()
+	f.hook = hook
+	f.mutex.Unlock()
+}
+
+func (f *fakeOpenList) serve(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Path string `json:"path"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	f.mutex.Lock()
+	hook := f.hook
+	f.mutex.Unlock()
+	if hook != nil {
+		hook(r.URL.Path, req.Path)
+	}
+	modified := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/api/fs/list":
+		children, ok := f.tree[req.Path]
+		if !ok {
+			json.NewEncoder(w).Encode(map[string]interface{}{"code": 500, "message": "object not found"})
+			return
+		}
+		content := []map[string]interface{}{}
+		for _, name := range children {
+			content = append(content, map[string]interface{}{
+				"name": strings.TrimSuffix(name, "/"), "is_dir": strings.HasSuffix(name, "/"), "size": 1234, "modified": modified,
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": 200, "data": map[string]interface{}{"content": content, "total": len(content)}})
+	case "/api/fs/get":
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": 200, "data": map[string]interface{}{
+			"name": path.Base(req.Path), "size": 1234, "modified": modified,
+		}})
+	default:
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": 200, "data": map[string]interface{}{}})
+	}
+}
+
+// createTestTask 创建服务、配置和生成任务
+func createTestTask(t *testing.T, db *gorm.DB, serviceURL, outputPath string) (*model.OpenListService, *model.StrmConfig, *model.StrmTask) {
+	t.Helper()
+	svc := &model.OpenListService{UserID: 1, Name: "svc", ServiceUrl: serviceURL, Enabled: true}
+	if err := db.Create(svc).Error; err != nil {
+		t.Fatal(err)
+	}
+	cfg := &model.StrmConfig{UserID: 1, Name: "cfg", ServiceID: svc.ID, AlistBasePath: "/media", StrmOutputPath: outputPath}
+	if err := model.CreateStrmConfig(db, cfg); err != nil {
+		t.Fatal(err)
+	}
+	task := &model.StrmTask{UserID: 1, Name: "task", ServiceID: svc.ID, ConfigID: cfg.ID, TaskMode: model.TaskModeCreate}
+	if err := model.CreateStrmTask(db, task); err != nil {
+		t.Fatal(err)
+	}
+	return svc, cfg, task
+}
+
+// useTestQueue 将全局任务队列替换为测试队列
+func useTestQueue(t *testing.T, db *gorm.DB, workers int) *StrmTaskQueue {
+	old := strmTaskQueue
+	q := newStrmTaskQueue(db, workers, 100)
+	strmTaskQueue = q
+	t.Cleanup(func() { strmTaskQueue = old })
+	return q
+}
+
+// waitTaskStatus 等待任务进度推送指定状态
+func waitTaskStatus(t *testing.T, taskID int, status model.TaskStatus) StrmTaskProgress {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if p := GetStrmTaskProgress(taskID); p.Status == status {
+			return p
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("任务 %d 未进入 %s 状态，当前 %s", taskID, status, GetStrmTaskProgress(taskID).Status)
+	return StrmTaskProgress{}
+}
@@ -0,0 +1,382 @@
+package strm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/openlist"
+	"go.uber.org/zap"
+)
+
+// maxCheckItems 检查报告中最多保留的问题条数
+const maxCheckItems = 1000
+
+// CheckIssue 检查发现的问题类型
+type CheckIssue string
+
+const (
+	CheckBroken       CheckIssue = "broken"        // 远程文件已不存在或链接不可访问
+	CheckStaleSign    CheckIssue = "stale_sign"    // 链接中的签名已过期或即将过期
+	CheckSizeMismatch CheckIssue = "size_mismatch" // 远程文件大小与生成时不一致
+)
+
+// CheckItem 单个 .strm 的检查问题
+type CheckItem struct {
+	Issue      CheckIssue `json:"issue"`
+	LocalPath  string     `json:"localPath"`
+	RemotePath string     `json:"remotePath,omitempty"`
+	Detail     string     `json:"detail,omitempty"`
+	Repaired   bool       `json:"repaired"`
+	Error      string     `json:"error,omitempty"` // 修复失败原因
+}
+
+// CheckReport 检查运行结果
+type CheckReport struct {
+	ConfigID     int          `json:"configId"`
+	Repair       bool         `json:"repair"`  // 是否自动修复
+	Checked      int          `json:"checked"` // 检查的 .strm 文件数
+	Healthy      int          `json:"healthy"`
+	Broken       int          `json:"broken"`
+	StaleSign    int          `json:"staleSign"`
+	SizeMismatch int          `json:"sizeMismatch"`
+	Repaired     int          `json:"repaired"`
+	Failed       int          `json:"failed"` // 无法检查或修复失败的文件数
+	Items        []*CheckItem `json:"items"`  // 问题列表（截断）
+	Errors       []string     `json:"errors"`
+	UsedURL      string       `json:"usedUrl"`
+	StartedAt    time.Time    `json:"startedAt"`
+	FinishedAt   time.Time    `json:"finishedAt"`
+}
+
+// Problems 发现问题或无法检查的文件数。每个文件只计入健康、问题项或无法检查三者之一，
+// 修复失败的问题项同时计入 Failed，不能与问题计数相加
+func (r *CheckReport) Problems() int {
+	return r.Checked - r.Healthy
+}
+
+func (r *CheckReport) addError(format string, args ...interface{}) {
+	r.Failed++
+	if len(r.Errors) < maxSummaryErrors {
+		r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+	}
+}
+
+func (r *CheckReport) addItem(item *CheckItem) {
+	switch item.Issue {
+	case CheckBroken:
+		r.Broken++
+	case CheckStaleSign:
+		r.StaleSign++
+	case CheckSizeMismatch:
+		r.SizeMismatch++
+	}
+	if item.Repaired {
+		r.Repaired++
+	} else if item.Error != "" {
+		r.Failed++
+	}
+	if len(r.Items) < maxCheckItems {
+		r.Items = append(r.Items, item)
+	}
+}
+
+// headClient 无法对应到远程路径的链接使用 HEAD 检查
+var headClient = &http.Client{Timeout: 15 * time.Second}
+
+// Check 扫描输出目录中的 .strm，检查远程文件是否仍存在、签名是否过期、大小是否变化；
+// repair 为 true 时重新生成过期/大小不一致的链接，并按孤立文件策略处理失效文件。
+// 修复后的清单通过 Manifest 获取
+func (g *Generator) Check(ctx context.Context, repair bool) (*CheckReport, error) {
+	report := &CheckReport{ConfigID: g.cfg.ID, Repair: repair, Items: []*CheckItem{}, Errors: []string{}, StartedAt: time.Now()}
+	logger.Info("[Strm] 开始检查", zap.Int("config_id", g.cfg.ID), zap.String("output", g.cfg.StrmOutputPath), zap.Bool("repair", repair))
+	if g.cfg.StrmOutputPath == "" {
+		return report, fmt.Errorf("strmOutputPath 不能为空")
+	}
+	perm, err := ParsePermissions(g.cfg)
+	if err != nil {
+		return report, err
+	}
+	g.perm = perm
+	// 修复写入复用 writeStrm，其计数不计入报告
+	g.summary = &Summary{ConfigID: g.cfg.ID, Errors: []string{}, OrphanFiles: []string{}}
+	// 清单按本地路径索引；修复在 current 上进行，未修改的记录原样保留
+	byLocal := make(map[string]*model.StrmManifestEntry, len(g.previous))
+	for rel, e := range g.previous {
+		entry := *e
+		g.current[rel] = &entry
+		byLocal[filepath.Clean(e.StrmPath)] = &entry
+	}
+
+	out := filepath.Clean(g.cfg.StrmOutputPath)
+	quarantine := g.quarantineDir()
+	g.summary.StartedAt = report.StartedAt
+	g.setPhase(PhaseChecking)
+	err = filepath.WalkDir(out, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == out {
+				return err
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if p != out && (p == quarantine || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.EqualFold(filepath.Ext(d.Name()), ".strm") {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		report.Checked++
+		g.checkFile(ctx, report, p, byLocal[p], repair)
+		g.reportCheckProgress(report, filepath.Dir(p), false)
+		return nil
+	})
+	if os.IsNotExist(err) {
+		err = nil
+	}
+	report.UsedURL = g.client.UsedURL()
+	report.FinishedAt = time.Now()
+	if err == nil {
+		g.phase = PhaseDone
+		g.reportCheckProgress(report, "", true)
+	}
+	logger.Info("[Strm] 检查结束",
+		zap.Int("config_id", g.cfg.ID),
+		zap.Int("checked", report.Checked),
+		zap.Int("broken", report.Broken),
+		zap.Int("stale_sign", report.StaleSign),
+		zap.Int("size_mismatch", report.SizeMismatch),
+		zap.Int("repaired", report.Repaired),
+		zap.Int("failed", report.Failed),
+		zap.Error(err))
+	return report, err
+}
+
+// checkFile 检查单个 .strm，entry 为清单中对应的记录（可能为空）
+func (g *Generator) checkFile(ctx context.Context, report *CheckReport, localPath string, entry *model.StrmManifestEntry, repair bool) {
+	content, err := os.ReadFile(localPath)
+	if err != nil {
+		report.addError("%s: %v", localPath, err)
+		return
+	}
+	link := firstLine(content)
+	remotePath := ""
+	if entry != nil {
+		remotePath = path.Join("/", g.cfg.AlistBasePath, entry.Path)
+	} else {
+		remotePath = g.remoteFromLink(link)
+	}
+	if remotePath == "" {
+		// 无法对应到远程路径（如自定义模板），只能直接访问链接
+		if err := headLink(ctx, link); err != nil {
+			item := &CheckItem{Issue: CheckBroken, LocalPath: localPath, Detail: err.Error()}
+			if repair {
+				g.repairBroken(item)
+			}
+			report.addItem(item)
+			return
+		}
+		report.Healthy++
+		return
+	}
+
+	obj, err := g.client.Get(ctx, &openlist.GetReq{Path: remotePath})
+	if err != nil {
+		if !openlist.IsNotFound(err) {
+			report.addError("%s: %v", remotePath, err)
+			return
+		}
+		item := &CheckItem{Issue: CheckBroken, LocalPath: localPath, RemotePath: remotePath, Detail: "远程文件不存在"}
+		if repair {
+			g.repairBroken(item)
+			if item.Repaired && entry != nil {
+				delete(g.current, entry.Path)
+			}
+		}
+		report.addItem(item)
+		return
+	}
+
+	var item *CheckItem
+	if entry != nil && entry.Size >= 0 && entry.Size != obj.Size {
+		item = &CheckItem{Issue: CheckSizeMismatch, LocalPath: localPath, RemotePath: remotePath,
+			Detail: fmt.Sprintf("生成时 %d 字节，当前 %d 字节", entry.Size, obj.Size)}
+	} else if sign := linkSign(link, entry); SignExpiring(sign, time.Now(), g.signRenewBefore) {
+		item = &CheckItem{Issue: CheckStaleSign, LocalPath: localPath, RemotePath: remotePath,
+			Detail: "签名过期时间 " + SignExpiry(sign).Format(time.RFC3339)}
+	}
+	if item == nil {
+		report.Healthy++
+		return
+	}
+	if repair {
+		if err := g.regenerate(ctx, remotePath, localPath, obj.Object, entry); err != nil {
+			item.Error = err.Error()
+		} else {
+			item.Repaired = true
+		}
+	}
+	report.addItem(item)
+}
+
+// regenerate 重新签名并渲染链接写回 .strm，同步更新清单记录
+func (g *Generator) regenerate(ctx context.Context, remotePath, localPath string, obj openlist.Object, entry *model.StrmManifestEntry) error {
+	rel := RelativePath(g.cfg.AlistBasePath, remotePath)
+	link, sign, err := g.renderURL(ctx, remotePath, rel, g.signFor(ctx, remotePath, obj))
+	if err != nil {
+		return err
+	}
+	if err := g.writeStrm(remotePath, localPath, []byte(link)); err != nil {
+		return err
+	}
+	if entry == nil {
+		entry = newManifestEntry(rel, obj, localPath)
+		g.current[rel] = entry
+	} else {
+		entry.Size = obj.Size
+		entry.Modified = obj.Modified
+		entry.Hash = objectHash(obj)
+	}
+	entry.Url = link
+	entry.Sign = sign
+	return nil
+}
+
+// repairBroken 按孤立文件策略删除或隔离失效的 .strm，report 策略不处理
+func (g *Generator) repairBroken(item *CheckItem) {
+	out := filepath.Clean(g.cfg.StrmOutputPath)
+	switch g.orphanPolicy() {
+	case model.OrphanPolicyDelete:
+		if err := os.Remove(item.LocalPath); err != nil && !os.IsNotExist(err) {
+			item.Error = err.Error()
+			return
+		}
+	case model.OrphanPolicyQuarantine:
+		rel, err := filepath.Rel(out, item.LocalPath)
+		if err != nil {
+			item.Error = err.Error()
+			return
+		}
+		if err := moveFile(item.LocalPath, filepath.Join(g.quarantineDir(), rel)); err != nil {
+			item.Error = err.Error()
+			return
+		}
+	default:
+		item.Detail += "（孤立文件策略为 report，未处理）"
+		return
+	}
+	item.Repaired = true
+	removeEmptyParents(filepath.Dir(item.LocalPath), out)
+}
+
+// remoteFromLink 从 /d/、/p/ 直链或 /play/ 链接还原远程路径，其他链接返回空
+func (g *Generator) remoteFromLink(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.Path == "" {
+		return ""
+	}
+	for _, prefix := range []string{"/d/", "/p/"} {
+		if i := strings.Index(u.Path, prefix); i >= 0 && g.sameHost(u) {
+			return path.Clean(u.Path[i+len(prefix)-1:])
+		}
+	}
+	if g.playBaseURL != "" && strings.HasPrefix(link, g.playBaseURL+"/play/") {
+		prefix := fmt.Sprintf("/play/%d/", g.cfg.ID)
+		if i := strings.Index(u.Path, prefix); i >= 0 {
+			return path.Join("/", g.cfg.AlistBasePath, u.Path[i+len(prefix):])
+		}
+	}
+	return ""
+}
+
+// sameHost 链接是否指向当前服务的主地址或备用地址
+func (g *Generator) sameHost(u *url.URL) bool {
+	for _, base := range []string{g.service.ServiceUrl, g.service.BackupUrl, g.client.BaseURL()} {
+		if b, err := url.Parse(base); err == nil && b.Host != "" && strings.EqualFold(b.Host, u.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+// linkSign 取链接中的 sign 参数，没有时使用清单记录的签名
+func linkSign(link string, entry *model.StrmManifestEntry) string {
+	if u, err := url.Parse(link); err == nil {
+		if s := u.Query().Get("sign"); s != "" {
+			return s
+		}
+	}
+	if entry != nil {
+		return entry.Sign
+	}
+	return ""
+}
+
+// headLink 用 HEAD 请求检查链接，404/410 或无法连接视为失效；
+// 部分网盘不支持 HEAD，其他状态码不作判断
+func headLink(ctx context.Context, link string) error {
+	if link == "" {
+		return fmt.Errorf(".strm 内容为空")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, link, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := headClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return fmt.Errorf("http status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// firstLine 取 .strm 中第一行非空、非注释内容
+func firstLine(content []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return line
+		}
+	}
+	return ""
+}
+
+// reportCheckProgress 按检查报告上报进度
+func (g *Generator) reportCheckProgress(report *CheckReport, dir string, force bool) {
+	if g.progress == nil || (!force && time.Since(g.progressAt) < progressInterval) {
+		return
+	}
+	p := Progress{
+		Phase:      g.phase,
+		CurrentDir: dir,
+		Scanned:    report.Checked,
+		Expected:   len(g.previous),
+		Created:    report.Repaired,
+		Failed:     report.Problems(),
+		StartedAt:  report.StartedAt,
+	}
+	if len(report.Errors) > 0 {
+		p.LastError = report.Errors[len(report.Errors)-1]
+	}
+	g.emitProgress(p)
+}
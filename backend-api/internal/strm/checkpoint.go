@@ -0,0 +1,113 @@
+package strm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+)
+
+var (
+	// ErrPaused 作为 context 的取消原因表示暂停，生成器保留进度供恢复
+	ErrPaused = errors.New("任务已暂停")
+	// ErrCancelled 作为 context 的取消原因表示取消，生成器清理未完成的下载
+	ErrCancelled = errors.New("任务已取消")
+)
+
+// Checkpoint 生成中途停止时的进度，恢复时只遍历未完成的目录，已处理的文件不再重复处理
+type Checkpoint struct {
+	Pending   []string                   `json:"pending"`   // 尚未遍历完的远程目录
+	Pages     map[string]int             `json:"pages"`     // 已处理过部分分页的目录继续时的起始页，未列出的从第 1 页开始
+	Walked    bool                       `json:"walked"`    // 已遍历完成，停止发生在下载伴随文件阶段
+	Entries   []*model.StrmManifestEntry `json:"entries"`   // 已处理的视频文件
+	Downloads []CheckpointDownload       `json:"downloads"` // 已发现的伴随文件，已下载的恢复后按大小和时间跳过
+	Summary   *Summary                   `json:"summary"`
+}
+
+// CheckpointDownload 待下载的伴随文件
+type CheckpointDownload struct {
+	RemotePath string    `json:"remotePath"`
+	LocalPath  string    `json:"localPath"`
+	Sign       string    `json:"sign"`
+	Size       int64     `json:"size"`
+	Modified   time.Time `json:"modified"`
+}
+
+// WithCheckpoint 从暂停时保存的进度继续生成
+func WithCheckpoint(cp *Checkpoint) Option {
+	return func(g *Generator) {
+		g.resume = cp
+	}
+}
+
+// Checkpoint 返回因 context 取消而中途停止时的进度，正常结束、出错或试运行时为 nil
+func (g *Generator) Checkpoint() *Checkpoint {
+	return g.stopped
+}
+
+// restore 载入进度：已处理的文件计入清单并在遍历时跳过
+func (g *Generator) restore(cp *Checkpoint) {
+	if cp.Summary != nil {
+		*g.summary = *cp.Summary
+		if g.summary.Errors == nil {
+			g.summary.Errors = []string{}
+		}
+		if g.summary.OrphanFiles == nil {
+			g.summary.OrphanFiles = []string{}
+		}
+	}
+	g.seen = make(map[string]bool, len(cp.Entries)+len(cp.Downloads))
+	for _, e := range cp.Entries {
+		g.current[e.Path] = e
+		g.localOwners[e.StrmPath] = e.Path
+		g.seen[e.Path] = true
+	}
+	for _, d := range cp.Downloads {
+		rel := RelativePath(g.cfg.AlistBasePath, d.RemotePath)
+		g.downloads = append(g.downloads, &downloadJob{
+			remotePath: d.RemotePath,
+			localPath:  d.LocalPath,
+			sign:       d.Sign,
+			size:       d.Size,
+			modified:   d.Modified,
+		})
+		g.localOwners[d.LocalPath] = rel
+		g.seen[rel] = true
+	}
+}
+
+// snapshot 记录当前进度，unfinished 为尚未遍历完的目录，pages 为其中部分目录的下一页
+func (g *Generator) snapshot(unfinished []string, pages map[string]int, walked bool) *Checkpoint {
+	cp := &Checkpoint{
+		Pending:   unfinished,
+		Pages:     pages,
+		Walked:    walked,
+		Entries:   g.Manifest(),
+		Downloads: make([]CheckpointDownload, 0, len(g.downloads)),
+		Summary:   g.summary,
+	}
+	for _, job := range g.downloads {
+		cp.Downloads = append(cp.Downloads, CheckpointDownload{
+			RemotePath: job.remotePath,
+			LocalPath:  job.localPath,
+			Sign:       job.sign,
+			Size:       job.size,
+			Modified:   job.modified,
+		})
+	}
+	return cp
+}
+
+// cancelled context 是否因取消（而非暂停）结束
+func cancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrCancelled)
+}
+
+// removePart 取消时删除未下载完成的临时文件，暂停时保留用于续传
+func removePart(ctx context.Context, part string) {
+	if cancelled(ctx) {
+		os.Remove(part)
+	}
+}
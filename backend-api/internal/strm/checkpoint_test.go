@@ -0,0 +1,161 @@
+package strm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+)
+
+func TestPauseResumeMidWalk(t *testing.T) {
+	for _, concurrency := range []int{1, 3} {
+		t.Run("concurrency="+strconv.Itoa(concurrency), func(t *testing.T) {
+			setListPageSize(t, 2)
+			fake := newFakeOpenList(t, fakeLibrary("/media", 3, 5))
+			svc := fake.service()
+			svc.WalkConcurrency = concurrency
+			out := t.TempDir()
+			cfg := &model.StrmConfig{ID: 1, AlistBasePath: "/media", StrmOutputPath: out, DownloadEnabled: true}
+
+			ctx, cancel := context.WithCancelCause(context.Background())
+			var once sync.Once
+			fake.setHook(func(api, dir string, page int) {
+				if api == "/api/fs/list" && dir == "/media/ShowB" && page == 2 {
+					once.Do(func() { cancel(ErrPaused) })
+				}
+			})
+			g := NewGenerator(cfg, svc, fake.client())
+			if _, err := g.Run(ctx); err == nil {
+				t.Fatal("首次运行应被暂停")
+			}
+			if !errors.Is(context.Cause(ctx), ErrPaused) {
+				t.Fatalf("cause = %v", context.Cause(ctx))
+			}
+			cp := g.Checkpoint()
+			if cp == nil || cp.Walked || len(cp.Pending) == 0 {
+				t.Fatalf("checkpoint = %+v", cp)
+			}
+			// 与服务层一致，经过 JSON 保存再读取
+			data, err := json.Marshal(cp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var restored Checkpoint
+			if err := json.Unmarshal(data, &restored); err != nil {
+				t.Fatal(err)
+			}
+
+			fake.setHook(nil)
+			g = NewGenerator(cfg, svc, fake.client(), WithCheckpoint(&restored))
+			summary, err := g.Run(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if g.Checkpoint() != nil {
+				t.Error("正常结束时不应返回进度")
+			}
+			if summary.Scanned != 15 || summary.Created != 15 || summary.Downloaded != 3 || summary.Failed != 0 {
+				t.Errorf("summary = scanned %d created %d downloaded %d failed %d, want 15/15/3/0",
+					summary.Scanned, summary.Created, summary.Downloaded, summary.Failed)
+			}
+
+			var paths []string
+			for _, e := range g.Manifest() {
+				paths = append(paths, e.Path)
+			}
+			sort.Strings(paths)
+			if len(paths) != 15 {
+				t.Fatalf("manifest has %d entries: %v", len(paths), paths)
+			}
+			for i, p := range paths {
+				if i > 0 && paths[i-1] == p {
+					t.Errorf("manifest 重复: %s", p)
+				}
+				if !strings.HasSuffix(p, ".mkv") {
+					t.Errorf("manifest 包含非视频文件: %s", p)
+				}
+			}
+
+			var strmFiles, srtFiles int
+			filepath.WalkDir(out, func(p string, d os.DirEntry, err error) error {
+				switch {
+				case err != nil || d.IsDir():
+				case strings.HasSuffix(p, ".strm"):
+					strmFiles++
+				case strings.HasSuffix(p, ".srt"):
+					srtFiles++
+				default:
+					t.Errorf("意外的文件: %s", p)
+				}
+				return nil
+			})
+			if strmFiles != 15 || srtFiles != 3 {
+				t.Errorf("output has %d .strm and %d .srt, want 15 and 3", strmFiles, srtFiles)
+			}
+		})
+	}
+}
+
+func TestPauseResumeSkipsListedPages(t *testing.T) {
+	setListPageSize(t, 2)
+	fake := newFakeOpenList(t, fakeLibrary("/media", 1, 5))
+	svc := fake.service()
+	cfg := &model.StrmConfig{ID: 1, AlistBasePath: "/media", StrmOutputPath: t.TempDir()}
+
+	// 处理第 3 页的第一个文件时暂停，此前的两页已回调完毕
+	ctx, cancel := context.WithCancelCause(context.Background())
+	fake.setHook(func(api, p string, page int) {
+		if api == "/api/fs/get" && p == "/media/ShowA/ShowA.e.mkv" {
+			cancel(ErrPaused)
+		}
+	})
+	g := NewGenerator(cfg, svc, fake.client())
+	g.Run(ctx)
+	cp := g.Checkpoint()
+	if cp == nil {
+		t.Fatal("应返回进度")
+	}
+	if len(cp.Pending) != 1 || cp.Pending[0] != "/media/ShowA" {
+		t.Fatalf("pending = %v", cp.Pending)
+	}
+	if page := cp.Pages["/media/ShowA"]; page != 3 {
+		t.Fatalf("pages = %v", cp.Pages)
+	}
+
+	fake.setHook(nil)
+	g = NewGenerator(cfg, svc, fake.client(), WithCheckpoint(cp))
+	summary, err := g.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for page := 1; page <= 2; page++ {
+		if n := fake.listCount("/media/ShowA", page); n != 1 {
+			t.Errorf("第 %d 页拉取了 %d 次，恢复后不应重新拉取", page, n)
+		}
+	}
+	if summary.Scanned != 5 || len(g.Manifest()) != 5 {
+		t.Errorf("scanned = %d, manifest = %d, want 5", summary.Scanned, len(g.Manifest()))
+	}
+}
+
+func TestCancelledWalkReturnsNoCheckpointForDryRun(t *testing.T) {
+	fake := newFakeOpenList(t, fakeLibrary("/media", 2, 2))
+	cfg := &model.StrmConfig{ID: 1, AlistBasePath: "/media", StrmOutputPath: t.TempDir()}
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(ErrCancelled)
+	g := NewGenerator(cfg, fake.service(), fake.client(), WithDryRun())
+	if _, err := g.Run(ctx); err == nil {
+		t.Fatal("应返回错误")
+	}
+	if g.Checkpoint() != nil {
+		t.Error("试运行不应返回进度")
+	}
+}
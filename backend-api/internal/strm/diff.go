@@ -0,0 +1,54 @@
+package strm
+
+// DiffAction 试运行报告中的操作类型
+type DiffAction string
+
+const (
+	DiffCreate     DiffAction = "create"
+	DiffUpdate     DiffAction = "update"
+	DiffDelete     DiffAction = "delete"
+	DiffQuarantine DiffAction = "quarantine"
+	DiffDownload   DiffAction = "download"
+)
+
+// maxDiffItems 试运行报告最多保留的操作明细数，超出部分只计数，避免大型媒体库占用过多内存
+var maxDiffItems = 10000
+
+// DiffItem 试运行报告中的单个文件操作
+type DiffItem struct {
+	Action     DiffAction `json:"action"`
+	RemotePath string     `json:"remotePath,omitempty"`
+	LocalPath  string     `json:"localPath"`
+	Content    string     `json:"content,omitempty"` // create/update 时为将写入的链接
+}
+
+// WithDryRun 试运行：完整遍历和比对，但不写入、下载或删除任何文件，操作记录在 Diff 中
+func WithDryRun() Option {
+	return func(g *Generator) {
+		g.dryRun = true
+	}
+}
+
+// Diff 返回试运行记录的操作列表，超出上限的操作不保留明细
+func (g *Generator) Diff() []*DiffItem {
+	return g.diff
+}
+
+// DiffCounts 返回试运行各类操作的数量，包括超出上限未保留明细的操作
+func (g *Generator) DiffCounts() map[DiffAction]int {
+	return g.diffCounts
+}
+
+// DiffTruncated 操作明细是否因超出上限被截断
+func (g *Generator) DiffTruncated() bool {
+	return g.diffTruncated
+}
+
+func (g *Generator) record(action DiffAction, remotePath, localPath, content string) {
+	g.diffCounts[action]++
+	if len(g.diff) >= maxDiffItems {
+		g.diffTruncated = true
+		return
+	}
+	g.diff = append(g.diff, &DiffItem{Action: action, RemotePath: remotePath, LocalPath: localPath, Content: content})
+}
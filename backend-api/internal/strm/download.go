@@ -0,0 +1,146 @@
+package strm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"go.uber.org/zap"
+)
+
+// partSuffix 未下载完成的临时文件后缀，下次运行时断点续传
+const partSuffix = ".part"
+
+// downloadJob 待下载的伴随文件
+type downloadJob struct {
+	remotePath string
+	localPath  string
+	sign       string
+	size       int64
+	modified   time.Time
+}
+
+// downloadCompanions 依次下载伴随文件，每次实际下载后按 DownloadInterval（秒）间隔等待
+func (g *Generator) downloadCompanions(ctx context.Context) error {
+	interval := time.Duration(g.cfg.DownloadInterval) * time.Second
+	for i, job := range g.downloads {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		g.downloadsDone = i
+		g.currentDir = path.Dir(job.remotePath)
+		g.reportProgress(false)
+		if localUpToDate(job) {
+			g.summary.DownloadSkipped++
+			continue
+		}
+		if g.dryRun {
+			g.summary.Downloaded++
+			g.record(DiffDownload, job.remotePath, job.localPath, "")
+			continue
+		}
+		if err := g.download(ctx, job); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			logger.Error("[Strm] 下载伴随文件失败", zap.String("path", job.remotePath), zap.Error(err))
+			g.summary.addError("%s: %v", job.remotePath, err)
+		} else {
+			g.summary.Downloaded++
+		}
+		if interval > 0 && i < len(g.downloads)-1 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(interval):
+			}
+		}
+	}
+	g.downloadsDone = len(g.downloads)
+	return nil
+}
+
+// localUpToDate 本地文件大小与修改时间均与远程一致
+func localUpToDate(job *downloadJob) bool {
+	info, err := os.Stat(job.localPath)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	return info.Size() == job.size && info.ModTime().Unix() == job.modified.Unix()
+}
+
+// download 下载单个文件到 .part，设置权限和修改时间后重命名；
+// 已有 .part 且中断时记录的远程修改时间与当前一致时使用 Range 续传，否则从头下载
+func (g *Generator) download(ctx context.Context, job *downloadJob) error {
+	if err := g.perm.mkdirAll(filepath.Dir(job.localPath)); err != nil {
+		return err
+	}
+	part := job.localPath + partSuffix
+	var offset int64
+	if info, err := os.Stat(part); err == nil && info.Size() < job.size && info.ModTime().Unix() == job.modified.Unix() {
+		offset = info.Size()
+	}
+	res, err := g.client.Open(ctx, job.remotePath, job.sign, offset)
+	if err != nil {
+		return err
+	}
+	defer func() { res.Body.Close() }()
+
+	// 服务端不支持 Range 时返回 200，从头下载
+	flag := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if offset > 0 && res.StatusCode == http.StatusPartialContent {
+		if validContentRange(res.Header.Get("Content-Range"), offset, job.size) {
+			flag = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		} else {
+			// 返回的范围与 .part 对不上，说明远程文件已变化，重新完整下载
+			logger.Warn("[Strm] 续传范围不匹配，重新下载", zap.String("path", job.remotePath), zap.String("content_range", res.Header.Get("Content-Range")))
+			res.Body.Close()
+			if res, err = g.client.Open(ctx, job.remotePath, job.sign, 0); err != nil {
+				return err
+			}
+		}
+	}
+	f, err := os.OpenFile(part, flag, g.perm.FileMode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, res.Body); err != nil {
+		f.Close()
+		if ctx.Err() != nil {
+			removePart(ctx, part)
+		}
+		// 记录 .part 对应的远程修改时间，下次续传前据此判断远程文件是否变化
+		if !job.modified.IsZero() {
+			os.Chtimes(part, job.modified, job.modified)
+		}
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	// 权限和修改时间在重命名前设置，文件出现时即为最终状态
+	if err := g.perm.apply(part, g.perm.FileMode); err != nil {
+		return err
+	}
+	if !job.modified.IsZero() {
+		if err := os.Chtimes(part, job.modified, job.modified); err != nil {
+			return err
+		}
+	}
+	return os.Rename(part, job.localPath)
+}
+
+// validContentRange 206 响应的 Content-Range 是否从 offset 开始且总大小与远程一致
+func validContentRange(header string, offset, size int64) bool {
+	var start, end, total int64
+	if _, err := fmt.Sscanf(header, "bytes %d-%d/%d", &start, &end, &total); err != nil {
+		return false
+	}
+	return start == offset && total == size
+}
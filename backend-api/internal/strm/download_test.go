@@ -0,0 +1,134 @@
+package strm
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/openlist"
+)
+
+// rangeServer 支持 Range 的 /d/ 下载服务，badRange 为 true 时返回错误的 Content-Range
+type rangeServer struct {
+	*httptest.Server
+	content  []byte
+	badRange bool
+	mutex    sync.Mutex
+	ranges   []string
+}
+
+func newRangeServer(t *testing.T, content []byte) *rangeServer {
+	t.Helper()
+	s := &rangeServer{content: content}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mutex.Lock()
+		s.ranges = append(s.ranges, r.Header.Get("Range"))
+		bad := s.badRange
+		s.mutex.Unlock()
+		if bad && r.Header.Get("Range") != "" {
+			w.Header().Set("Content-Range", "bytes 0-9/999")
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(s.content[:10])
+			return
+		}
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(s.content))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *rangeServer) requests() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string(nil), s.ranges...)
+}
+
+func newDownloadGenerator(t *testing.T, serverURL string) *Generator {
+	t.Helper()
+	svc := &model.OpenListService{ServiceUrl: serverURL}
+	g := NewGenerator(&model.StrmConfig{StrmOutputPath: t.TempDir()}, svc, openlist.NewClient(svc))
+	g.perm = &Permissions{FileMode: DefaultFileMode, DirMode: DefaultDirMode, UID: -1, GID: -1}
+	return g
+}
+
+func TestDownloadResume(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 10))
+	modified := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name      string
+		partMtime time.Time
+		partData  []byte
+		badRange  bool
+		wantRange []string
+	}{
+		// .part 记录的修改时间与远程一致时从已下载处续传
+		{"resume", modified, content[:40], false, []string{"bytes=40-"}},
+		// 远程修改时间已变化，丢弃 .part 从头下载
+		{"remote changed", modified.Add(time.Hour), []byte(strings.Repeat("x", 40)), false, []string{""}},
+		// 返回的范围与请求不符时重新完整下载
+		{"bad content range", modified, content[:40], true, []string{"bytes=40-", ""}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newRangeServer(t, content)
+			server.badRange = tc.badRange
+			g := newDownloadGenerator(t, server.URL)
+			job := &downloadJob{
+				remotePath: "/media/Movie.srt",
+				localPath:  filepath.Join(g.cfg.StrmOutputPath, "Movie.srt"),
+				size:       int64(len(content)),
+				modified:   modified,
+			}
+			part := job.localPath + partSuffix
+			if err := os.WriteFile(part, tc.partData, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(part, tc.partMtime, tc.partMtime); err != nil {
+				t.Fatal(err)
+			}
+			if err := g.download(context.Background(), job); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(job.localPath)
+			if err != nil || !bytes.Equal(data, content) {
+				t.Errorf("content = %q, %v", data, err)
+			}
+			if got := server.requests(); strings.Join(got, ",") != strings.Join(tc.wantRange, ",") {
+				t.Errorf("ranges = %q, want %q", got, tc.wantRange)
+			}
+			if _, err := os.Stat(part); !os.IsNotExist(err) {
+				t.Error(".part 应在完成后重命名")
+			}
+			info, _ := os.Stat(job.localPath)
+			if !info.ModTime().Equal(modified) {
+				t.Errorf("mtime = %v", info.ModTime())
+			}
+		})
+	}
+}
+
+func TestValidContentRange(t *testing.T) {
+	cases := []struct {
+		header string
+		want   bool
+	}{
+		{"bytes 40-99/100", true},
+		{"bytes 0-99/100", false},
+		{"bytes 40-99/200", false},
+		{"bytes 40-99/*", false},
+		{"", false},
+	}
+	for _, tc := range cases {
+		if got := validContentRange(tc.header, 40, 100); got != tc.want {
+			t.Errorf("validContentRange(%q) = %v, want %v", tc.header, got, tc.want)
+		}
+	}
+}
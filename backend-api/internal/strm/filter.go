@@ -0,0 +1,178 @@
+package strm
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/openlist"
+)
+
+// regexPrefix 排除规则以该前缀开头时按正则表达式处理，否则按 glob 处理
+const regexPrefix = "re:"
+
+// Extensions 全局默认扩展名，来自字典表
+type Extensions struct {
+	Video    []string
+	Subtitle []string
+	Image    []string
+}
+
+// DefaultExtensions 内置默认扩展名
+func DefaultExtensions() Extensions {
+	return Extensions{Video: DefaultVideoExts, Subtitle: DefaultSubtitleExts, Image: DefaultImageExts}
+}
+
+// Filter 按扩展名、大小和排除规则筛选远程文件
+type Filter struct {
+	video     map[string]bool
+	companion map[string]bool
+	exclude   map[string]bool
+	minSize   int64
+	maxSize   int64
+	patterns  []*pathPattern
+}
+
+// pathPattern 排除规则，不含 / 的 glob 只匹配文件/目录名
+type pathPattern struct {
+	re       *regexp.Regexp
+	baseOnly bool
+}
+
+// NewFilter 根据配置和全局默认扩展名创建过滤器；配置了 IncludeExts 时替代默认视频扩展名
+func NewFilter(cfg *model.StrmConfig, defaults Extensions) (*Filter, error) {
+	f := &Filter{
+		video:     extSet(defaults.Video),
+		companion: extSet(append(append(append([]string{}, defaults.Subtitle...), defaults.Image...), metadataExts...)),
+		exclude:   extSet(SplitList(cfg.ExcludeExts)),
+		minSize:   cfg.MinFileSize,
+		maxSize:   cfg.MaxFileSize,
+	}
+	if include := SplitList(cfg.IncludeExts); len(include) > 0 {
+		f.video = extSet(include)
+	}
+	for _, line := range SplitList(cfg.ExcludePatterns) {
+		p, err := compilePattern(line)
+		if err != nil {
+			return nil, err
+		}
+		f.patterns = append(f.patterns, p)
+	}
+	return f, nil
+}
+
+// ValidateFilter 校验配置中的排除规则可以编译
+func ValidateFilter(cfg *model.StrmConfig) error {
+	_, err := NewFilter(cfg, DefaultExtensions())
+	return err
+}
+
+// SkipDir 目录匹配排除规则时不再遍历，rel 为相对 alistBasePath 的路径；
+// 带或不带结尾 / 匹配均可，如 Extras/** 和 **/Trailers
+func (f *Filter) SkipDir(rel string) bool {
+	return f.excluded(rel+"/") || f.excluded(rel)
+}
+
+// IsVideo 文件是否按配置生成 .strm：扩展名、大小、排除规则
+func (f *Filter) IsVideo(rel string, obj openlist.Object) bool {
+	ext := strings.ToLower(path.Ext(obj.Name))
+	if !f.video[ext] || f.exclude[ext] {
+		return false
+	}
+	if f.minSize > 0 && obj.Size < f.minSize {
+		return false
+	}
+	if f.maxSize > 0 && obj.Size > f.maxSize {
+		return false
+	}
+	return !f.excluded(rel)
+}
+
+// IsCompanion 文件是否为需要下载的伴随文件，不受大小限制
+func (f *Filter) IsCompanion(rel string, obj openlist.Object) bool {
+	ext := strings.ToLower(path.Ext(obj.Name))
+	if !f.companion[ext] || f.exclude[ext] {
+		return false
+	}
+	return !f.excluded(rel)
+}
+
+func (f *Filter) excluded(rel string) bool {
+	for _, p := range f.patterns {
+		target := rel
+		if p.baseOnly {
+			target = path.Base(strings.TrimSuffix(rel, "/"))
+		}
+		if p.re.MatchString(target) {
+			return true
+		}
+	}
+	return false
+}
+
+// compilePattern 编译排除规则：re: 前缀为正则，其余为 glob（支持 **、*、?），均不区分大小写
+func compilePattern(line string) (*pathPattern, error) {
+	if strings.HasPrefix(line, regexPrefix) {
+		re, err := regexp.Compile("(?i)" + strings.TrimPrefix(line, regexPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("正则 %q 无效: %v", line, err)
+		}
+		return &pathPattern{re: re}, nil
+	}
+	glob := strings.TrimPrefix(line, "/")
+	var sb strings.Builder
+	sb.WriteString("(?i)^")
+	for i := 0; i < len(glob); i++ {
+		ch := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case ch == '*':
+			sb.WriteString("[^/]*")
+		case ch == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	sb.WriteString("$")
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return nil, fmt.Errorf("规则 %q 无效: %v", line, err)
+	}
+	return &pathPattern{re: re, baseOnly: !strings.Contains(glob, "/")}, nil
+}
+
+// SplitList 拆分以逗号或换行分隔的列表，忽略空项
+func SplitList(s string) []string {
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' || r == '\r' })
+	list := make([]string, 0, len(fields))
+	for _, v := range fields {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// extSet 扩展名统一为小写并补全开头的 .
+func extSet(exts []string) map[string]bool {
+	set := make(map[string]bool, len(exts))
+	for _, e := range exts {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if e == "" {
+			continue
+		}
+		if !strings.HasPrefix(e, ".") {
+			e = "." + e
+		}
+		set[e] = true
+	}
+	return set
+}
@@ -0,0 +1,138 @@
+package strm
+
+import (
+	"testing"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/openlist"
+)
+
+func TestCompilePattern(t *testing.T) {
+	cases := []struct {
+		pattern  string
+		baseOnly bool
+		match    []string
+		noMatch  []string
+	}{
+		// 不含 / 的 glob 只匹配文件/目录名，不区分大小写
+		{"*.sample.mkv", true, []string{"A.sample.mkv", "A.SAMPLE.MKV"}, []string{"A.mkv", "sample.mkv.bak"}},
+		{"?.mkv", true, []string{"a.mkv"}, []string{"ab.mkv", ".mkv"}},
+		{"Extras", true, []string{"extras"}, []string{"Extras2", "My Extras"}},
+		// 含 / 的 glob 匹配相对路径，开头的 / 被忽略，* 和 ? 不跨目录
+		{"/Movies/*.iso", false, []string{"Movies/a.iso"}, []string{"X/Movies/a.iso", "Movies/sub/a.iso"}},
+		{"Movies/?/*.mkv", false, []string{"Movies/a/b.mkv"}, []string{"Movies/ab/b.mkv"}},
+		// ** 匹配任意层级
+		{"Extras/**", false, []string{"Extras/", "Extras/a.mkv", "Extras/x/y/a.mkv"}, []string{"A/Extras/a.mkv"}},
+		{"**/Trailers/*", false, []string{"Trailers/a.mkv", "A/B/Trailers/a.mkv", "A/Trailers/"}, []string{"A/Trailers/x/a.mkv", "ATrailers/a.mkv"}},
+		{"Show/**.nfo", false, []string{"Show/a.nfo", "Show/S01/a.nfo"}, []string{"Other/a.nfo"}},
+		// 正则元字符在 glob 中按字面匹配
+		{"a+b(c).mkv", true, []string{"a+b(c).mkv"}, []string{"aab(c).mkv", "a+bc.mkv"}},
+		// re: 前缀按正则匹配完整相对路径，不区分大小写
+		{`re:\bsample\b`, false, []string{"A/Sample/x.mkv", "A/x.sample.mkv"}, []string{"A/samples/x.mkv"}},
+		{`re:^Movies/.*\.iso$`, false, []string{"movies/a/b.iso"}, []string{"TV/Movies/a.iso"}},
+	}
+	for _, tc := range cases {
+		p, err := compilePattern(tc.pattern)
+		if err != nil {
+			t.Errorf("compilePattern(%q): %v", tc.pattern, err)
+			continue
+		}
+		if p.baseOnly != tc.baseOnly {
+			t.Errorf("compilePattern(%q) baseOnly = %v", tc.pattern, p.baseOnly)
+		}
+		for _, s := range tc.match {
+			if !p.re.MatchString(s) {
+				t.Errorf("%q 应匹配 %q (%s)", tc.pattern, s, p.re)
+			}
+		}
+		for _, s := range tc.noMatch {
+			if p.re.MatchString(s) {
+				t.Errorf("%q 不应匹配 %q (%s)", tc.pattern, s, p.re)
+			}
+		}
+	}
+	if _, err := compilePattern("re:("); err == nil {
+		t.Error("无效正则应报错")
+	}
+}
+
+func TestFilterSkipDir(t *testing.T) {
+	f, err := NewFilter(&model.StrmConfig{ExcludePatterns: "Extras\n**/Trailers\nre:^TV/Old\nMovies/*/Featurettes/**"}, DefaultExtensions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		rel  string
+		want bool
+	}{
+		{"Extras", true},
+		{"Show/extras", true},
+		{"Show/Extras2", false},
+		{"Trailers", true},
+		{"Show/S01/Trailers", true},
+		{"Show/Trailers2", false},
+		{"TV/Old", true},
+		{"TV/Older", true}, // 正则未锚定结尾
+		{"Archive/TV/Old", false},
+		{"Movies/A/Featurettes", true},
+		{"Movies/A/B/Featurettes", false},
+		{"Movies", false},
+		{"", false},
+	}
+	for _, tc := range cases {
+		if got := f.SkipDir(tc.rel); got != tc.want {
+			t.Errorf("SkipDir(%q) = %v, want %v", tc.rel, got, tc.want)
+		}
+	}
+}
+
+func TestFilterFiles(t *testing.T) {
+	cfg := &model.StrmConfig{
+		IncludeExts:     "mkv, .MP4",
+		ExcludeExts:     ".srt",
+		MinFileSize:     100,
+		MaxFileSize:     1000,
+		ExcludePatterns: "*.sample.*",
+	}
+	f, err := NewFilter(cfg, DefaultExtensions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	video := []struct {
+		rel  string
+		size int64
+		want bool
+	}{
+		{"A/a.mkv", 500, true},
+		{"A/a.MP4", 500, true},
+		{"A/a.avi", 500, false}, // 配置了 IncludeExts 时不使用默认视频扩展名
+		{"A/a.mkv", 99, false},
+		{"A/a.mkv", 1001, false},
+		{"A/a.mkv", 100, true},
+		{"A/a.sample.mkv", 500, false},
+	}
+	for _, tc := range video {
+		obj := openlist.Object{Name: tc.rel[len("A/"):], Size: tc.size}
+		if got := f.IsVideo(tc.rel, obj); got != tc.want {
+			t.Errorf("IsVideo(%q, %d) = %v, want %v", tc.rel, tc.size, got, tc.want)
+		}
+	}
+	companion := []struct {
+		rel  string
+		want bool
+	}{
+		{"A/a.ass", true},
+		{"A/a.nfo", true},
+		{"A/poster.JPG", true},
+		{"A/a.srt", false}, // ExcludeExts 同样作用于伴随文件
+		{"A/a.sample.ass", false},
+		{"A/a.txt", false},
+	}
+	for _, tc := range companion {
+		// 伴随文件不受大小限制
+		obj := openlist.Object{Name: tc.rel[len("A/"):], Size: 1 << 30}
+		if got := f.IsCompanion(tc.rel, obj); got != tc.want {
+			t.Errorf("IsCompanion(%q) = %v, want %v", tc.rel, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package strm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+)
+
+const (
+	DefaultFileMode os.FileMode = 0644
+	DefaultDirMode  os.FileMode = 0755
+)
+
+// Permissions 输出文件和目录的权限及属主，UID/GID 为 -1 时不修改
+type Permissions struct {
+	FileMode os.FileMode
+	DirMode  os.FileMode
+	UID      int
+	GID      int
+}
+
+// ParsePermissions 解析配置的 FileMode/DirMode（八进制，如 0644）和 FileOwner（uid:gid），未配置时使用默认值
+func ParsePermissions(cfg *model.StrmConfig) (*Permissions, error) {
+	perm := &Permissions{FileMode: DefaultFileMode, DirMode: DefaultDirMode, UID: -1, GID: -1}
+	var err error
+	if perm.FileMode, err = parseMode(cfg.FileMode, DefaultFileMode); err != nil {
+		return nil, fmt.Errorf("fileMode %v", err)
+	}
+	if perm.DirMode, err = parseMode(cfg.DirMode, DefaultDirMode); err != nil {
+		return nil, fmt.Errorf("dirMode %v", err)
+	}
+	if owner := strings.TrimSpace(cfg.FileOwner); owner != "" {
+		uid, gid, ok := strings.Cut(owner, ":")
+		if perm.UID, err = strconv.Atoi(uid); err != nil || perm.UID < 0 {
+			return nil, errors.New("fileOwner 格式应为 uid:gid")
+		}
+		perm.GID = perm.UID
+		if ok {
+			if perm.GID, err = strconv.Atoi(gid); err != nil || perm.GID < 0 {
+				return nil, errors.New("fileOwner 格式应为 uid:gid")
+			}
+		}
+	}
+	return perm, nil
+}
+
+func parseMode(s string, def os.FileMode) (os.FileMode, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return def, nil
+	}
+	v, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || v > 0777 {
+		return 0, errors.New("须为八进制权限，如 0644")
+	}
+	return os.FileMode(v), nil
+}
+
+// apply 设置文件或目录的权限和属主，不受 umask 影响
+func (p *Permissions) apply(name string, mode os.FileMode) error {
+	if err := os.Chmod(name, mode); err != nil {
+		return err
+	}
+	if p.UID >= 0 {
+		return os.Lchown(name, p.UID, p.GID)
+	}
+	return nil
+}
+
+// mkdirAll 逐级创建目录，新建的目录按配置设置权限和属主，已存在的目录保持不变
+func (p *Permissions) mkdirAll(dir string) error {
+	info, err := os.Stat(dir)
+	if err == nil {
+		if !info.IsDir() {
+			return fmt.Errorf("%s 不是目录", dir)
+		}
+		return nil
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+	if parent := filepath.Dir(dir); parent != dir {
+		if err := p.mkdirAll(parent); err != nil {
+			return err
+		}
+	}
+	if err := os.Mkdir(dir, p.DirMode); err != nil {
+		if os.IsExist(err) {
+			return nil
+		}
+		return err
+	}
+	return p.apply(dir, p.DirMode)
+}
+
+// writeFile 先写入同目录下的临时文件再重命名，媒体服务器不会读到写了一半的文件
+func (p *Permissions) writeFile(name string, content []byte) error {
+	dir := filepath.Dir(name)
+	if err := p.mkdirAll(dir); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := p.apply(tmpName, p.FileMode); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, name); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
+
+// PathsOverlap 两个目录相同或互相包含
+func PathsOverlap(a, b string) bool {
+	a, b = absPath(a), absPath(b)
+	return a == b || within(a, b) || within(b, a)
+}
+
+func absPath(p string) string {
+	if abs, err := filepath.Abs(p); err == nil {
+		p = abs
+	}
+	// 目录已存在时解析符号链接，避免通过链接绕过重叠检查
+	if real, err := filepath.EvalSymlinks(p); err == nil {
+		p = real
+	}
+	return filepath.Clean(p)
+}
+
+// CheckWritable 检查输出目录可写；目录尚不存在时检查最近的已存在上级目录。
+// 配置了属主时同时检查测试文件能否改为该属主，避免保存后每次生成都因 chown 失败
+func CheckWritable(dir string, perm *Permissions) error {
+	if !filepath.IsAbs(dir) {
+		return errors.New("须为绝对路径")
+	}
+	existing := filepath.Clean(dir)
+	for {
+		info, err := os.Stat(existing)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s 不是目录", existing)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return fmt.Errorf("%s 不存在", dir)
+		}
+		existing = parent
+	}
+	f, err := os.CreateTemp(existing, ".strm-write-test-*")
+	if err != nil {
+		return fmt.Errorf("%s 不可写: %v", existing, err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+	if perm != nil && perm.UID >= 0 {
+		if err := os.Lchown(f.Name(), perm.UID, perm.GID); err != nil {
+			return fmt.Errorf("无法将文件属主设为 %d:%d: %v", perm.UID, perm.GID, err)
+		}
+	}
+	return nil
+}
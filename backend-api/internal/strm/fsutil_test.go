@@ -0,0 +1,221 @@
+package strm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+)
+
+func TestParseMode(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    os.FileMode
+		wantErr bool
+	}{
+		{"", DefaultFileMode, false},
+		{"  ", DefaultFileMode, false},
+		{"0644", 0644, false},
+		{"644", 0644, false},
+		{"0600", 0600, false},
+		{"0777", 0777, false},
+		{"1777", 0, true}, // 不支持特殊权限位
+		{"0800", 0, true},
+		{"rw-r--r--", 0, true},
+		{"-1", 0, true},
+	}
+	for _, tc := range cases {
+		got, err := parseMode(tc.in, DefaultFileMode)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("parseMode(%q) err = %v, wantErr %v", tc.in, err, tc.wantErr)
+			continue
+		}
+		if !tc.wantErr && got != tc.want {
+			t.Errorf("parseMode(%q) = %o, want %o", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestParsePermissionsOwner(t *testing.T) {
+	cases := []struct {
+		owner    string
+		uid, gid int
+		wantErr  bool
+	}{
+		{"", -1, -1, false},
+		{"1000:100", 1000, 100, false},
+		{"1000", 1000, 1000, false},
+		{" 0:0 ", 0, 0, false},
+		{"abc:1", 0, 0, true},
+		{"1:abc", 0, 0, true},
+		{"-1:0", 0, 0, true},
+		{"1000:", 0, 0, true},
+	}
+	for _, tc := range cases {
+		perm, err := ParsePermissions(&model.StrmConfig{FileOwner: tc.owner})
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParsePermissions(%q) err = %v, wantErr %v", tc.owner, err, tc.wantErr)
+			continue
+		}
+		if !tc.wantErr && (perm.UID != tc.uid || perm.GID != tc.gid) {
+			t.Errorf("ParsePermissions(%q) = %d:%d, want %d:%d", tc.owner, perm.UID, perm.GID, tc.uid, tc.gid)
+		}
+	}
+}
+
+func TestPathsOverlap(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want bool
+	}{
+		{"/a/b", "/a/b", true},
+		{"/a/b", "/a/b/", true},
+		{"/a/b", "/a/b/c", true},
+		{"/a/b/c", "/a/b", true},
+		{"/a/b", "/a/bc", false},
+		{"/a/bc", "/a/b", false},
+		{"/a/b", "/a/c", false},
+		{"/a/b/../c", "/a/c/d", true},
+	}
+	for _, tc := range cases {
+		if got := PathsOverlap(tc.a, tc.b); got != tc.want {
+			t.Errorf("PathsOverlap(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestPathsOverlapSymlink(t *testing.T) {
+	root := t.TempDir()
+	real := filepath.Join(root, "real")
+	if err := os.MkdirAll(filepath.Join(real, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(root, "link")
+	if err := os.Symlink(real, link); err != nil {
+		t.Skip("不支持符号链接:", err)
+	}
+	if !PathsOverlap(link, filepath.Join(real, "sub")) {
+		t.Error("通过符号链接指向的目录应视为重叠")
+	}
+	if !PathsOverlap(filepath.Join(link, "sub"), real) {
+		t.Error("符号链接下的子目录应视为重叠")
+	}
+	if PathsOverlap(link, filepath.Join(root, "other")) {
+		t.Error("不相关的目录不应重叠")
+	}
+}
+
+func TestCheckWritable(t *testing.T) {
+	root := t.TempDir()
+	if err := CheckWritable("relative/path", nil); err == nil {
+		t.Error("相对路径应报错")
+	}
+	if err := CheckWritable(root, nil); err != nil {
+		t.Errorf("已存在的目录: %v", err)
+	}
+	// 目录尚不存在时检查最近的上级目录，且不创建目录
+	missing := filepath.Join(root, "x", "y")
+	if err := CheckWritable(missing, nil); err != nil {
+		t.Errorf("不存在的子目录: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "x")); !os.IsNotExist(err) {
+		t.Error("检查时不应创建目录")
+	}
+	file := filepath.Join(root, "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckWritable(filepath.Join(file, "sub"), nil); err == nil {
+		t.Error("上级为文件时应报错")
+	}
+	entries, _ := os.ReadDir(root)
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".strm-write-test-") {
+			t.Errorf("测试文件未删除: %s", e.Name())
+		}
+	}
+
+	if os.Geteuid() != 0 {
+		readonly := filepath.Join(root, "readonly")
+		if err := os.Mkdir(readonly, 0555); err != nil {
+			t.Fatal(err)
+		}
+		if err := CheckWritable(readonly, nil); err == nil {
+			t.Error("只读目录应报错")
+		}
+	}
+}
+
+func TestCheckWritableOwner(t *testing.T) {
+	root := t.TempDir()
+	// 当前用户总能把文件属主设为自己
+	self := &Permissions{UID: os.Geteuid(), GID: os.Getegid()}
+	if err := CheckWritable(root, self); err != nil {
+		t.Errorf("设为当前用户: %v", err)
+	}
+	other := &Permissions{UID: 54321, GID: 54321}
+	err := CheckWritable(root, other)
+	if os.Geteuid() == 0 {
+		if err != nil {
+			t.Errorf("root 应能修改属主: %v", err)
+		}
+	} else if err == nil {
+		t.Error("非 root 用户修改为其他属主应报错")
+	}
+	entries, _ := os.ReadDir(root)
+	if len(entries) != 0 {
+		t.Errorf("测试文件未删除: %v", entries)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	root := t.TempDir()
+	perm := &Permissions{FileMode: 0600, DirMode: 0750, UID: -1, GID: -1}
+	name := filepath.Join(root, "a", "b", "Movie.strm")
+	if err := perm.writeFile(name, []byte("http://one")); err != nil {
+		t.Fatal(err)
+	}
+	if err := perm.writeFile(name, []byte("http://two")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(name)
+	if err != nil || string(data) != "http://two" {
+		t.Fatalf("content = %q, %v", data, err)
+	}
+	info, _ := os.Stat(name)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("file mode = %o", info.Mode().Perm())
+	}
+	for _, dir := range []string{filepath.Join(root, "a"), filepath.Join(root, "a", "b")} {
+		info, _ := os.Stat(dir)
+		if info.Mode().Perm() != 0750 {
+			t.Errorf("%s mode = %o", dir, info.Mode().Perm())
+		}
+	}
+	// 重命名后不留临时文件
+	entries, _ := os.ReadDir(filepath.Dir(name))
+	if len(entries) != 1 {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("entries = %v", names)
+	}
+
+	// 目标位置是目录时重命名失败，原目录不受影响且临时文件被清理
+	blocked := filepath.Join(root, "blocked.strm")
+	if err := os.MkdirAll(filepath.Join(blocked, "keep"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := perm.writeFile(blocked, []byte("x")); err == nil {
+		t.Fatal("目标为非空目录时应报错")
+	}
+	entries, _ = os.ReadDir(root)
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".tmp") {
+			t.Errorf("临时文件未清理: %s", e.Name())
+		}
+	}
+}
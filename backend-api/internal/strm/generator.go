@@ -0,0 +1,446 @@
+package strm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/openlist"
+	"go.uber.org/zap"
+)
+
+// maxSummaryErrors 运行结果中最多保留的错误条数
+const maxSummaryErrors = 100
+
+// Summary STRM 生成运行结果
+type Summary struct {
+	ConfigID        int       `json:"configId"`
+	Scanned         int       `json:"scanned"` // 扫描到的视频文件数
+	Created         int       `json:"created"`
+	Updated         int       `json:"updated"`
+	Skipped         int       `json:"skipped"`
+	Orphans         int       `json:"orphans"`         // 远程已不存在的本地文件数（含伴随文件）
+	Deleted         int       `json:"deleted"`         // 已删除的孤立文件数
+	Quarantined     int       `json:"quarantined"`     // 已移入隔离目录的孤立文件数
+	OrphanAborted   bool      `json:"orphanAborted"`   // 孤立文件占比超过安全阈值或扫描输出目录出错，已中止清理
+	OrphanFiles     []string  `json:"orphanFiles"`     // 孤立文件列表（截断）
+	DryRun          bool      `json:"dryRun"`          // 试运行，各计数为将要执行的操作数
+	Downloaded      int       `json:"downloaded"`      // 已下载的伴随文件数
+	Resigned        int       `json:"resigned"`        // 签名即将过期而重新生成的 .strm 数
+	DownloadSkipped int       `json:"downloadSkipped"` // 本地已是最新而跳过下载的伴随文件数
+	Failed          int       `json:"failed"`
+	Errors          []string  `json:"errors"`
+	UsedURL         string    `json:"usedUrl"` // 本次运行实际使用的 OpenList 地址
+	StartedAt       time.Time `json:"startedAt"`
+	FinishedAt      time.Time `json:"finishedAt"`
+}
+
+func (s *Summary) addError(format string, args ...interface{}) {
+	s.Failed++
+	if len(s.Errors) < maxSummaryErrors {
+		s.Errors = append(s.Errors, fmt.Sprintf(format, args...))
+	}
+}
+
+// Generator 遍历 OpenList 远程目录并在本地生成 .strm 文件
+type Generator struct {
+	cfg     *model.StrmConfig
+	service *model.OpenListService
+	client  *openlist.Client
+	summary *Summary
+
+	// previous 上次成功扫描的清单，current 本次扫描的清单，均以相对路径为 key
+	previous map[string]*model.StrmManifestEntry
+	current  map[string]*model.StrmManifestEntry
+
+	// downloads 本次扫描发现的伴随文件，仅 DownloadEnabled 时收集
+	downloads []*downloadJob
+
+	// signSecret 解密后的签名密钥，signRenewBefore 签名剩余有效期低于该值时重新签名
+	signSecret      string
+	signRenewBefore time.Duration
+	signProbe       int
+	// signProbeFailures /api/fs/get 获取签名连续失败的次数
+	signProbeFailures int
+
+	// playBaseURL 本服务对外访问地址，用于 {play_url}
+	playBaseURL string
+
+	// extensions 全局默认扩展名，filter 由配置和 extensions 在 Run 时生成
+	extensions Extensions
+	filter     *Filter
+
+	// rewriter 路径改写规则，localOwners 记录本地路径对应的远程相对路径，用于发现冲突
+	rewriter    *Rewriter
+	localOwners map[string]string
+
+	// perm 输出文件和目录的权限及属主，在 Run 时由配置解析
+	perm *Permissions
+
+	// dryRun 试运行不修改本地文件，diff 记录将要执行的操作，diffCounts 为包括未保留明细在内的各操作数量
+	dryRun        bool
+	diff          []*DiffItem
+	diffCounts    map[DiffAction]int
+	diffTruncated bool
+
+	// resume 恢复运行时载入的进度，seen 为其中已处理的相对路径；stopped 为本次中途停止时的进度
+	resume  *Checkpoint
+	seen    map[string]bool
+	stopped *Checkpoint
+
+	// progress 进度回调，其余字段记录上报所需的运行状态
+	progress      ProgressFunc
+	progressAt    time.Time
+	phase         Phase
+	phaseStarted  time.Time
+	phaseScanned  int
+	currentDir    string
+	downloadsDone int
+}
+
+// Option 生成器可选配置
+type Option func(*Generator)
+
+// WithPreviousManifest 传入上次成功扫描的清单，用于增量比对和清理远程已删除的文件
+func WithPreviousManifest(entries []*model.StrmManifestEntry) Option {
+	return func(g *Generator) {
+		for _, e := range entries {
+			g.previous[e.Path] = e
+		}
+	}
+}
+
+// WithSignSecret 设置签名密钥，列表未返回 sign 时本地计算
+func WithSignSecret(secret string) Option {
+	return func(g *Generator) {
+		g.signSecret = secret
+	}
+}
+
+// WithSignRenewBefore 设置签名提前续期的时长，默认 DefaultSignRenewBefore
+func WithSignRenewBefore(d time.Duration) Option {
+	return func(g *Generator) {
+		g.signRenewBefore = d
+	}
+}
+
+// WithPlayBaseURL 设置本服务对外访问地址，模板使用 {play_url} 时必需
+func WithPlayBaseURL(baseURL string) Option {
+	return func(g *Generator) {
+		g.playBaseURL = baseURL
+	}
+}
+
+// WithExtensions 设置全局默认扩展名（来自字典表），未设置时使用内置默认值
+func WithExtensions(ext Extensions) Option {
+	return func(g *Generator) {
+		g.extensions = ext
+	}
+}
+
+// NewGenerator 创建生成器
+func NewGenerator(cfg *model.StrmConfig, service *model.OpenListService, client *openlist.Client, opts ...Option) *Generator {
+	g := &Generator{
+		cfg:             cfg,
+		service:         service,
+		client:          client,
+		signRenewBefore: DefaultSignRenewBefore,
+		extensions:      DefaultExtensions(),
+		previous:        make(map[string]*model.StrmManifestEntry),
+		current:         make(map[string]*model.StrmManifestEntry),
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// Manifest 返回本次扫描得到的清单，仅在 Run 成功后有意义
+func (g *Generator) Manifest() []*model.StrmManifestEntry {
+	entries := make([]*model.StrmManifestEntry, 0, len(g.current))
+	for _, e := range g.current {
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// Run 执行一次完整生成，单个文件失败不会中断运行，计入 Failed
+func (g *Generator) Run(ctx context.Context) (*Summary, error) {
+	g.summary = &Summary{ConfigID: g.cfg.ID, Errors: []string{}, OrphanFiles: []string{}, StartedAt: time.Now()}
+	logger.Info("[Strm] 开始生成", zap.Int("config_id", g.cfg.ID), zap.String("base", g.cfg.AlistBasePath), zap.String("output", g.cfg.StrmOutputPath))
+	if g.cfg.StrmOutputPath == "" {
+		return g.summary, errors.New("strmOutputPath 不能为空")
+	}
+	filter, err := NewFilter(g.cfg, g.extensions)
+	if err != nil {
+		return g.summary, err
+	}
+	g.filter = filter
+	rewriter, err := ParseRewriteRules(g.cfg.RewriteRules)
+	if err != nil {
+		return g.summary, err
+	}
+	g.rewriter = rewriter
+	perm, err := ParsePermissions(g.cfg)
+	if err != nil {
+		return g.summary, err
+	}
+	g.perm = perm
+	g.localOwners = make(map[string]string)
+	g.summary.DryRun = g.dryRun
+	g.diff = []*DiffItem{}
+	g.diffCounts = make(map[DiffAction]int)
+	if !g.dryRun {
+		if err := g.perm.mkdirAll(g.cfg.StrmOutputPath); err != nil {
+			return g.summary, err
+		}
+	}
+	roots := []string{g.cfg.AlistBasePath}
+	var startPages map[string]int
+	walked := false
+	if g.resume != nil && !g.dryRun {
+		g.restore(g.resume)
+		roots, startPages, walked = g.resume.Pending, g.resume.Pages, g.resume.Walked
+		logger.Info("[Strm] 从暂停处恢复", zap.Int("config_id", g.cfg.ID), zap.Int("pending_dirs", len(roots)), zap.Int("done_files", len(g.seen)))
+	}
+	var unfinished []string
+	var nextPages map[string]int
+	if !walked {
+		g.setPhase(PhaseWalking)
+		unfinished, nextPages, err = walkDirs(ctx, g.client, roots, startPages, g.service.WalkConcurrency, func(dir string, obj openlist.Object) error {
+			return g.handle(ctx, dir, obj)
+		})
+		walked = err == nil
+	}
+	if err == nil && len(g.downloads) > 0 {
+		g.setPhase(PhaseDownloading)
+		err = g.downloadCompanions(ctx)
+	}
+	// 被暂停或取消时保留进度，由调用方决定是否保存
+	if err != nil && ctx.Err() != nil && !g.dryRun {
+		g.stopped = g.snapshot(unfinished, nextPages, walked)
+	}
+	// 遍历不完整时不做孤立文件清理，避免误删
+	if err == nil {
+		g.setPhase(PhaseCleaning)
+		g.reconcileOrphans()
+		g.setPhase(PhaseDone)
+	}
+	g.summary.UsedURL = g.client.UsedURL()
+	g.summary.FinishedAt = time.Now()
+	logger.Info("[Strm] 生成结束",
+		zap.Int("config_id", g.cfg.ID),
+		zap.String("used_url", g.summary.UsedURL),
+		zap.Int("scanned", g.summary.Scanned),
+		zap.Int("created", g.summary.Created),
+		zap.Int("updated", g.summary.Updated),
+		zap.Int("skipped", g.summary.Skipped),
+		zap.Int("deleted", g.summary.Deleted),
+		zap.Int("downloaded", g.summary.Downloaded),
+		zap.Int("failed", g.summary.Failed),
+		zap.Error(err))
+	return g.summary, err
+}
+
+// handle 处理单个远程对象
+func (g *Generator) handle(ctx context.Context, dir string, obj openlist.Object) error {
+	// 被暂停或取消后立即结束遍历，当前页未处理的文件在恢复时重新拉取
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	g.currentDir = dir
+	defer g.reportProgress(false)
+	remotePath := path.Join(dir, obj.Name)
+	rel := RelativePath(g.cfg.AlistBasePath, remotePath)
+	if obj.IsDir {
+		if g.filter.SkipDir(rel) {
+			return SkipDir
+		}
+		return nil
+	}
+	// 恢复运行时，暂停前已处理的文件不再重复处理
+	if g.seen[rel] {
+		return nil
+	}
+	if !g.filter.IsVideo(rel, obj) {
+		if g.cfg.DownloadEnabled && g.filter.IsCompanion(rel, obj) {
+			localPath, err := g.localPath(rel, false)
+			if err != nil {
+				g.summary.addError("%s: %v", remotePath, err)
+				return nil
+			}
+			g.downloads = append(g.downloads, &downloadJob{
+				remotePath: remotePath,
+				localPath:  localPath,
+				sign:       g.signFor(ctx, remotePath, obj),
+				size:       obj.Size,
+				modified:   obj.Modified,
+			})
+		}
+		return nil
+	}
+	g.summary.Scanned++
+	localPath, err := g.localPath(rel, true)
+	if err != nil {
+		logger.Error("[Strm] 路径改写失败", zap.String("path", remotePath), zap.Error(err))
+		g.summary.addError("%s: %v", remotePath, err)
+		return nil
+	}
+	entry := newManifestEntry(rel, obj, localPath)
+	g.current[rel] = entry
+	prev := g.previous[rel]
+	// 增量模式：远程文件未变化且本地 .strm 仍存在时跳过
+	// 链接模板可能已修改，仍需渲染后与上次链接比对；raw_url 会过期，每次都重新获取
+	incremental := g.cfg.UpdateMode == model.UpdateModeIncremental && unchanged(prev, entry) && fileExists(localPath)
+	expiring := prev != nil && SignExpiring(prev.Sign, time.Now(), g.signRenewBefore)
+	sign := ""
+	if incremental && prev.Sign != "" && !expiring {
+		// 上次的签名仍有效，沿用以免每次运行都改写 .strm
+		sign = prev.Sign
+	} else {
+		sign = g.signFor(ctx, remotePath, obj)
+	}
+	link, sign, err := g.renderURL(ctx, remotePath, rel, sign)
+	if err != nil && ctx.Err() != nil {
+		// 因暂停或取消失败的文件不计入清单，恢复时重新处理
+		delete(g.current, rel)
+		g.summary.Scanned--
+		return ctx.Err()
+	}
+	if err != nil {
+		logger.Error("[Strm] 获取链接失败", zap.String("path", remotePath), zap.Error(err))
+		g.summary.addError("%s: %v", remotePath, err)
+		entry.Size = -1
+		return nil
+	}
+	entry.Url = link
+	entry.Sign = sign
+	// 旧清单没有记录链接时视为一致；模板变化导致链接不同时重新写入
+	if incremental && (prev.Url == "" || prev.Url == link) {
+		g.summary.Skipped++
+		return nil
+	}
+	if err := g.writeStrm(remotePath, localPath, []byte(link)); err != nil {
+		logger.Error("[Strm] 写入失败", zap.String("path", localPath), zap.Error(err))
+		g.summary.addError("%s: %v", remotePath, err)
+		// 标记为失效，保证下次增量运行会重新生成
+		entry.Size = -1
+	} else if expiring {
+		g.summary.Resigned++
+	}
+	return nil
+}
+
+// localPath 按改写规则计算本地路径，strmFile 为 true 时替换为 .strm 扩展名；
+// 多个远程文件改写到同一本地路径时保留远程相对路径字典序最小的，结果与并发遍历的顺序无关
+func (g *Generator) localPath(rel string, strmFile bool) (string, error) {
+	localRel, err := g.rewriter.Apply(rel)
+	if err != nil {
+		return "", err
+	}
+	var p string
+	if strmFile {
+		p = StrmFilePath(g.cfg.StrmOutputPath, localRel)
+	} else {
+		p = LocalFilePath(g.cfg.StrmOutputPath, localRel)
+	}
+	if owner, ok := g.localOwners[p]; ok && owner != rel {
+		if owner < rel {
+			return "", fmt.Errorf("改写后与 %s 冲突: %s", owner, p)
+		}
+		g.displace(owner, rel, p)
+	}
+	g.localOwners[p] = rel
+	return p, nil
+}
+
+// displace 先处理的 owner 在冲突中落选，撤销它的清单记录、待下载文件和已写入的文件，由 rel 重新生成
+func (g *Generator) displace(owner, rel, localPath string) {
+	written := g.current[owner] != nil && g.current[owner].StrmPath == localPath
+	delete(g.current, owner)
+	downloads := g.downloads[:0]
+	for _, d := range g.downloads {
+		if d.localPath != localPath {
+			downloads = append(downloads, d)
+		}
+	}
+	g.downloads = downloads
+	if g.dryRun {
+		diff := g.diff[:0]
+		for _, d := range g.diff {
+			if d.LocalPath != localPath {
+				diff = append(diff, d)
+				continue
+			}
+			g.diffCounts[d.Action]--
+			switch d.Action {
+			case DiffCreate:
+				g.summary.Created--
+			case DiffUpdate:
+				g.summary.Updated--
+			}
+		}
+		g.diff = diff
+	} else if !written {
+		// 伴随文件在遍历结束后才下载，此时尚未写入
+	} else if err := os.Remove(localPath); err != nil && !os.IsNotExist(err) {
+		logger.Error("[Strm] 删除冲突文件失败", zap.String("path", localPath), zap.Error(err))
+	}
+	g.summary.addError("%s: 改写后与 %s 冲突: %s", path.Join(g.cfg.AlistBasePath, owner), rel, localPath)
+}
+
+// renderURL 按配置的模板生成 .strm 内容并返回实际使用的签名，模板使用 {raw_url} 时请求 /api/fs/get
+func (g *Generator) renderURL(ctx context.Context, remotePath, rel, sign string) (string, string, error) {
+	vars := URLVars{ServiceURL: g.service.ServiceUrl, Path: remotePath, RelPath: rel, Sign: sign}
+	if g.playBaseURL != "" {
+		vars.PlayURL = PlayURL(g.playBaseURL, g.cfg.ID, g.cfg.PlaySecret, rel)
+	}
+	if NeedsRawURL(g.cfg.UrlTemplate) {
+		resp, err := g.client.Get(ctx, &openlist.GetReq{Path: remotePath})
+		if err != nil {
+			return "", "", err
+		}
+		vars.RawURL = resp.RawURL
+		if resp.Sign != "" {
+			vars.Sign = resp.Sign
+		}
+	}
+	return RenderURL(g.cfg.UrlTemplate, vars), vars.Sign, nil
+}
+
+// writeStrm 原子写入 .strm 文件，内容相同则跳过，避免触发媒体服务器重新扫描
+func (g *Generator) writeStrm(remotePath, localPath string, content []byte) error {
+	existing, err := os.ReadFile(localPath)
+	switch {
+	case err == nil && bytes.Equal(existing, content):
+		g.summary.Skipped++
+		return nil
+	case err != nil && !os.IsNotExist(err):
+		return err
+	}
+	if g.dryRun {
+		if existing != nil {
+			g.summary.Updated++
+			g.record(DiffUpdate, remotePath, localPath, string(content))
+		} else {
+			g.summary.Created++
+			g.record(DiffCreate, remotePath, localPath, string(content))
+		}
+		return nil
+	}
+	if err := g.perm.writeFile(localPath, content); err != nil {
+		return err
+	}
+	if existing != nil {
+		g.summary.Updated++
+	} else {
+		g.summary.Created++
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package strm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+)
+
+func TestRewriteCollisionKeepsSmallestRemotePath(t *testing.T) {
+	tree := map[string][]string{
+		"/media":   {"A/", "B/", "C/"},
+		"/media/A": {"Movie.mkv", "Movie.srt"},
+		"/media/B": {"Movie.mkv", "Movie.srt"},
+		"/media/C": {"Movie.mkv", "Movie.srt"},
+	}
+	// 分别让 A、B、C 最后列出，无论遍历顺序如何都应保留 A
+	for _, slow := range []string{"/media/A", "/media/B", "/media/C"} {
+		for _, dryRun := range []bool{false, true} {
+			name := "slow=" + slow
+			if dryRun {
+				name += "/dry-run"
+			}
+			t.Run(name, func(t *testing.T) {
+				fake := newFakeOpenList(t, tree)
+				fake.setHook(func(api, dir string, page int) {
+					if api == "/api/fs/list" && dir == slow {
+						time.Sleep(50 * time.Millisecond)
+					}
+				})
+				svc := fake.service()
+				svc.WalkConcurrency = 3
+				out := t.TempDir()
+				cfg := &model.StrmConfig{
+					ID:              1,
+					AlistBasePath:   "/media",
+					StrmOutputPath:  out,
+					DownloadEnabled: true,
+					RewriteRules:    `[{"type":"flatten","depth":0}]`,
+				}
+				var opts []Option
+				if dryRun {
+					opts = append(opts, WithDryRun())
+				}
+				g := NewGenerator(cfg, svc, fake.client(), opts...)
+				summary, err := g.Run(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				// B、C 的视频和字幕各冲突一次
+				if summary.Failed != 4 {
+					t.Errorf("failed = %d, errors = %v", summary.Failed, summary.Errors)
+				}
+				manifest := g.Manifest()
+				if len(manifest) != 1 || manifest[0].Path != "A/Movie.mkv" {
+					t.Errorf("manifest = %+v", manifest)
+				}
+				if dryRun {
+					if summary.Created != 1 {
+						t.Errorf("created = %d", summary.Created)
+					}
+					var downloads []string
+					for _, d := range g.Diff() {
+						switch d.Action {
+						case DiffCreate:
+							if !strings.Contains(d.Content, "/media/A/Movie.mkv") {
+								t.Errorf("create = %+v", d)
+							}
+						case DiffDownload:
+							downloads = append(downloads, d.RemotePath)
+						default:
+							t.Errorf("unexpected diff %+v", d)
+						}
+					}
+					if len(downloads) != 1 || downloads[0] != "/media/A/Movie.srt" {
+						t.Errorf("downloads = %v", downloads)
+					}
+					return
+				}
+				data, err := os.ReadFile(filepath.Join(out, "Movie.strm"))
+				if err != nil || !strings.Contains(string(data), "/media/A/Movie.mkv") {
+					t.Errorf("Movie.strm = %q, %v", data, err)
+				}
+				data, err = os.ReadFile(filepath.Join(out, "Movie.srt"))
+				if err != nil || !strings.Contains(string(data), "/media/A/Movie.srt") {
+					t.Errorf("Movie.srt = %q, %v", data, err)
+				}
+			})
+		}
+	}
+}
+
+func TestDryRunDiffCapped(t *testing.T) {
+	old := maxDiffItems
+	maxDiffItems = 5
+	t.Cleanup(func() { maxDiffItems = old })
+
+	fake := newFakeOpenList(t, fakeLibrary("/media", 3, 4))
+	out := t.TempDir()
+	cfg := &model.StrmConfig{ID: 1, AlistBasePath: "/media", StrmOutputPath: out, DownloadEnabled: true}
+	g := NewGenerator(cfg, fake.service(), fake.client(), WithDryRun())
+	summary, err := g.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Diff()) != 5 || !g.DiffTruncated() {
+		t.Errorf("diff = %d, truncated = %v", len(g.Diff()), g.DiffTruncated())
+	}
+	counts := g.DiffCounts()
+	if counts[DiffCreate] != 12 || counts[DiffDownload] != 3 {
+		t.Errorf("counts = %v", counts)
+	}
+	if summary.Created != 12 {
+		t.Errorf("created = %d", summary.Created)
+	}
+	if entries, _ := os.ReadDir(out); len(entries) != 0 {
+		t.Errorf("试运行不应写入文件: %v", entries)
+	}
+}
@@ -0,0 +1,57 @@
+package strm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/openlist"
+)
+
+// hashPriority 清单优先记录的哈希算法
+var hashPriority = []string{"sha1", "md5", "sha256"}
+
+// objectHash 取对象的哈希值，格式为 算法:值，无哈希时返回空
+func objectHash(obj openlist.Object) string {
+	for _, algo := range hashPriority {
+		if v := obj.HashInfo[algo]; v != "" {
+			return algo + ":" + v
+		}
+	}
+	return ""
+}
+
+// newManifestEntry 根据远程对象生成清单记录
+func newManifestEntry(rel string, obj openlist.Object, strmPath string) *model.StrmManifestEntry {
+	return &model.StrmManifestEntry{
+		Path:     rel,
+		Size:     obj.Size,
+		Modified: obj.Modified,
+		Hash:     objectHash(obj),
+		StrmPath: strmPath,
+	}
+}
+
+// unchanged 远程文件与上次清单一致（大小、修改时间、哈希）
+func unchanged(prev, cur *model.StrmManifestEntry) bool {
+	if prev == nil {
+		return false
+	}
+	if prev.Size != cur.Size || prev.Modified.Unix() != cur.Modified.Unix() {
+		return false
+	}
+	return prev.Hash == "" || cur.Hash == "" || prev.Hash == cur.Hash
+}
+
+// fileExists 本地文件是否存在
+func fileExists(p string) bool {
+	_, err := os.Stat(p)
+	return err == nil
+}
+
+// within 判断 p 是否位于 dir 目录内
+func within(dir, p string) bool {
+	rel, err := filepath.Rel(dir, p)
+	return err == nil && rel != "." && !strings.HasPrefix(rel, "..")
+}
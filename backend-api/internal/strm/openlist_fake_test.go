@@ -0,0 +1,155 @@
+package strm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/openlist"
+)
+
+// fakeOpenList 模拟 OpenList 的 fs/list、fs/get 和 /d/ 下载接口，目录树以路径到子项名称的映射表示，
+// 名称以 / 结尾的为目录
+type fakeOpenList struct {
+	*httptest.Server
+	tree map[string][]string
+
+	mutex sync.Mutex
+	// lists 各目录各页被拉取的次数，键为 "目录#页"
+	lists map[string]int
+	// failDirs 拉取时返回错误的目录
+	failDirs map[string]bool
+	// hook 每次请求 fs/list 或 fs/get 前调用，api 为接口路径，可用于在遍历中途暂停
+	hook func(api, p string, page int)
+}
+
+func newFakeOpenList(t *testing.T, tree map[string][]string) *fakeOpenList {
+	t.Helper()
+	f := &fakeOpenList{tree: tree, lists: make(map[string]int), failDirs: make(map[string]bool)}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeOpenList) serve(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/d/") {
+		w.Write([]byte("DATA:" + r.URL.Path))
+		return
+	}
+	var req struct {
+		Path    string `json:"path"`
+		Page    int    `json:"page"`
+		PerPage int    `json:"per_page"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	f.mutex.Lock()
+	hook := f.hook
+	f.mutex.Unlock()
+	if hook != nil {
+		hook(r.URL.Path, req.Path, req.Page)
+	}
+	switch r.URL.Path {
+	case "/api/fs/list":
+		f.mutex.Lock()
+		f.lists[req.Path+"#"+strconv.Itoa(req.Page)]++
+		fail := f.failDirs[req.Path]
+		f.mutex.Unlock()
+		children, ok := f.tree[req.Path]
+		if !ok || fail {
+			writeJSON(w, map[string]interface{}{"code": 500, "message": "object not found"})
+			return
+		}
+		from := (req.Page - 1) * req.PerPage
+		to := from + req.PerPage
+		if from > len(children) {
+			from = len(children)
+		}
+		if to > len(children) {
+			to = len(children)
+		}
+		content := []openlist.Object{}
+		for _, name := range children[from:to] {
+			content = append(content, fakeObject(name))
+		}
+		writeJSON(w, map[string]interface{}{"code": 200, "data": map[string]interface{}{"content": content, "total": len(children)}})
+	case "/api/fs/get":
+		obj := fakeObject(path.Base(req.Path))
+		writeJSON(w, map[string]interface{}{"code": 200, "data": map[string]interface{}{
+			"name": obj.Name, "size": obj.Size, "is_dir": obj.IsDir, "modified": obj.Modified, "sign": "abc:0",
+		}})
+	default:
+		writeJSON(w, map[string]interface{}{"code": 200, "data": map[string]interface{}{}})
+	}
+}
+
+// setHook 设置请求钩子，nil 表示清除
+func (f *fakeOpenList) setHook(hook func(api, p string, page int)) {
+	f.mutex.Lock()
+	f.hook = hook
+	f.mutex.Unlock()
+}
+
+// failDir 设置目录拉取时返回错误
+func (f *fakeOpenList) failDir(dir string) {
+	f.mutex.Lock()
+	f.failDirs[dir] = true
+	f.mutex.Unlock()
+}
+
+// listCount 目录某页被拉取的次数
+func (f *fakeOpenList) listCount(dir string, page int) int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.lists[dir+"#"+strconv.Itoa(page)]
+}
+
+func (f *fakeOpenList) service() *model.OpenListService {
+	return &model.OpenListService{ID: 1, ServiceUrl: f.URL}
+}
+
+func (f *fakeOpenList) client() *openlist.Client {
+	return openlist.NewClient(f.service())
+}
+
+func fakeObject(name string) openlist.Object {
+	if strings.HasSuffix(name, "/") {
+		return openlist.Object{Name: strings.TrimSuffix(name, "/"), IsDir: true, Modified: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	}
+	return openlist.Object{Name: name, Size: 1234, Modified: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// fakeLibrary 生成 dirs 个子目录、每个目录 files 个视频和一个字幕的目录树
+func fakeLibrary(root string, dirs, files int) map[string][]string {
+	tree := map[string][]string{root: {}}
+	for d := 0; d < dirs; d++ {
+		name := "Show" + string(rune('A'+d))
+		tree[root] = append(tree[root], name+"/")
+		dir := path.Join(root, name)
+		for i := 0; i < files; i++ {
+			tree[dir] = append(tree[dir], name+"."+string(rune('a'+i))+".mkv")
+		}
+		tree[dir] = append(tree[dir], name+".srt")
+		sort.Strings(tree[dir])
+	}
+	return tree
+}
+
+// setListPageSize 测试期间修改分页大小
+func setListPageSize(t *testing.T, n int) {
+	old := listPageSize
+	listPageSize = n
+	t.Cleanup(func() { listPageSize = old })
+}
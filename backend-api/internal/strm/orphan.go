@@ -0,0 +1,238 @@
+package strm
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"go.uber.org/zap"
+)
+
+const (
+	maxSummaryOrphans = 1000
+	quarantineDirName = ".quarantine"
+)
+
+// localDir 输出目录中单个目录的文件分组
+type localDir struct {
+	strms  map[string]bool // .strm 文件名（不含扩展名） -> 是否孤立
+	others []string        // 非 .strm 文件名
+}
+
+// quarantineDir 隔离目录，未配置时使用 StrmOutputPath/.quarantine
+func (g *Generator) quarantineDir() string {
+	if g.cfg.QuarantinePath != "" {
+		return filepath.Clean(g.cfg.QuarantinePath)
+	}
+	return filepath.Join(g.cfg.StrmOutputPath, quarantineDirName)
+}
+
+// orphanPolicy 返回配置的孤立文件策略，未配置时为删除
+func (g *Generator) orphanPolicy() model.OrphanPolicy {
+	if g.cfg.OrphanPolicy == "" {
+		return model.OrphanPolicyDelete
+	}
+	return g.cfg.OrphanPolicy
+}
+
+// expectedFiles 本次运行应存在的本地文件
+func (g *Generator) expectedFiles() map[string]bool {
+	expected := make(map[string]bool, len(g.current)+len(g.downloads))
+	for _, e := range g.current {
+		expected[filepath.Clean(e.StrmPath)] = true
+	}
+	// 远程仍存在的伴随文件不算孤立
+	for _, job := range g.downloads {
+		expected[filepath.Clean(job.localPath)] = true
+	}
+	return expected
+}
+
+// reconcileOrphans 找出远程已不存在的本地 .strm 及其伴随文件，按配置策略删除/隔离/仅报告
+func (g *Generator) reconcileOrphans() {
+	out := filepath.Clean(g.cfg.StrmOutputPath)
+	quarantine := g.quarantineDir()
+	expected := g.expectedFiles()
+	dirs := make(map[string]*localDir)
+	total, orphanStrms, walkErrors := 0, 0, 0
+	filepath.WalkDir(out, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// 输出目录尚未创建时没有需要清理的文件
+			if p == out && os.IsNotExist(err) {
+				return nil
+			}
+			walkErrors++
+			logger.Error("[Strm] 扫描输出目录失败", zap.String("path", p), zap.Error(err))
+			g.summary.addError("%s: %v", p, err)
+			return nil
+		}
+		if d.IsDir() {
+			if p != out && (p == quarantine || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		dir := filepath.Dir(p)
+		ld, ok := dirs[dir]
+		if !ok {
+			ld = &localDir{strms: make(map[string]bool)}
+			dirs[dir] = ld
+		}
+		name := d.Name()
+		if strings.EqualFold(filepath.Ext(name), ".strm") {
+			total++
+			orphan := !expected[p]
+			if orphan {
+				orphanStrms++
+			}
+			ld.strms[strings.TrimSuffix(name, filepath.Ext(name))] = orphan
+			return nil
+		}
+		ld.others = append(ld.others, name)
+		return nil
+	})
+	if orphanStrms == 0 {
+		return
+	}
+
+	var orphans []string
+	for dir, ld := range dirs {
+		for base, orphan := range ld.strms {
+			if orphan {
+				orphans = append(orphans, filepath.Join(dir, base+".strm"))
+			}
+		}
+		for _, name := range ld.others {
+			p := filepath.Join(dir, name)
+			if expected[p] {
+				continue
+			}
+			if base, ok := ownerBase(ld, name); ok && ld.strms[base] {
+				orphans = append(orphans, p)
+			}
+		}
+	}
+	g.summary.Orphans = len(orphans)
+	for _, p := range orphans {
+		if len(g.summary.OrphanFiles) >= maxSummaryOrphans {
+			break
+		}
+		g.summary.OrphanFiles = append(g.summary.OrphanFiles, p)
+	}
+
+	threshold := g.cfg.OrphanThreshold
+	if threshold <= 0 {
+		threshold = model.DefaultOrphanThreshold
+	}
+	policy := g.orphanPolicy()
+	// 未能完整扫描输出目录时伴随文件的归属可能判断错误，只报告不清理
+	if policy != model.OrphanPolicyReport && walkErrors > 0 {
+		g.summary.OrphanAborted = true
+		logger.Warn("[Strm] 扫描输出目录出错，中止清理", zap.Int("config_id", g.cfg.ID), zap.Int("errors", walkErrors))
+		return
+	}
+	if policy != model.OrphanPolicyReport && orphanStrms*100 > threshold*total {
+		g.summary.OrphanAborted = true
+		logger.Warn("[Strm] 孤立文件占比超过安全阈值，中止清理",
+			zap.Int("config_id", g.cfg.ID), zap.Int("orphans", orphanStrms), zap.Int("total", total), zap.Int("threshold", threshold))
+		return
+	}
+	logger.Info("[Strm] 发现孤立文件", zap.Int("config_id", g.cfg.ID), zap.Int("count", len(orphans)), zap.String("policy", string(policy)))
+
+	if g.dryRun {
+		for _, p := range orphans {
+			switch policy {
+			case model.OrphanPolicyDelete:
+				g.summary.Deleted++
+				g.record(DiffDelete, "", p, "")
+			case model.OrphanPolicyQuarantine:
+				g.summary.Quarantined++
+				g.record(DiffQuarantine, "", p, "")
+			}
+		}
+		return
+	}
+
+	switch policy {
+	case model.OrphanPolicyDelete:
+		for _, p := range orphans {
+			if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+				logger.Error("[Strm] 删除孤立文件失败", zap.String("path", p), zap.Error(err))
+				g.summary.addError("%s: %v", p, err)
+				continue
+			}
+			g.summary.Deleted++
+			removeEmptyParents(filepath.Dir(p), out)
+		}
+	case model.OrphanPolicyQuarantine:
+		for _, p := range orphans {
+			rel, err := filepath.Rel(out, p)
+			if err != nil {
+				continue
+			}
+			dst := filepath.Join(quarantine, rel)
+			if err := moveFile(p, dst); err != nil {
+				logger.Error("[Strm] 隔离孤立文件失败", zap.String("path", p), zap.Error(err))
+				g.summary.addError("%s: %v", p, err)
+				continue
+			}
+			g.summary.Quarantined++
+			removeEmptyParents(filepath.Dir(p), out)
+		}
+	}
+}
+
+// ownerBase 为伴随文件找到所属的 .strm（文件名前缀匹配最长者），如 A.zh.srt、A-poster.jpg 属于 A.strm
+func ownerBase(ld *localDir, name string) (string, bool) {
+	owner := ""
+	for base := range ld.strms {
+		if len(base) <= len(owner) {
+			continue
+		}
+		if strings.HasPrefix(name, base+".") || strings.HasPrefix(name, base+"-") {
+			owner = base
+		}
+	}
+	return owner, owner != ""
+}
+
+// moveFile 移动文件，跨设备时退化为复制后删除
+func moveFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// removeEmptyParents 自下而上删除空目录，直到 root（不含）
+func removeEmptyParents(dir, root string) {
+	for dir != root && within(root, dir) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
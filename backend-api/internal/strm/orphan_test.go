@@ -0,0 +1,50 @@
+package strm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+)
+
+func TestReconcileOrphansAbortsOnWalkError(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root 用户可读取无权限目录")
+	}
+	for _, locked := range []bool{false, true} {
+		name := "ok"
+		if locked {
+			name = "locked"
+		}
+		t.Run(name, func(t *testing.T) {
+			fake := newFakeOpenList(t, fakeLibrary("/media", 2, 2))
+			out := t.TempDir()
+			orphan := filepath.Join(out, "Old.strm")
+			if err := os.WriteFile(orphan, []byte("http://old"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if locked {
+				dir := filepath.Join(out, "Locked")
+				if err := os.Mkdir(dir, 0); err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { os.Chmod(dir, 0755) })
+			}
+			cfg := &model.StrmConfig{ID: 1, AlistBasePath: "/media", StrmOutputPath: out}
+			summary, err := NewGenerator(cfg, fake.service(), fake.client()).Run(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, statErr := os.Stat(orphan)
+			if locked {
+				if !summary.OrphanAborted || statErr != nil || summary.Failed == 0 {
+					t.Errorf("扫描出错时应中止清理: aborted = %v, failed = %d, stat = %v", summary.OrphanAborted, summary.Failed, statErr)
+				}
+			} else if summary.OrphanAborted || !os.IsNotExist(statErr) {
+				t.Errorf("孤立文件应被删除: aborted = %v, stat = %v", summary.OrphanAborted, statErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package strm
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultVideoExts 默认视为视频的扩展名，可通过字典 video_ext 覆盖
+var DefaultVideoExts = []string{
+	".mp4", ".mkv", ".avi", ".mov", ".wmv", ".flv", ".ts", ".m2ts", ".rmvb",
+	".webm", ".iso", ".mpg", ".mpeg", ".m4v", ".3gp", ".vob",
+}
+
+// DefaultSubtitleExts 默认字幕扩展名，可通过字典 subtitle_ext 覆盖
+var DefaultSubtitleExts = []string{".srt", ".ass", ".ssa", ".sup", ".vtt", ".sub", ".idx"}
+
+// DefaultImageExts 默认图片扩展名，可通过字典 image_ext 覆盖
+var DefaultImageExts = []string{".jpg", ".jpeg", ".png", ".webp"}
+
+// metadataExts 刮削元数据，始终随视频下载
+var metadataExts = []string{".nfo"}
+
+// RelativePath 计算远程路径相对于基础路径的相对路径
+func RelativePath(base, remotePath string) string {
+	base = "/" + strings.Trim(base, "/")
+	rel := strings.TrimPrefix(remotePath, base)
+	return strings.TrimPrefix(rel, "/")
+}
+
+// StrmFilePath 计算相对路径对应的本地 .strm 文件路径
+func StrmFilePath(outputDir, rel string) string {
+	ext := path.Ext(rel)
+	return filepath.Join(outputDir, filepath.FromSlash(strings.TrimSuffix(rel, ext)+".strm"))
+}
+
+// DirectURL 生成 OpenList /d/ 直链
+func DirectURL(serviceURL, remotePath, sign string) string {
+	return RenderURL(DefaultURLTemplate, URLVars{ServiceURL: serviceURL, Path: remotePath, Sign: sign})
+}
+
+// LocalFilePath 计算相对路径对应的本地文件路径（保持原文件名）
+func LocalFilePath(outputDir, rel string) string {
+	return filepath.Join(outputDir, filepath.FromSlash(rel))
+}
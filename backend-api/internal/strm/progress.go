@@ -0,0 +1,104 @@
+package strm
+
+import (
+	"time"
+)
+
+// progressInterval 两次进度回调的最小间隔，阶段变化时不受限制
+const progressInterval = 500 * time.Millisecond
+
+// Phase 运行阶段
+type Phase string
+
+const (
+	PhaseWalking     Phase = "walking"     // 遍历远程目录
+	PhaseDownloading Phase = "downloading" // 下载伴随文件
+	PhaseCleaning    Phase = "cleaning"    // 清理孤立文件
+	PhaseChecking    Phase = "checking"    // 检查已生成的 .strm
+	PhaseDone        Phase = "done"
+)
+
+// Progress 运行中的实时进度
+type Progress struct {
+	Phase            Phase     `json:"phase"`
+	CurrentDir       string    `json:"currentDir"` // 正在处理的远程目录（检查时为本地目录）
+	Scanned          int       `json:"scanned"`    // 已扫描的视频文件数（检查时为已检查的 .strm 数）
+	Expected         int       `json:"expected"`   // 预计文件数，取自上次清单，0 表示未知
+	Created          int       `json:"created"`    // 检查时为已修复数
+	Updated          int       `json:"updated"`
+	Skipped          int       `json:"skipped"`
+	Downloaded       int       `json:"downloaded"`
+	DownloadsPending int       `json:"downloadsPending"` // 已发现但尚未下载的伴随文件数
+	Failed           int       `json:"failed"`           // 检查时为发现问题的文件数
+	LastError        string    `json:"lastError"`
+	EtaSeconds       int       `json:"etaSeconds"` // 预计剩余秒数，-1 表示无法估计
+	StartedAt        time.Time `json:"startedAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// ProgressFunc 进度回调，在生成器所在 goroutine 中同步调用，不应阻塞
+type ProgressFunc func(Progress)
+
+// WithProgress 设置进度回调，运行中至多每 500ms 调用一次，阶段变化和结束时必定调用
+func WithProgress(fn ProgressFunc) Option {
+	return func(g *Generator) {
+		g.progress = fn
+	}
+}
+
+// setPhase 切换阶段并立即上报
+func (g *Generator) setPhase(phase Phase) {
+	g.phase = phase
+	g.phaseStarted = time.Now()
+	g.phaseScanned = g.summary.Scanned
+	g.currentDir = ""
+	g.reportProgress(true)
+}
+
+// reportProgress 按生成统计上报进度，force 为 false 时受最小间隔限制
+func (g *Generator) reportProgress(force bool) {
+	if g.progress == nil || (!force && time.Since(g.progressAt) < progressInterval) {
+		return
+	}
+	s := g.summary
+	p := Progress{
+		Phase:            g.phase,
+		CurrentDir:       g.currentDir,
+		Scanned:          s.Scanned,
+		Expected:         len(g.previous),
+		Created:          s.Created,
+		Updated:          s.Updated,
+		Skipped:          s.Skipped,
+		Downloaded:       s.Downloaded,
+		DownloadsPending: len(g.downloads) - g.downloadsDone,
+		Failed:           s.Failed,
+		StartedAt:        s.StartedAt,
+	}
+	if len(s.Errors) > 0 {
+		p.LastError = s.Errors[len(s.Errors)-1]
+	}
+	g.emitProgress(p)
+}
+
+// emitProgress 估算剩余时间后回调：遍历阶段按上次清单的文件数估算，下载阶段按已下载文件的平均耗时估算
+func (g *Generator) emitProgress(p Progress) {
+	now := time.Now()
+	g.progressAt = now
+	p.UpdatedAt = now
+	p.EtaSeconds = -1
+	elapsed := now.Sub(g.phaseStarted)
+	switch g.phase {
+	case PhaseWalking, PhaseChecking:
+		// 恢复运行时只按本阶段新扫描的文件计算速度
+		if done := p.Scanned - g.phaseScanned; done > 0 && p.Expected > p.Scanned {
+			p.EtaSeconds = int(elapsed.Seconds() / float64(done) * float64(p.Expected-p.Scanned))
+		}
+	case PhaseDownloading:
+		if g.downloadsDone > 0 {
+			p.EtaSeconds = int(elapsed.Seconds() / float64(g.downloadsDone) * float64(p.DownloadsPending))
+		}
+	case PhaseDone:
+		p.EtaSeconds = 0
+	}
+	g.progress(p)
+}
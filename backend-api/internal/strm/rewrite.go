@@ -0,0 +1,134 @@
+package strm
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RewriteRuleType 路径改写规则类型
+type RewriteRuleType string
+
+const (
+	RewriteRegex   RewriteRuleType = "regex"   // 对相对路径做正则查找替换
+	RewriteFlatten RewriteRuleType = "flatten" // 只保留前 depth 层目录，更深的目录合并
+	RewriteStrip   RewriteRuleType = "strip"   // 去掉开头 depth 层目录
+	RewriteSeason  RewriteRuleType = "season"  // 将 S01、第1季、Specials 等季目录重命名为 format（默认 Season %02d）
+)
+
+// defaultSeasonFormat Jellyfin/Emby 识别的季目录命名
+const defaultSeasonFormat = "Season %02d"
+
+var (
+	seasonPattern   = regexp.MustCompile(`(?i)^(?:s|season|第)\s*0*(\d{1,3})\s*(?:季)?$`)
+	specialsPattern = regexp.MustCompile(`(?i)^(?:specials?|sp|特别篇)$`)
+)
+
+// RewriteRule 单条改写规则，按配置顺序依次作用于相对 alistBasePath 的路径
+type RewriteRule struct {
+	Type    RewriteRuleType `json:"type"`
+	Find    string          `json:"find,omitempty"`
+	Replace string          `json:"replace,omitempty"`
+	Depth   int             `json:"depth,omitempty"`
+	Format  string          `json:"format,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// Rewriter 有序的路径改写规则
+type Rewriter struct {
+	rules []*RewriteRule
+}
+
+// ParseRewriteRules 解析 JSON 数组形式的改写规则，空字符串表示不改写
+func ParseRewriteRules(raw string) (*Rewriter, error) {
+	rw := &Rewriter{}
+	if strings.TrimSpace(raw) == "" {
+		return rw, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &rw.rules); err != nil {
+		return nil, fmt.Errorf("改写规则须为 JSON 数组: %v", err)
+	}
+	for i, rule := range rw.rules {
+		switch rule.Type {
+		case RewriteRegex:
+			re, err := regexp.Compile(rule.Find)
+			if err != nil {
+				return nil, fmt.Errorf("第 %d 条规则正则无效: %v", i+1, err)
+			}
+			rule.re = re
+		case RewriteFlatten, RewriteStrip:
+			if rule.Depth < 0 {
+				return nil, fmt.Errorf("第 %d 条规则 depth 不能为负数", i+1)
+			}
+		case RewriteSeason:
+			if rule.Format == "" {
+				rule.Format = defaultSeasonFormat
+			}
+			// 用季号 1 试渲染，占位符缺失、多余或类型不符时输出含 %!
+			if strings.Contains(fmt.Sprintf(rule.Format, 1), "%!") {
+				return nil, fmt.Errorf("第 %d 条规则 format 须包含且仅包含一个整数季号占位符，如 Season %%02d", i+1)
+			}
+		default:
+			return nil, fmt.Errorf("第 %d 条规则类型 %q 不支持", i+1, rule.Type)
+		}
+	}
+	return rw, nil
+}
+
+// Apply 依次应用规则得到本地相对路径，结果为空时返回错误
+func (rw *Rewriter) Apply(rel string) (string, error) {
+	out := rel
+	for _, rule := range rw.rules {
+		dirs, name := splitRel(out)
+		switch rule.Type {
+		case RewriteRegex:
+			out = rule.re.ReplaceAllString(out, rule.Replace)
+			continue
+		case RewriteFlatten:
+			if len(dirs) > rule.Depth {
+				dirs = dirs[:rule.Depth]
+			}
+		case RewriteStrip:
+			if len(dirs) > rule.Depth {
+				dirs = dirs[rule.Depth:]
+			} else {
+				dirs = nil
+			}
+		case RewriteSeason:
+			for i, d := range dirs {
+				dirs[i] = renameSeason(d, rule.Format)
+			}
+		}
+		out = path.Join(append(dirs, name)...)
+	}
+	// 以 / 为根清理，.. 无法跳出输出目录
+	out = strings.TrimPrefix(path.Clean("/"+out), "/")
+	if out == "" {
+		return rel, fmt.Errorf("改写后路径为空: %s", rel)
+	}
+	return out, nil
+}
+
+// splitRel 拆分为目录列表和文件名
+func splitRel(rel string) ([]string, string) {
+	parts := strings.Split(strings.Trim(rel, "/"), "/")
+	return parts[:len(parts)-1], parts[len(parts)-1]
+}
+
+// renameSeason 识别季目录并按 format 重命名，非季目录原样返回
+func renameSeason(dir, format string) string {
+	name := strings.TrimSpace(dir)
+	if specialsPattern.MatchString(name) {
+		return fmt.Sprintf(format, 0)
+	}
+	m := seasonPattern.FindStringSubmatch(name)
+	if m == nil {
+		return dir
+	}
+	n, _ := strconv.Atoi(m[1])
+	return fmt.Sprintf(format, n)
+}
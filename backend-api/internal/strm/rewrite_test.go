@@ -0,0 +1,82 @@
+package strm
+
+import "testing"
+
+func TestParseRewriteRules(t *testing.T) {
+	cases := []struct {
+		raw     string
+		wantErr bool
+	}{
+		{"", false},
+		{"  ", false},
+		{`[]`, false},
+		{`[{"type":"regex","find":"^(.+)$","replace":"x/$1"}]`, false},
+		{`[{"type":"season"}]`, false},
+		{`[{"type":"season","format":"S%02d"}]`, false},
+		{`[{"type":"season","format":"第%d季"}]`, false},
+		{`{"type":"season"}`, true},
+		{`[{"type":"regex","find":"("}]`, true},
+		{`[{"type":"flatten","depth":-1}]`, true},
+		{`[{"type":"strip","depth":-1}]`, true},
+		{`[{"type":"unknown"}]`, true},
+		{`[{"type":"season","format":"Season"}]`, true},
+		{`[{"type":"season","format":"Season %%"}]`, true},
+		{`[{"type":"season","format":"Season %s"}]`, true},
+		{`[{"type":"season","format":"Season %d-%d"}]`, true},
+		{`[{"type":"season","format":"Season %"}]`, true},
+	}
+	for _, tc := range cases {
+		_, err := ParseRewriteRules(tc.raw)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseRewriteRules(%s) err = %v, wantErr %v", tc.raw, err, tc.wantErr)
+		}
+	}
+}
+
+func TestRewriterApply(t *testing.T) {
+	cases := []struct {
+		name    string
+		rules   string
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"无规则", "", "Show/S01/E01.mkv", "Show/S01/E01.mkv", false},
+		{"正则替换", `[{"type":"regex","find":"^Anime/","replace":"TV/"}]`, "Anime/Show/E01.mkv", "TV/Show/E01.mkv", false},
+		{"正则分组", `[{"type":"regex","find":"(\\d{4})/(.+)","replace":"$2 ($1)"}]`, "2024/Movie.mkv", "Movie.mkv (2024)", false},
+		{"正则不匹配", `[{"type":"regex","find":"^X/","replace":""}]`, "Show/E01.mkv", "Show/E01.mkv", false},
+		{"全部展平", `[{"type":"flatten","depth":0}]`, "A/B/C/E01.mkv", "E01.mkv", false},
+		{"保留一层", `[{"type":"flatten","depth":1}]`, "A/B/C/E01.mkv", "A/E01.mkv", false},
+		{"展平层数超过目录数", `[{"type":"flatten","depth":5}]`, "A/E01.mkv", "A/E01.mkv", false},
+		{"去掉一层", `[{"type":"strip","depth":1}]`, "A/B/E01.mkv", "B/E01.mkv", false},
+		{"去掉全部目录", `[{"type":"strip","depth":5}]`, "A/B/E01.mkv", "E01.mkv", false},
+		{"季目录", `[{"type":"season"}]`, "Show/S1/E01.mkv", "Show/Season 01/E01.mkv", false},
+		{"中文季目录", `[{"type":"season"}]`, "Show/第 2 季/E01.mkv", "Show/Season 02/E01.mkv", false},
+		{"Season 目录", `[{"type":"season"}]`, "Show/season 010/E01.mkv", "Show/Season 10/E01.mkv", false},
+		{"特别篇", `[{"type":"season"}]`, "Show/Specials/E01.mkv", "Show/Season 00/E01.mkv", false},
+		{"SP", `[{"type":"season"}]`, "Show/sp/E01.mkv", "Show/Season 00/E01.mkv", false},
+		{"自定义格式", `[{"type":"season","format":"S%d"}]`, "Show/Season 3/E01.mkv", "Show/S3/E01.mkv", false},
+		{"非季目录不变", `[{"type":"season"}]`, "Show/Season Finale/S01.mkv", "Show/Season Finale/S01.mkv", false},
+		{"规则按顺序作用", `[{"type":"strip","depth":1},{"type":"season"},{"type":"regex","find":"^","replace":"TV/"}]`, "Anime/Show/S01/E01.mkv", "TV/Show/Season 01/E01.mkv", false},
+		{".. 不能跳出输出目录", `[{"type":"regex","find":"^","replace":"../../"}]`, "Show/E01.mkv", "Show/E01.mkv", false},
+		{".. 在中间时正常清理", `[{"type":"regex","find":"^Show/","replace":"Show/X/../"}]`, "Show/E01.mkv", "Show/E01.mkv", false},
+		{"绝对路径视为相对路径", `[{"type":"regex","find":"^","replace":"/"}]`, "Show/E01.mkv", "Show/E01.mkv", false},
+		{"结果为空", `[{"type":"regex","find":".*","replace":""}]`, "Show/E01.mkv", "Show/E01.mkv", true},
+		{"结果只剩 ..", `[{"type":"regex","find":".*","replace":".."}]`, "Show/E01.mkv", "Show/E01.mkv", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rw, err := ParseRewriteRules(tc.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := rw.Apply(tc.in)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Apply(%q) err = %v, wantErr %v", tc.in, err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Apply(%q) = %q, want %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package strm
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/logger"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/openlist"
+	"go.uber.org/zap"
+)
+
+// DefaultSignRenewBefore 剩余有效期不足该时长的签名视为即将过期，运行时重新生成对应 .strm
+const DefaultSignRenewBefore = 24 * time.Hour
+
+// signProbe 列表未返回 sign 时是否改用 /api/fs/get 获取
+const (
+	signProbeUnknown = iota
+	signProbeEnabled
+	signProbeDisabled
+)
+
+// maxSignProbeFailures /api/fs/get 连续失败该次数后本次运行不再请求签名，避免每个文件都多等一次失败的请求
+const maxSignProbeFailures = 5
+
+// ComputeSign 按 OpenList 规则计算签名：base64url(hmac-sha256(path:expire)):expire，expire 为 0 表示永不过期
+func ComputeSign(secret, remotePath string, expire int64) string {
+	ts := strconv.FormatInt(expire, 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(remotePath + ":" + ts))
+	return base64.URLEncoding.EncodeToString(mac.Sum(nil)) + ":" + ts
+}
+
+// SignExpiry 解析签名中的过期时间，永不过期或无法解析时返回零值
+func SignExpiry(sign string) time.Time {
+	i := strings.LastIndex(sign, ":")
+	if i < 0 {
+		return time.Time{}
+	}
+	ts, err := strconv.ParseInt(sign[i+1:], 10, 64)
+	if err != nil || ts <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(ts, 0)
+}
+
+// SignExpiring 签名将在 renewBefore 内过期
+func SignExpiring(sign string, now time.Time, renewBefore time.Duration) bool {
+	expiry := SignExpiry(sign)
+	return !expiry.IsZero() && expiry.Sub(now) < renewBefore
+}
+
+// signFor 获取文件签名：优先使用列表返回的 sign，其次用配置的密钥本地计算，
+// 都没有时请求 /api/fs/get；首次请求未返回 sign 说明未开启签名，连续失败 maxSignProbeFailures 次时同样放弃，本次运行不再请求
+func (g *Generator) signFor(ctx context.Context, remotePath string, obj openlist.Object) string {
+	if obj.Sign != "" {
+		return obj.Sign
+	}
+	if g.signSecret != "" {
+		var expire int64
+		if g.service.SignExpireHours > 0 {
+			expire = time.Now().Add(time.Duration(g.service.SignExpireHours) * time.Hour).Unix()
+		}
+		return ComputeSign(g.signSecret, remotePath, expire)
+	}
+	if g.signProbe == signProbeDisabled {
+		return ""
+	}
+	resp, err := g.client.Get(ctx, &openlist.GetReq{Path: remotePath})
+	if err != nil {
+		logger.Warn("[Strm] 获取签名失败", zap.String("path", remotePath), zap.Error(err))
+		if ctx.Err() == nil {
+			g.signProbeFailures++
+			if g.signProbeFailures >= maxSignProbeFailures {
+				logger.Warn("[Strm] 获取签名连续失败，本次运行不再获取", zap.Int("config_id", g.cfg.ID), zap.Int("failures", g.signProbeFailures))
+				g.signProbe = signProbeDisabled
+			}
+		}
+		return ""
+	}
+	g.signProbeFailures = 0
+	if resp.Sign == "" {
+		if g.signProbe == signProbeUnknown {
+			g.signProbe = signProbeDisabled
+		}
+		return ""
+	}
+	g.signProbe = signProbeEnabled
+	return resp.Sign
+}
@@ -0,0 +1,53 @@
+package strm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/model"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/openlist"
+)
+
+func TestSignForGivesUpAfterFailures(t *testing.T) {
+	var gets atomic.Int32
+	var fail atomic.Bool
+	fail.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gets.Add(1)
+		if fail.Load() {
+			writeJSON(w, map[string]interface{}{"code": 403, "message": "permission denied"})
+			return
+		}
+		writeJSON(w, map[string]interface{}{"code": 200, "data": map[string]interface{}{"name": "a.mkv", "sign": "abc:0"}})
+	}))
+	defer server.Close()
+	svc := &model.OpenListService{ServiceUrl: server.URL}
+	g := NewGenerator(&model.StrmConfig{}, svc, openlist.NewClient(svc))
+	obj := openlist.Object{Name: "a.mkv"}
+
+	// 失败次数未达上限前，成功一次会重置计数
+	for i := 0; i < maxSignProbeFailures-1; i++ {
+		g.signFor(context.Background(), "/a.mkv", obj)
+	}
+	fail.Store(false)
+	if sign := g.signFor(context.Background(), "/a.mkv", obj); sign != "abc:0" {
+		t.Fatalf("sign = %q", sign)
+	}
+	fail.Store(true)
+	for i := 0; i < maxSignProbeFailures; i++ {
+		g.signFor(context.Background(), "/a.mkv", obj)
+	}
+	if g.signProbe != signProbeDisabled {
+		t.Fatalf("连续失败 %d 次后应停止获取签名", maxSignProbeFailures)
+	}
+	before := gets.Load()
+	if sign := g.signFor(context.Background(), "/a.mkv", obj); sign != "" || gets.Load() != before {
+		t.Errorf("停止后不应再请求, sign = %q", sign)
+	}
+	if want := int32(2 * maxSignProbeFailures); before != want {
+		t.Errorf("fs/get 请求 %d 次，期望 %d", before, want)
+	}
+}
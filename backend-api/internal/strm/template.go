@@ -0,0 +1,106 @@
+package strm
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/openlist"
+	"github.com/tnnevol/openlist-strm/backend-api/internal/util"
+)
+
+// DefaultURLTemplate 未配置模板时使用的 OpenList /d/ 直链
+const DefaultURLTemplate = "{service_url}/d{encoded_path}{sign_query}"
+
+// URLTemplatePresets 常用播放器的模板预设
+var URLTemplatePresets = map[string]string{
+	"direct": DefaultURLTemplate,                          // /d/ 直链，Emby/Jellyfin/Kodi 通用
+	"proxy":  "{service_url}/p{encoded_path}{sign_query}", // /p/ 经 OpenList 中转
+	"raw":    "{raw_url}",                                 // 存储提供方的原始链接，可能过期
+	"mount":  "/mnt/alist{path}",                          // 本地挂载路径，按实际挂载点修改
+	"infuse": "{service_url}/d{encoded_path}?sign={sign}", // 始终带 sign 参数
+	"play":   "{play_url}",                                // 本服务 /play 302 跳转，播放时实时获取直链
+}
+
+// URLPlaceholders 模板支持的占位符及说明
+var URLPlaceholders = map[string]string{
+	"service_url":       "OpenList 服务地址，不含结尾 /",
+	"path":              "远程完整路径，如 /media/Movies/A.mkv",
+	"encoded_path":      "逐段 URL 编码后的远程完整路径",
+	"rel_path":          "相对 alistBasePath 的路径，不含开头 /",
+	"encoded_rel_path":  "逐段 URL 编码后的相对路径",
+	"file_name":         "文件名",
+	"encoded_file_name": "URL 编码后的文件名",
+	"sign":              "文件签名，未开启签名时为空",
+	"sign_query":        "有签名时为 ?sign=xxx，否则为空",
+	"raw_url":           "存储提供方原始链接，需逐个文件请求 /api/fs/get",
+	"play_url":          "本服务的 /play 跳转链接，需配置 PLAY_BASE_URL",
+}
+
+var placeholderPattern = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// URLVars 渲染模板所需的单个文件信息
+type URLVars struct {
+	ServiceURL string
+	Path       string // 远程完整路径
+	RelPath    string // 相对 alistBasePath 的路径
+	Sign       string
+	RawURL     string
+	PlayURL    string
+}
+
+// ValidateURLTemplate 校验模板中的占位符均受支持
+func ValidateURLTemplate(tmpl string) error {
+	for _, m := range placeholderPattern.FindAllStringSubmatch(tmpl, -1) {
+		if _, ok := URLPlaceholders[m[1]]; !ok {
+			return fmt.Errorf("不支持的占位符 {%s}", m[1])
+		}
+	}
+	return nil
+}
+
+// NeedsRawURL 模板是否使用 {raw_url}，需要额外请求 /api/fs/get
+func NeedsRawURL(tmpl string) bool {
+	return strings.Contains(tmpl, "{raw_url}")
+}
+
+// NeedsPlayURL 模板是否使用 {play_url}，需要配置对外访问地址
+func NeedsPlayURL(tmpl string) bool {
+	return strings.Contains(tmpl, "{play_url}")
+}
+
+// PlayURL 生成本服务的播放跳转链接，附带绑定配置、配置的播放链接密钥和路径的签名
+func PlayURL(baseURL string, configID int, playSecret, relPath string) string {
+	relPath = strings.TrimPrefix(relPath, "/")
+	return strings.TrimRight(baseURL, "/") + "/play/" + strconv.Itoa(configID) + openlist.EncodePath("/"+relPath) +
+		"?t=" + util.SignPlayToken(configID, playSecret, relPath)
+}
+
+// RenderURL 用文件信息替换模板占位符，模板为空时使用 DefaultURLTemplate
+func RenderURL(tmpl string, v URLVars) string {
+	if tmpl == "" {
+		tmpl = DefaultURLTemplate
+	}
+	signQuery := ""
+	if v.Sign != "" {
+		signQuery = "?sign=" + url.QueryEscape(v.Sign)
+	}
+	name := path.Base(v.Path)
+	r := strings.NewReplacer(
+		"{service_url}", strings.TrimRight(v.ServiceURL, "/"),
+		"{path}", v.Path,
+		"{encoded_path}", openlist.EncodePath(v.Path),
+		"{rel_path}", v.RelPath,
+		"{encoded_rel_path}", openlist.EncodePath(v.RelPath),
+		"{file_name}", name,
+		"{encoded_file_name}", url.PathEscape(name),
+		"{sign}", url.QueryEscape(v.Sign),
+		"{sign_query}", signQuery,
+		"{raw_url}", v.RawURL,
+		"{play_url}", v.PlayURL,
+	)
+	return r.Replace(tmpl)
+}
@@ -0,0 +1,169 @@
+package strm
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sync"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/openlist"
+)
+
+// listPageSize 每页拉取的对象数
+var listPageSize = 1000
+
+// SkipDir 回调对目录返回 SkipDir 时不再深入该目录
+var SkipDir = errors.New("skip this directory")
+
+// WalkFunc 遍历回调，dir 为对象所在的远程目录
+type WalkFunc func(dir string, obj openlist.Object) error
+
+// Walk 顺序遍历远程目录，等同于并发数为 1 的 WalkConcurrent
+func Walk(ctx context.Context, client *openlist.Client, root string, fn WalkFunc) error {
+	return WalkConcurrent(ctx, client, root, 1, fn)
+}
+
+// walkPage 一页目录内容，last 表示该目录已拉取完毕
+type walkPage struct {
+	dir  string
+	page int
+	objs []openlist.Object
+	last bool
+	err  error
+}
+
+// walkDir 交给 worker 拉取的目录及起始页
+type walkDir struct {
+	path string
+	page int
+}
+
+// WalkConcurrent 递归遍历远程目录，concurrency 个 worker 并发拉取目录内容，
+// 回调始终在调用方 goroutine 中串行执行，目录先回调再深入。
+// 每个目录按页拉取后立即交给回调，待遍历目录按后进先出处理，内存占用与目录深度和并发数相关，而非整棵目录树
+func WalkConcurrent(ctx context.Context, client *openlist.Client, root string, concurrency int, fn WalkFunc) error {
+	_, _, err := walkDirs(ctx, client, []string{root}, nil, concurrency, fn)
+	return err
+}
+
+// walkDirs 从 roots 开始遍历，startPages 为部分目录的起始页（未列出的从第 1 页开始）。
+// 提前结束时返回尚未遍历完的目录（含已开始拉取但未拉取完的）及其中已处理过部分分页的目录的下一页，
+// 可作为 roots 和 startPages 继续遍历，已回调过的对象不会重复回调
+func walkDirs(ctx context.Context, client *openlist.Client, roots []string, startPages map[string]int, concurrency int, fn WalkFunc) (unfinished []string, nextPages map[string]int, err error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	dirs := make(chan walkDir)
+	pages := make(chan walkPage, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for dir := range dirs {
+				listDir(ctx, client, dir.path, dir.page, pages)
+			}
+		}()
+	}
+	defer func() {
+		cancel()
+		close(dirs)
+		wg.Wait()
+	}()
+
+	pending := append([]string(nil), roots...)
+	// inflight 已交给 worker 但尚未拉取完的目录
+	inflight := make(map[string]bool)
+	// queued 本次遍历中已加入过的目录，避免同一目录被重复遍历
+	queued := make(map[string]bool, len(roots))
+	for _, dir := range roots {
+		queued[dir] = true
+	}
+	// pageOf 未遍历完的目录下一次应拉取的页，未列出的为第 1 页
+	pageOf := make(map[string]int, len(startPages))
+	for dir, page := range startPages {
+		if page > 1 {
+			pageOf[dir] = page
+		}
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		for dir := range inflight {
+			unfinished = append(unfinished, dir)
+		}
+		unfinished = append(unfinished, pending...)
+		nextPages = pageOf
+	}()
+	for len(pending) > 0 || len(inflight) > 0 {
+		var send chan walkDir
+		var next walkDir
+		if len(pending) > 0 {
+			send = dirs
+			next = walkDir{path: pending[len(pending)-1], page: max(pageOf[pending[len(pending)-1]], 1)}
+		}
+		select {
+		case send <- next:
+			pending = pending[:len(pending)-1]
+			inflight[next.path] = true
+		case page := <-pages:
+			if page.err != nil {
+				return nil, nil, page.err
+			}
+			// 一页未处理完就出错时丢弃这一页加入的子目录，继续遍历时该页会重新拉取
+			mark := len(pending)
+			for _, obj := range page.objs {
+				if err := fn(page.dir, obj); err != nil {
+					if obj.IsDir && errors.Is(err, SkipDir) {
+						continue
+					}
+					for _, dir := range pending[mark:] {
+						delete(queued, dir)
+					}
+					pending = pending[:mark]
+					return nil, nil, err
+				}
+				if obj.IsDir {
+					sub := path.Join(page.dir, obj.Name)
+					if !queued[sub] {
+						queued[sub] = true
+						pending = append(pending, sub)
+					}
+				}
+			}
+			if page.last {
+				delete(inflight, page.dir)
+				delete(pageOf, page.dir)
+			} else {
+				pageOf[page.dir] = page.page + 1
+			}
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+	return nil, nil, nil
+}
+
+// listDir 从 start 页开始按页拉取单个目录，每页通过 pages 发送，出错时发送错误并结束
+func listDir(ctx context.Context, client *openlist.Client, dir string, start int, pages chan<- walkPage) {
+	for page := start; ; page++ {
+		var p walkPage
+		resp, err := client.List(ctx, &openlist.ListReq{Path: dir, Page: page, PerPage: listPageSize})
+		if err != nil {
+			p = walkPage{dir: dir, page: page, last: true, err: err}
+		} else {
+			last := len(resp.Content) < listPageSize || int64(page*listPageSize) >= resp.Total
+			p = walkPage{dir: dir, page: page, objs: resp.Content, last: last}
+		}
+		select {
+		case pages <- p:
+		case <-ctx.Done():
+			return
+		}
+		if p.last {
+			return
+		}
+	}
+}
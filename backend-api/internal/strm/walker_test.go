@@ -0,0 +1,251 @@
+package strm
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/tnnevol/openlist-strm/backend-api/internal/openlist"
+)
+
+// allPaths 目录树中除根目录外的全部对象路径
+func allPaths(tree map[string][]string) []string {
+	var paths []string
+	for dir, children := range tree {
+		for _, name := range children {
+			paths = append(paths, path.Join(dir, name))
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// walkRecorder 记录回调顺序，fail 返回非空时以该错误结束回调
+type walkRecorder struct {
+	visited []string
+	fail    func(p string, obj openlist.Object) error
+}
+
+func (r *walkRecorder) fn(dir string, obj openlist.Object) error {
+	p := path.Join(dir, obj.Name)
+	if r.fail != nil {
+		if err := r.fail(p, obj); err != nil {
+			return err
+		}
+	}
+	r.visited = append(r.visited, p)
+	return nil
+}
+
+func sortedCopy(s []string) []string {
+	out := append([]string(nil), s...)
+	sort.Strings(out)
+	return out
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestWalkPaginated(t *testing.T) {
+	for _, concurrency := range []int{1, 4} {
+		t.Run("concurrency="+strconv.Itoa(concurrency), func(t *testing.T) {
+			setListPageSize(t, 2)
+			tree := fakeLibrary("/media", 4, 5)
+			fake := newFakeOpenList(t, tree)
+			rec := &walkRecorder{}
+			unfinished, nextPages, err := walkDirs(context.Background(), fake.client(), []string{"/media"}, nil, concurrency, rec.fn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if unfinished != nil || nextPages != nil {
+				t.Errorf("完整遍历后 unfinished = %v, nextPages = %v", unfinished, nextPages)
+			}
+			// 每个对象回调一次，包括字幕等非视频文件
+			want := []string{"/media/ShowA", "/media/ShowB", "/media/ShowC", "/media/ShowD"}
+			for dir, children := range tree {
+				if dir == "/media" {
+					continue
+				}
+				for _, name := range children {
+					want = append(want, path.Join(dir, name))
+				}
+			}
+			sort.Strings(want)
+			if got := sortedCopy(rec.visited); !equalStrings(got, want) {
+				t.Errorf("visited = %v\nwant %v", got, want)
+			}
+			// 目录先回调再深入
+			index := make(map[string]int)
+			for i, p := range rec.visited {
+				index[p] = i
+			}
+			for _, p := range rec.visited {
+				if dir := path.Dir(p); dir != "/media" && index[dir] > index[p] {
+					t.Errorf("%s 在所在目录之前回调", p)
+				}
+			}
+			// 6 个对象按每页 2 个分 3 页，每页只拉取一次
+			for page := 1; page <= 3; page++ {
+				if n := fake.listCount("/media/ShowA", page); n != 1 {
+					t.Errorf("ShowA 第 %d 页拉取 %d 次", page, n)
+				}
+			}
+			if n := fake.listCount("/media/ShowA", 4); n != 0 {
+				t.Errorf("ShowA 第 4 页不应拉取，实际 %d 次", n)
+			}
+		})
+	}
+}
+
+func TestWalkSkipDir(t *testing.T) {
+	fake := newFakeOpenList(t, fakeLibrary("/media", 3, 2))
+	rec := &walkRecorder{fail: func(p string, obj openlist.Object) error {
+		if obj.IsDir && p == "/media/ShowB" {
+			return SkipDir
+		}
+		return nil
+	}}
+	if _, _, err := walkDirs(context.Background(), fake.client(), []string{"/media"}, nil, 2, rec.fn); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range rec.visited {
+		if path.Dir(p) == "/media/ShowB" || p == "/media/ShowB" {
+			t.Errorf("跳过的目录不应回调: %s", p)
+		}
+	}
+	if n := fake.listCount("/media/ShowB", 1); n != 0 {
+		t.Errorf("跳过的目录不应拉取，实际 %d 次", n)
+	}
+	if len(rec.visited) != 2*(1+3) {
+		t.Errorf("visited = %v", rec.visited)
+	}
+}
+
+func TestWalkListError(t *testing.T) {
+	fake := newFakeOpenList(t, fakeLibrary("/media", 3, 2))
+	fake.failDir("/media/ShowC")
+	rec := &walkRecorder{}
+	unfinished, _, err := walkDirs(context.Background(), fake.client(), []string{"/media"}, nil, 2, rec.fn)
+	if !errors.Is(err, openlist.ErrNotFound) {
+		t.Fatalf("err = %v", err)
+	}
+	found := false
+	for _, dir := range unfinished {
+		found = found || dir == "/media/ShowC"
+	}
+	if !found {
+		t.Errorf("拉取失败的目录应在 unfinished 中: %v", unfinished)
+	}
+}
+
+func TestWalkCallbackErrorResume(t *testing.T) {
+	setListPageSize(t, 2)
+	tree := fakeLibrary("/media", 3, 5)
+	fake := newFakeOpenList(t, tree)
+	errStop := errors.New("stop")
+	rec := &walkRecorder{fail: func(p string, obj openlist.Object) error {
+		if p == "/media/ShowB/ShowB.d.mkv" {
+			return errStop
+		}
+		return nil
+	}}
+	unfinished, nextPages, err := walkDirs(context.Background(), fake.client(), []string{"/media"}, nil, 1, rec.fn)
+	if !errors.Is(err, errStop) {
+		t.Fatalf("err = %v", err)
+	}
+	if len(unfinished) == 0 {
+		t.Fatal("回调出错时应返回未完成的目录")
+	}
+	// ShowB.d.mkv 在第 2 页（ShowB.c、ShowB.d），前 1 页已处理完
+	if nextPages["/media/ShowB"] != 2 {
+		t.Errorf("nextPages = %v", nextPages)
+	}
+
+	first := rec.visited
+	rec = &walkRecorder{}
+	if _, _, err := walkDirs(context.Background(), fake.client(), unfinished, nextPages, 1, rec.fn); err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, p := range first {
+		seen[p] = true
+	}
+	for _, p := range rec.visited {
+		// 出错的一页会重新拉取，其中出错前已回调的对象允许重复
+		if seen[p] && p != "/media/ShowB/ShowB.c.mkv" {
+			t.Errorf("%s 重复回调", p)
+		}
+		seen[p] = true
+	}
+	var all []string
+	for p := range seen {
+		all = append(all, p)
+	}
+	sort.Strings(all)
+	if want := allPaths(tree); !equalStrings(all, want) {
+		t.Errorf("两次遍历合计 = %v\nwant %v", all, want)
+	}
+	if n := fake.listCount("/media/ShowB", 1); n != 1 {
+		t.Errorf("已处理完的第 1 页拉取 %d 次", n)
+	}
+}
+
+func TestWalkCancelResume(t *testing.T) {
+	for _, concurrency := range []int{1, 3} {
+		t.Run("concurrency="+strconv.Itoa(concurrency), func(t *testing.T) {
+			setListPageSize(t, 2)
+			tree := fakeLibrary("/media", 4, 5)
+			fake := newFakeOpenList(t, tree)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var once sync.Once
+			fake.setHook(func(api, dir string, page int) {
+				if api == "/api/fs/list" && dir == "/media/ShowC" && page == 2 {
+					once.Do(cancel)
+				}
+			})
+			rec := &walkRecorder{}
+			unfinished, nextPages, err := walkDirs(ctx, fake.client(), []string{"/media"}, nil, concurrency, rec.fn)
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("err = %v", err)
+			}
+			found := false
+			for _, dir := range unfinished {
+				found = found || dir == "/media/ShowC"
+			}
+			if !found {
+				t.Fatalf("取消时正在拉取的目录应在 unfinished 中: %v", unfinished)
+			}
+
+			fake.setHook(nil)
+			first := rec.visited
+			rec = &walkRecorder{}
+			if _, _, err := walkDirs(context.Background(), fake.client(), unfinished, nextPages, concurrency, rec.fn); err != nil {
+				t.Fatal(err)
+			}
+			seen := make(map[string]bool)
+			for _, p := range append(first, rec.visited...) {
+				if seen[p] {
+					t.Errorf("%s 重复回调", p)
+				}
+				seen[p] = true
+			}
+			if len(seen) != len(allPaths(tree)) {
+				t.Errorf("两次遍历合计 %d 个对象，期望 %d", len(seen), len(allPaths(tree)))
+			}
+		})
+	}
+}
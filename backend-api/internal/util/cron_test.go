@@ -0,0 +1,171 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+// bits 将取值列表转换为位图
+func bits(values ...int) uint64 {
+	var b uint64
+	for _, v := range values {
+		b |= 1 << uint(v)
+	}
+	return b
+}
+
+func TestParseCronFields(t *testing.T) {
+	cases := []struct {
+		expr                          string
+		minute, hour, dom, month, dow uint64
+	}{
+		{"1,5,10 0 1 1 0", bits(1, 5, 10), bits(0), bits(1), bits(1), bits(0)},
+		{"10-15 8-9 * * *", bits(10, 11, 12, 13, 14, 15), bits(8, 9), cronAll(cronDom), cronAll(cronMonth), cronAll(cronDow) | 1},
+		{"*/15 */6 */10 */4 */2", bits(0, 15, 30, 45), bits(0, 6, 12, 18), bits(1, 11, 21, 31), bits(1, 5, 9), bits(0, 2, 4, 6)},
+		{"5/20 3/8 1 1 0", bits(5, 25, 45), bits(3, 11, 19), bits(1), bits(1), bits(0)},
+		{"10-30/10,59 0 1-3,15 jan-mar,DEC mon-fri", bits(10, 20, 30, 59), bits(0), bits(1, 2, 3, 15), bits(1, 2, 3, 12), bits(1, 2, 3, 4, 5)},
+		{"0 0 * * SUN", bits(0), bits(0), cronAll(cronDom), cronAll(cronMonth), bits(0)},
+		// 7 与 0 均表示周日
+		{"0 0 * * 7", bits(0), bits(0), cronAll(cronDom), cronAll(cronMonth), bits(0, 7)},
+		{"0 0 * * 5-7", bits(0), bits(0), cronAll(cronDom), cronAll(cronMonth), bits(0, 5, 6, 7)},
+		{"@daily", bits(0), bits(0), cronAll(cronDom), cronAll(cronMonth), cronAll(cronDow) | 1},
+		{"@weekly", bits(0), bits(0), cronAll(cronDom), cronAll(cronMonth), bits(0)},
+		{"@MONTHLY", bits(0), bits(0), bits(1), cronAll(cronMonth), cronAll(cronDow) | 1},
+		{"  0 12 * * *  ", bits(0), bits(12), cronAll(cronDom), cronAll(cronMonth), cronAll(cronDow) | 1},
+	}
+	for _, tc := range cases {
+		s, err := ParseCron(tc.expr)
+		if err != nil {
+			t.Errorf("ParseCron(%q): %v", tc.expr, err)
+			continue
+		}
+		if s.minute != tc.minute || s.hour != tc.hour || s.dom != tc.dom || s.month != tc.month || s.dow != tc.dow {
+			t.Errorf("ParseCron(%q) = %b %b %b %b %b\nwant %b %b %b %b %b", tc.expr,
+				s.minute, s.hour, s.dom, s.month, s.dow, tc.minute, tc.hour, tc.dom, tc.month, tc.dow)
+		}
+	}
+}
+
+// cronAll 字段全部取值的位图
+func cronAll(f cronField) uint64 {
+	var b uint64
+	for v := f.min; v <= f.max; v++ {
+		b |= 1 << uint(v)
+	}
+	return b
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 0 *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"a * * * *",
+		"* * * foo *",
+		"* * * * funday",
+		"1,,2 * * * *",
+		"@every 5m",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) 应报错", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	cases := []struct {
+		expr, from, want string
+	}{
+		{"*/15 * * * *", "2024-01-01 10:07:30", "2024-01-01 10:15:00"},
+		// 严格晚于起点
+		{"*/15 * * * *", "2024-01-01 10:15:00", "2024-01-01 10:30:00"},
+		{"0 0 * * *", "2024-01-01 23:59:59", "2024-01-02 00:00:00"},
+		// 跨月、跨年
+		{"0 0 1 * *", "2024-12-15 08:00:00", "2025-01-01 00:00:00"},
+		{"30 23 31 * *", "2024-04-01 00:00:00", "2024-05-31 23:30:00"},
+		{"0 9 * feb *", "2024-03-01 00:00:00", "2025-02-01 09:00:00"},
+		// 闰年
+		{"0 0 29 2 *", "2024-03-01 00:00:00", "2028-02-29 00:00:00"},
+		// 2024-01-01 为周一
+		{"0 0 * * fri", "2024-01-01 00:00:00", "2024-01-05 00:00:00"},
+		{"0 12 * * 7", "2024-01-01 00:00:00", "2024-01-07 12:00:00"},
+		{"0 12 * * 0", "2024-01-01 00:00:00", "2024-01-07 12:00:00"},
+		{"0 0 13 * *", "2024-01-01 00:00:00", "2024-01-13 00:00:00"},
+		// 日和周同时限定时任一满足即可
+		{"0 0 13 * fri", "2024-01-01 00:00:00", "2024-01-05 00:00:00"},
+		{"0 0 13 * fri", "2024-01-12 00:00:00", "2024-01-13 00:00:00"},
+		// 日为 * 时只按周匹配
+		{"0 0 */1 * fri", "2024-01-01 00:00:00", "2024-01-05 00:00:00"},
+		{"0 0 1 * mon-fri", "2024-01-06 00:00:00", "2024-01-08 00:00:00"},
+	}
+	for _, tc := range cases {
+		s, err := ParseCron(tc.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q): %v", tc.expr, err)
+		}
+		if got := s.Next(at(tc.from)); !got.Equal(at(tc.want)) {
+			t.Errorf("%q Next(%s) = %s, want %s", tc.expr, tc.from, got, tc.want)
+		}
+	}
+
+	// 2 月 30 日永远不会到来
+	s, _ := ParseCron("0 0 30 2 *")
+	if got := s.Next(at("2024-01-01 00:00:00")); !got.IsZero() {
+		t.Errorf("0 0 30 2 * Next = %s, want zero", got)
+	}
+}
+
+func TestCronNextDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("缺少时区数据:", err)
+	}
+	est := time.FixedZone("EST", -5*3600)
+	edt := time.FixedZone("EDT", -4*3600)
+	cases := []struct {
+		name       string
+		expr       string
+		from, want time.Time
+	}{
+		// 2024-03-10 02:00 EST 跳到 03:00 EDT，跳过的时刻在跳变后执行一次
+		{"跳过的时刻顺延", "30 2 * * *", time.Date(2024, 3, 10, 1, 0, 0, 0, est), time.Date(2024, 3, 10, 3, 0, 0, 0, edt)},
+		{"顺延后次日正常", "30 2 * * *", time.Date(2024, 3, 10, 3, 0, 0, 0, edt), time.Date(2024, 3, 11, 2, 30, 0, 0, edt)},
+		{"跳变期间的多个时刻只执行一次", "*/20 * * * *", time.Date(2024, 3, 10, 1, 45, 0, 0, est), time.Date(2024, 3, 10, 3, 0, 0, 0, edt)},
+		{"跳变后继续", "*/20 * * * *", time.Date(2024, 3, 10, 3, 0, 0, 0, edt), time.Date(2024, 3, 10, 3, 20, 0, 0, edt)},
+		{"跳变前后不受影响", "0 4 * * *", time.Date(2024, 3, 10, 1, 0, 0, 0, est), time.Date(2024, 3, 10, 4, 0, 0, 0, edt)},
+		// 2024-11-03 02:00 EDT 回拨到 01:00 EST，重复的时刻只执行一次
+		{"回拨不重复执行", "30 1 * * *", time.Date(2024, 11, 3, 1, 30, 0, 0, edt), time.Date(2024, 11, 4, 1, 30, 0, 0, est)},
+		{"回拨第二遍从次日开始", "30 1 * * *", time.Date(2024, 11, 3, 1, 0, 0, 0, est), time.Date(2024, 11, 4, 1, 30, 0, 0, est)},
+		{"回拨前首次出现", "30 1 * * *", time.Date(2024, 11, 3, 0, 0, 0, 0, edt), time.Date(2024, 11, 3, 1, 30, 0, 0, edt)},
+		{"每小时跳过重复的一小时", "0 * * * *", time.Date(2024, 11, 3, 1, 0, 0, 0, edt), time.Date(2024, 11, 3, 2, 0, 0, 0, est)},
+	}
+	for _, tc := range cases {
+		s, err := ParseCron(tc.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		from := tc.from.In(ny)
+		if got := s.Next(from); !got.Equal(tc.want) {
+			t.Errorf("%s: %q Next(%s) = %s, want %s", tc.name, tc.expr, from, got, tc.want.In(ny))
+		} else if got.Location() != ny {
+			t.Errorf("%s: 结果时区 = %s", tc.name, got.Location())
+		}
+	}
+}
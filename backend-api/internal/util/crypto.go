@@ -0,0 +1,136 @@
+package util
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	cryptoKey      []byte
+	cryptoKeyMutex sync.Mutex
+)
+
+// LoadSecretKey 加载加密密钥，优先使用 OPENLIST_SECRET，不与 JWT 签名密钥共用；
+// 未设置时读取 keyFile，文件不存在则生成随机密钥并写入，保证重启后已加密的数据仍可解密
+func LoadSecretKey(keyFile string) error {
+	secret := os.Getenv("OPENLIST_SECRET")
+	if secret == "" {
+		data, err := os.ReadFile(keyFile)
+		switch {
+		case err == nil:
+			secret = strings.TrimSpace(string(data))
+			if secret == "" {
+				return errors.New("密钥文件为空：" + keyFile)
+			}
+		case errors.Is(err, os.ErrNotExist):
+			b := make([]byte, 32)
+			if _, err := io.ReadFull(rand.Reader, b); err != nil {
+				return err
+			}
+			secret = hex.EncodeToString(b)
+			if err := os.WriteFile(keyFile, []byte(secret+"\n"), 0600); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+	}
+	key := sha256.Sum256([]byte(secret))
+	cryptoKeyMutex.Lock()
+	cryptoKey = key[:]
+	cryptoKeyMutex.Unlock()
+	return nil
+}
+
+// secretKey 加密密钥，未调用 LoadSecretKey 时（如测试）使用进程内的随机密钥
+func secretKey() []byte {
+	cryptoKeyMutex.Lock()
+	defer cryptoKeyMutex.Unlock()
+	if cryptoKey == nil {
+		key := make([]byte, 32)
+		rand.Read(key)
+		cryptoKey = key
+	}
+	return cryptoKey
+}
+
+// EncryptString 使用 AES-GCM 加密字符串，返回 base64 编码密文，空字符串原样返回
+func EncryptString(plain string) (string, error) {
+	if plain == "" {
+		return "", nil
+	}
+	block, err := aes.NewCipher(secretKey())
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plain), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptString 解密 EncryptString 生成的密文，空字符串原样返回
+func DecryptString(encrypted string) (string, error) {
+	if encrypted == "" {
+		return "", nil
+	}
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(secretKey())
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("密文格式错误")
+	}
+	nonce, sealed := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// NewPlaySecret 生成配置的播放链接密钥
+func NewPlaySecret() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// SignPlayToken 生成播放链接的签名，绑定配置ID、配置的播放链接密钥和相对路径，防止播放接口被当作开放代理；
+// 轮换配置的密钥后该配置已生成的链接全部失效
+func SignPlayToken(configID int, playSecret, relPath string) string {
+	mac := hmac.New(sha256.New, secretKey())
+	mac.Write([]byte("play:" + strconv.Itoa(configID) + ":" + playSecret + ":" + relPath))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyPlayToken 校验播放链接签名
+func VerifyPlayToken(configID int, playSecret, relPath, token string) bool {
+	expected := SignPlayToken(configID, playSecret, relPath)
+	return hmac.Equal([]byte(expected), []byte(token))
+}
@@ -0,0 +1,101 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitAll 依次获取 n 个令牌，返回总耗时
+func waitAll(t *testing.T, l *RateLimiter, n int) time.Duration {
+	t.Helper()
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return time.Since(start)
+}
+
+func TestRateLimiterUnlimited(t *testing.T) {
+	l := NewRateLimiter(0, 1)
+	if d := waitAll(t, l, 1000); d > 50*time.Millisecond {
+		t.Errorf("不限速时不应等待，耗时 %v", d)
+	}
+}
+
+func TestRateLimiterBurstThenRate(t *testing.T) {
+	l := NewRateLimiter(50, 5)
+	// 初始满桶，前 5 个立即返回
+	if d := waitAll(t, l, 5); d > 20*time.Millisecond {
+		t.Errorf("满桶时耗时 %v", d)
+	}
+	// 之后按 50/s 生成，10 个约 200ms
+	d := waitAll(t, l, 10)
+	if d < 160*time.Millisecond || d > 400*time.Millisecond {
+		t.Errorf("10 个令牌耗时 %v，期望约 200ms", d)
+	}
+}
+
+func TestRateLimiterWaitNLargerThanBurst(t *testing.T) {
+	l := NewRateLimiter(100, 10)
+	start := time.Now()
+	// 桶内 10 个，另外 20 个按 100/s 折算约 200ms
+	if err := l.WaitN(context.Background(), 30); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 160*time.Millisecond || d > 400*time.Millisecond {
+		t.Errorf("WaitN(30) 耗时 %v，期望约 200ms", d)
+	}
+}
+
+func TestRateLimiterCancelReturnsTokens(t *testing.T) {
+	l := NewRateLimiter(10, 1)
+	waitAll(t, l, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	// 需要等待约 1s，超时后返回并归还预占的令牌
+	if err := l.WaitN(ctx, 10); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v", err)
+	}
+	// 归还后下一个令牌约 100ms 内可用，而不是再等 1s
+	if d := waitAll(t, l, 1); d > 200*time.Millisecond {
+		t.Errorf("取消后未归还令牌，等待 %v", d)
+	}
+}
+
+func TestRateLimiterSetRate(t *testing.T) {
+	l := NewRateLimiter(0, 1)
+	waitAll(t, l, 100)
+
+	// 由不限速切换为限速时从满桶开始
+	l.SetRate(20, 3)
+	if d := waitAll(t, l, 3); d > 20*time.Millisecond {
+		t.Errorf("切换后满桶耗时 %v", d)
+	}
+	if d := waitAll(t, l, 2); d < 80*time.Millisecond || d > 250*time.Millisecond {
+		t.Errorf("20/s 下 2 个令牌耗时 %v，期望约 100ms", d)
+	}
+
+	// 提高速率立即生效
+	l.SetRate(200, 1)
+	if d := waitAll(t, l, 10); d > 150*time.Millisecond {
+		t.Errorf("200/s 下 10 个令牌耗时 %v，期望约 50ms", d)
+	}
+
+	// 缩小容量时已有令牌不超过新容量
+	l.SetRate(10, 10)
+	time.Sleep(150 * time.Millisecond)
+	l.SetRate(10, 1)
+	if d := waitAll(t, l, 2); d < 70*time.Millisecond {
+		t.Errorf("容量为 1 时第 2 个令牌应等待约 100ms，实际 %v", d)
+	}
+
+	// 切换为不限速
+	l.SetRate(0, 1)
+	if d := waitAll(t, l, 100); d > 50*time.Millisecond {
+		t.Errorf("不限速时耗时 %v", d)
+	}
+}